@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/charmbracelet/bubbletea"
 	"github.com/gliderlabs/ssh"
 	"github.com/ssh-notes/terminal-notes/models"
@@ -12,6 +14,10 @@ type App struct {
 	model     tea.Model
 	program   *tea.Program
 	session   ssh.Session
+	
+	idleTimeout   time.Duration
+	maxDuration   time.Duration
+	timeoutReason string
 }
 
 func NewApp(username, dataDir string) *App {
@@ -21,6 +27,17 @@ func NewApp(username, dataDir string) *App {
 	}
 }
 
+// SetSessionLimits sets the idle and absolute session timeouts (0 disables)
+func (a *App) SetSessionLimits(idle, max time.Duration) {
+	a.idleTimeout = idle
+	a.maxDuration = max
+}
+
+// TimeoutReason reports why the session ended, if it timed out
+func (a *App) TimeoutReason() string {
+	return a.timeoutReason
+}
+
 func (a *App) Run(s ssh.Session, width, height int) error {
 	a.session = s
 	
@@ -34,6 +51,7 @@ func (a *App) Run(s ssh.Session, width, height int) error {
 	
 	// Initialize the main model with default size
 	initialModel := models.NewMainModel(a.username, a.dataDir)
+	initialModel.SetSessionLimits(a.idleTimeout, a.maxDuration)
 	
 	// Pre-set the window size on the model
 	initialModel.Update(tea.WindowSizeMsg{
@@ -52,6 +70,7 @@ func (a *App) Run(s ssh.Session, width, height int) error {
 	// Run the program - this blocks until exit
 	// Window size was already set on the model, so it should render immediately
 	_, err := a.program.Run()
+	a.timeoutReason = initialModel.TimeoutReason()
 	return err
 }
 
@@ -1,11 +1,25 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/subtle"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/utils"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func generateHostKey(path string) error {
@@ -30,28 +44,221 @@ func generateHostKey(path string) error {
 	return pem.Encode(file, privateKeyPEM)
 }
 
+// Argon2id parameters used for new hashes (RFC 9106 second recommendation)
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
 // UserAuth handles user authentication
 type UserAuth struct {
-	users map[string]string // username -> password hash
+	mu      sync.RWMutex
+	users   map[string]string // username -> password hash
+	path    string
+	modTime time.Time
+	
+	// dummyHash is compared against when a user doesn't exist, so unknown
+	// and known usernames take the same time to reject. It uses the
+	// algorithm passwords are hashed with.
+	dummyHash string
 }
 
-func NewUserAuth() *UserAuth {
+// NewUserAuth creates an empty UserAuth for passwords hashed with
+// algorithm ("bcrypt" or "argon2id")
+func NewUserAuth(algorithm string) *UserAuth {
+	dummyHash, err := hashPassword("dummy-password", algorithm)
+	if err != nil {
+		logger.Warn("Falling back to bcrypt for unknown users: %v", err)
+		dummyHash, _ = hashPassword("dummy-password", "bcrypt")
+	}
 	return &UserAuth{
-		users: make(map[string]string),
+		users:     make(map[string]string),
+		dummyHash: dummyHash,
 	}
 }
 
-// LoadUsers loads users from a file (YAML or JSON)
+// LoadUsers loads users from an htpasswd-style file with one "username:hash"
+// entry per line. Hashes are bcrypt ($2a$, $2b$, $2y$) or argon2id
+// ($argon2id$...). The file is re-read whenever it changes on disk.
 func (ua *UserAuth) LoadUsers(path string) error {
-	// Implementation for loading users from file
-	// For now, return nil (accept all users)
+	// Remember the path even on failure so a file created later gets picked up
+	ua.mu.Lock()
+	ua.path = path
+	ua.mu.Unlock()
+	
+	users, err := readPasswordFile(path)
+	if err != nil {
+		return err
+	}
+	
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	
+	ua.mu.Lock()
+	ua.users = users
+	ua.modTime = info.ModTime()
+	ua.mu.Unlock()
+	
+	logger.Info("Loaded %d users from %s", len(users), path)
 	return nil
 }
 
+// reloadIfChanged re-reads the password file if its modification time moved
+func (ua *UserAuth) reloadIfChanged() {
+	ua.mu.RLock()
+	path, modTime := ua.path, ua.modTime
+	ua.mu.RUnlock()
+	
+	if path == "" {
+		return
+	}
+	
+	info, err := os.Stat(path)
+	if err != nil || info.ModTime().Equal(modTime) {
+		return
+	}
+	
+	if err := ua.LoadUsers(path); err != nil {
+		logger.Warn("Failed to reload password file: %v", err)
+	}
+}
+
 // VerifyPassword checks if password is correct for user
 func (ua *UserAuth) VerifyPassword(username, password string) bool {
-	// In production, use bcrypt or similar
-	// For demo, accept any password
-	return true
+	ua.reloadIfChanged()
+	
+	ua.mu.RLock()
+	hash, exists := ua.users[username]
+	ua.mu.RUnlock()
+	
+	if !exists {
+		// Burn the same amount of time as a real check
+		comparePasswordHash(ua.dummyHash, password)
+		return false
+	}
+	
+	ok, err := comparePasswordHash(hash, password)
+	if err != nil {
+		logger.Warn("Bad password hash for user %s: %v", username, err)
+		return false
+	}
+	return ok
+}
+
+// comparePasswordHash checks password against a bcrypt or argon2id hash
+func comparePasswordHash(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return compareArgon2id(hash, password)
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	default:
+		return false, fmt.Errorf("unsupported hash format")
+	}
 }
 
+// compareArgon2id checks password against a PHC-formatted argon2id hash:
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func compareArgon2id(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, fmt.Errorf("invalid argon2id hash")
+	}
+	
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2 version")
+	}
+	
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+	
+	computed := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, computed) == 1, nil
+}
+
+// hashPassword creates a new hash with the given algorithm ("bcrypt" or "argon2id")
+func hashPassword(password, algorithm string) (string, error) {
+	switch algorithm {
+	case "bcrypt":
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		return string(hash), err
+	case "argon2id":
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
+
+// readPasswordFile parses "username:hash" lines, ignoring blanks and comments
+func readPasswordFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		
+		username, hash, ok := strings.Cut(line, ":")
+		if !ok || utils.ValidateUsername(username) != nil || hash == "" {
+			return nil, fmt.Errorf("%s line %d: expected username:hash", path, lineNum)
+		}
+		users[username] = hash
+	}
+	
+	return users, scanner.Err()
+}
+
+// writePasswordFile atomically writes users back in "username:hash" form
+func writePasswordFile(path string, users map[string]string) error {
+	names := make([]string, 0, len(users))
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	var buf bytes.Buffer
+	buf.WriteString("# Managed by 'ssh-notes passwd' - do not edit by hand\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s:%s\n", name, users[name])
+	}
+	
+	return utils.SafeWriteFile(path, buf.Bytes(), 0600)
+}
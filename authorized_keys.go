@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/ssh-notes/terminal-notes/logger"
+)
+
+// keyOptionsContextKey stores the options of the authorized_keys entry that
+// accepted the connection, so later callbacks (PTY, session) can honour them.
+var keyOptionsContextKey = &struct{ name string }{"authorized-key-options"}
+
+// authorizedKey is a single parsed authorized_keys entry
+type authorizedKey struct {
+	key     ssh.PublicKey
+	comment string
+	options keyOptions
+}
+
+// keyOptions holds the OpenSSH options we understand for an authorized key
+type keyOptions struct {
+	From       string    // from="pattern-list"
+	NoPTY      bool      // no-pty (or restrict without pty)
+	ExpiryTime time.Time // expiry-time="YYYYMMDD[HHMM[SS]]"
+}
+
+// loadAuthorizedKeys parses an OpenSSH authorized_keys file.
+// Malformed lines are logged and skipped, like sshd does.
+func loadAuthorizedKeys(path string) ([]authorizedKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []authorizedKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		key, comment, options, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			logger.Warn("Skipping invalid key in %s line %d: %v", path, lineNum, err)
+			continue
+		}
+
+		opts, err := parseKeyOptions(options)
+		if err != nil {
+			logger.Warn("Skipping key in %s line %d: %v", path, lineNum, err)
+			continue
+		}
+
+		keys = append(keys, authorizedKey{
+			key:     key,
+			comment: comment,
+			options: opts,
+		})
+	}
+
+	return keys, scanner.Err()
+}
+
+// parseKeyOptions converts the raw option strings returned by
+// ssh.ParseAuthorizedKey into keyOptions
+func parseKeyOptions(options []string) (keyOptions, error) {
+	var opts keyOptions
+	restrict := false
+	allowPTY := false
+
+	for _, option := range options {
+		name, value, hasValue := strings.Cut(option, "=")
+		name = strings.ToLower(name)
+		if hasValue {
+			unquoted, err := unquoteOptionValue(value)
+			if err != nil {
+				return opts, fmt.Errorf("option %s: %w", name, err)
+			}
+			value = unquoted
+		}
+
+		switch name {
+		case "from":
+			opts.From = value
+		case "command":
+			// The server only ever runs the notes app, so it has no way to
+			// honour a forced command; refuse the key rather than ignore it
+			return opts, fmt.Errorf("command= is not supported")
+		case "no-pty":
+			opts.NoPTY = true
+		case "pty":
+			allowPTY = true
+		case "restrict":
+			restrict = true
+		case "expiry-time":
+			expiry, err := parseExpiryTime(value)
+			if err != nil {
+				return opts, err
+			}
+			opts.ExpiryTime = expiry
+		default:
+			// Forwarding and environment options don't apply to this server
+		}
+	}
+
+	if restrict && !allowPTY {
+		opts.NoPTY = true
+	}
+
+	return opts, nil
+}
+
+// unquoteOptionValue strips the surrounding quotes of an option value and
+// resolves \" escapes
+func unquoteOptionValue(value string) (string, error) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", fmt.Errorf("value must be quoted")
+	}
+	return strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`), nil
+}
+
+func parseExpiryTime(value string) (time.Time, error) {
+	layouts := map[int]string{
+		8:  "20060102",
+		12: "200601021504",
+		14: "20060102150405",
+	}
+	layout, ok := layouts[len(value)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid expiry-time %q", value)
+	}
+	return time.ParseInLocation(layout, value, time.Local)
+}
+
+// matchFrom reports whether the remote address satisfies an OpenSSH
+// from="pattern-list". Patterns may use * and ? wildcards, CIDR notation,
+// and a leading ! to negate; any negated match rejects the address.
+func matchFrom(patternList string, remote net.Addr) bool {
+	host := remote.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+
+	matched := false
+	for _, pattern := range strings.Split(patternList, ",") {
+		pattern = strings.TrimSpace(pattern)
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if pattern == "" {
+			continue
+		}
+
+		if matchHostPattern(pattern, host, ip) {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+func matchHostPattern(pattern, host string, ip net.IP) bool {
+	if strings.Contains(pattern, "/") {
+		_, network, err := net.ParseCIDR(pattern)
+		return err == nil && ip != nil && network.Contains(ip)
+	}
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(host))
+	return err == nil && ok
+}
+
+// findAuthorizedKey returns the entry in keys matching the offered key
+func findAuthorizedKey(keys []authorizedKey, offered ssh.PublicKey) *authorizedKey {
+	for i := range keys {
+		if ssh.KeysEqual(keys[i].key, offered) {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// keysForUser returns the entries of the shared authorized_keys file that
+// may log in as username: those whose comment is the username itself or
+// starts with "username@", like the comments ssh-keygen writes. Without
+// this, any key in the shared file could open every account.
+func keysForUser(keys []authorizedKey, username string) []authorizedKey {
+	var owned []authorizedKey
+	for _, key := range keys {
+		owner, _, _ := strings.Cut(key.comment, "@")
+		if owner == username {
+			owned = append(owned, key)
+		}
+	}
+	return owned
+}
+
+// userAuthorizedKeysPath returns the per-user authorized_keys location,
+// <data_dir>/<user>/.ssh/authorized_keys
+func userAuthorizedKeysPath(dataDir, username string) string {
+	return filepath.Join(dataDir, username, ".ssh", "authorized_keys")
+}
+
+// keyOptionsFromContext returns the options of the key that authenticated
+// the connection, if any
+func keyOptionsFromContext(ctx ssh.Context) (keyOptions, bool) {
+	opts, ok := ctx.Value(keyOptionsContextKey).(keyOptions)
+	return opts, ok
+}
+
+// checkKeyPTY denies PTY allocation for keys carrying no-pty
+func checkKeyPTY(ctx ssh.Context, pty ssh.Pty) bool {
+	if opts, ok := keyOptionsFromContext(ctx); ok && opts.NoPTY {
+		logger.Info("PTY denied by key options for user %s", ctx.User())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/ssh-notes/terminal-notes/config"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestParseKeyOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		want    keyOptions
+		wantErr bool
+	}{
+		{name: "none", options: nil, want: keyOptions{}},
+		{name: "from", options: []string{`from="10.0.0.0/8,!10.0.0.1"`}, want: keyOptions{From: "10.0.0.0/8,!10.0.0.1"}},
+		{name: "escaped quote", options: []string{`from="a\"b"`}, want: keyOptions{From: `a"b`}},
+		{name: "unquoted value", options: []string{`from=10.0.0.1`}, wantErr: true},
+		{name: "no-pty", options: []string{"no-pty"}, want: keyOptions{NoPTY: true}},
+		{name: "upper case", options: []string{"NO-PTY"}, want: keyOptions{NoPTY: true}},
+		{name: "restrict", options: []string{"restrict"}, want: keyOptions{NoPTY: true}},
+		{name: "restrict with pty", options: []string{"restrict", "pty"}, want: keyOptions{}},
+		{name: "ignored options", options: []string{"no-port-forwarding", `environment="A=b"`}, want: keyOptions{}},
+		{name: "command", options: []string{`command="ls"`}, wantErr: true},
+		{name: "command after others", options: []string{"no-pty", `command="ls"`}, wantErr: true},
+		{name: "expiry date", options: []string{`expiry-time="20300102"`},
+			want: keyOptions{ExpiryTime: time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local)}},
+		{name: "expiry minutes", options: []string{`expiry-time="203001021504"`},
+			want: keyOptions{ExpiryTime: time.Date(2030, 1, 2, 15, 4, 0, 0, time.Local)}},
+		{name: "expiry seconds", options: []string{`expiry-time="20300102150405"`},
+			want: keyOptions{ExpiryTime: time.Date(2030, 1, 2, 15, 4, 5, 0, time.Local)}},
+		{name: "expiry bad length", options: []string{`expiry-time="2030"`}, wantErr: true},
+		{name: "expiry bad date", options: []string{`expiry-time="20301302"`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKeyOptions(tt.options)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseKeyOptions(%q) = %+v, want an error", tt.options, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseKeyOptions(%q): %v", tt.options, err)
+			}
+			if got.From != tt.want.From || got.NoPTY != tt.want.NoPTY || !got.ExpiryTime.Equal(tt.want.ExpiryTime) {
+				t.Errorf("parseKeyOptions(%q) = %+v, want %+v", tt.options, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchFrom(t *testing.T) {
+	tests := []struct {
+		patterns string
+		addr     string
+		want     bool
+	}{
+		{"10.0.0.1", "10.0.0.1:2222", true},
+		{"10.0.0.1", "10.0.0.2:2222", false},
+		{"10.0.0.*", "10.0.0.2:2222", true},
+		{"10.0.?.1", "10.0.5.1:2222", true},
+		{"10.0.?.1", "10.0.55.1:2222", false},
+		{"10.0.0.0/8", "10.200.3.4:2222", true},
+		{"10.0.0.0/8", "11.0.0.1:2222", false},
+		{"2001:db8::/32", "[2001:db8::1]:2222", true},
+		{"10.0.0.0/8,!10.0.0.1", "10.0.0.1:2222", false},
+		{"10.0.0.0/8,!10.0.0.1", "10.0.0.2:2222", true},
+		{"!10.0.0.1,10.0.0.0/8", "10.0.0.1:2222", false},
+		{"!10.0.0.1", "10.0.0.2:2222", false}, // a negation alone allows nothing
+		{"192.168.1.1, 10.0.0.*", "10.0.0.9:2222", true},
+		{"not-a-cidr/99", "10.0.0.1:2222", false},
+		{"", "10.0.0.1:2222", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patterns+" "+tt.addr, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", tt.addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matchFrom(tt.patterns, addr); got != tt.want {
+				t.Errorf("matchFrom(%q, %s) = %v, want %v", tt.patterns, tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAuthorizedKeys(t *testing.T) {
+	alice, bob, carol := newTestKey(t), newTestKey(t), newTestKey(t)
+	file := writeAuthorizedKeys(t, t.TempDir(),
+		"# comment line",
+		"",
+		authorizedLine(alice, "alice@laptop"),
+		"not a key at all",
+		`command="ls" `+authorizedLine(carol, "carol"),
+		`from="10.0.0.0/8",no-pty `+authorizedLine(bob, "bob"),
+	)
+
+	keys, err := loadAuthorizedKeys(file)
+	if err != nil {
+		t.Fatalf("loadAuthorizedKeys: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("loaded %d keys, want 2 (the bad line and the command= key skipped)", len(keys))
+	}
+
+	if entry := findAuthorizedKey(keys, alice); entry == nil || entry.comment != "alice@laptop" {
+		t.Errorf("alice's key = %+v, want it found with its comment", entry)
+	}
+	entry := findAuthorizedKey(keys, bob)
+	if entry == nil || entry.options.From != "10.0.0.0/8" || !entry.options.NoPTY {
+		t.Errorf("bob's key = %+v, want it found with its options", entry)
+	}
+	if findAuthorizedKey(keys, carol) != nil {
+		t.Error("carol's command= key was loaded")
+	}
+
+	if _, err := loadAuthorizedKeys(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("loading a missing file succeeded")
+	}
+}
+
+func TestSetupKeyAuth(t *testing.T) {
+	alice, bob, stranger := newTestKey(t), newTestKey(t), newTestKey(t)
+	expired, limited := newTestKey(t), newTestKey(t)
+
+	dataDir := t.TempDir()
+	global := writeAuthorizedKeys(t, t.TempDir(),
+		authorizedLine(alice, "alice@laptop"),
+		authorizedLine(stranger, "no-owner"),
+	)
+	writeAuthorizedKeys(t, filepath.Join(dataDir, "bob", ".ssh"),
+		authorizedLine(bob, "bob's own key"),
+		`expiry-time="20000101" `+authorizedLine(expired, "old"),
+		`from="192.168.0.0/16",no-pty `+authorizedLine(limited, "lan only"),
+	)
+
+	cfg := &config.Config{}
+	cfg.Server.DataDir = dataDir
+	cfg.Security.AuthorizedKeysFile = global
+	auth := setupKeyAuth(cfg)
+
+	tests := []struct {
+		name   string
+		user   string
+		key    ssh.PublicKey
+		remote string
+		want   bool
+		noPTY  bool
+	}{
+		{name: "global key for its user", user: "alice", key: alice, want: true},
+		{name: "global key for another user", user: "bob", key: alice, want: false},
+		{name: "global key naming nobody", user: "alice", key: stranger, want: false},
+		{name: "per-user key", user: "bob", key: bob, want: true},
+		{name: "per-user key for another user", user: "alice", key: bob, want: false},
+		{name: "expired key", user: "bob", key: expired, want: false},
+		{name: "from allows", user: "bob", key: limited, remote: "192.168.1.5:40000", want: true, noPTY: true},
+		{name: "from refuses", user: "bob", key: limited, remote: "10.0.0.5:40000", want: false},
+		{name: "invalid username", user: "../bob", key: bob, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(t, tt.user, tt.remote)
+			if got := auth(ctx, tt.key); got != tt.want {
+				t.Fatalf("auth = %v, want %v", got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			opts, ok := keyOptionsFromContext(ctx)
+			if !ok {
+				t.Fatal("accepted key's options were not stored on the context")
+			}
+			if opts.NoPTY != tt.noPTY {
+				t.Errorf("NoPTY = %v, want %v", opts.NoPTY, tt.noPTY)
+			}
+			if checkKeyPTY(ctx, ssh.Pty{}) == tt.noPTY {
+				t.Errorf("checkKeyPTY = %v with NoPTY %v", !tt.noPTY, tt.noPTY)
+			}
+		})
+	}
+}
+
+func TestSetupKeyAuthWithoutKeyFiles(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.DataDir = t.TempDir()
+	auth := setupKeyAuth(cfg)
+
+	if auth(newTestContext(t, "alice", ""), newTestKey(t)) {
+		t.Error("key accepted with no authorized_keys file anywhere")
+	}
+}
+
+func newTestKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// authorizedLine is the authorized_keys line for key, without options
+func authorizedLine(key ssh.PublicKey, comment string) string {
+	return strings.TrimSpace(string(gossh.MarshalAuthorizedKey(key))) + " " + comment
+}
+
+func writeAuthorizedKeys(t *testing.T, dir string, lines ...string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "authorized_keys")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// testContext is the part of an SSH connection's context the auth
+// callbacks use
+type testContext struct {
+	context.Context
+	sync.Mutex
+	user   string
+	remote net.Addr
+	values map[interface{}]interface{}
+}
+
+func newTestContext(t *testing.T, user, remote string) *testContext {
+	t.Helper()
+	if remote == "" {
+		remote = "127.0.0.1:40000"
+	}
+	addr, err := net.ResolveTCPAddr("tcp", remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testContext{
+		Context: context.Background(),
+		user:    user,
+		remote:  addr,
+		values:  make(map[interface{}]interface{}),
+	}
+}
+
+func (c *testContext) User() string          { return c.user }
+func (c *testContext) SessionID() string     { return "test" }
+func (c *testContext) ClientVersion() string { return "SSH-2.0-test" }
+func (c *testContext) ServerVersion() string { return "SSH-2.0-test" }
+func (c *testContext) RemoteAddr() net.Addr  { return c.remote }
+func (c *testContext) LocalAddr() net.Addr   { return c.remote }
+func (c *testContext) Permissions() *ssh.Permissions {
+	return &ssh.Permissions{Permissions: &gossh.Permissions{}}
+}
+func (c *testContext) SetValue(key, value interface{}) { c.values[key] = value }
+func (c *testContext) Value(key interface{}) interface{} {
+	if value, ok := c.values[key]; ok {
+		return value
+	}
+	return c.Context.Value(key)
+}
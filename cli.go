@@ -1,12 +1,19 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/ssh-notes/terminal-notes/models"
+	"github.com/ssh-notes/terminal-notes/config"
+	"github.com/ssh-notes/terminal-notes/store"
+	"github.com/ssh-notes/terminal-notes/utils"
+	"golang.org/x/term"
 )
 
 // CLI provides command-line interface for power users
@@ -23,6 +30,35 @@ func runCLI() {
 	importUser := importCmd.String("user", "", "Username")
 	importDataDir := importCmd.String("data", "./data", "Data directory")
 
+	passwdCmd := flag.NewFlagSet("passwd", flag.ExitOnError)
+	passwdUser := passwdCmd.String("user", "", "Username")
+	passwdFile := passwdCmd.String("file", "", "Password file (defaults to security.password_file from config)")
+	passwdConfig := passwdCmd.String("config", "", "Path to configuration file")
+	passwdAlgo := passwdCmd.String("algo", "", "Hash algorithm: bcrypt, argon2id (defaults to security.password_algorithm from config)")
+	passwdDelete := passwdCmd.Bool("delete", false, "Remove the user instead of setting a password")
+
+	replaceCmd := flag.NewFlagSet("replace", flag.ExitOnError)
+	replaceUser := replaceCmd.String("user", "", "Username")
+	replaceDataDir := replaceCmd.String("data", "./data", "Data directory")
+	replaceRegex := replaceCmd.Bool("regex", false, "Treat the pattern as a regular expression ($1 in the replacement refers to its groups)")
+	replaceCase := replaceCmd.Bool("case", false, "Match case")
+	replaceTitles := replaceCmd.Bool("titles", false, "Replace in note titles too")
+	replaceDryRun := replaceCmd.Bool("dry-run", false, "Show the changes without applying them")
+	replaceYes := replaceCmd.Bool("yes", false, "Apply without asking for confirmation")
+	var replaceInclude, replaceExclude pathList
+	replaceCmd.Var(&replaceInclude, "include", "Only change this note or folder (repeatable)")
+	replaceCmd.Var(&replaceExclude, "exclude", "Leave this note or folder alone (repeatable)")
+
+	linksCmd := flag.NewFlagSet("links", flag.ExitOnError)
+	linksUser := linksCmd.String("user", "", "Username")
+	linksDataDir := linksCmd.String("data", "./data", "Data directory")
+
+	tasksCmd := flag.NewFlagSet("tasks", flag.ExitOnError)
+	tasksUser := tasksCmd.String("user", "", "Username")
+	tasksDataDir := tasksCmd.String("data", "./data", "Data directory")
+	tasksDue := tasksCmd.String("due", "", "Only tasks due: overdue, today (overdue included) or week (the next 7 days, overdue included)")
+	tasksAssignee := tasksCmd.String("assignee", "", "Only tasks assigned to this @user")
+
 	if len(os.Args) < 2 {
 		printUsage()
 		os.Exit(1)
@@ -36,8 +72,7 @@ func runCLI() {
 			os.Exit(1)
 		}
 		userDataDir := filepath.Join(*exportDataDir, *exportUser)
-		model := models.NewMainModel(*exportUser, userDataDir)
-		if err := model.ExportNotes(*exportFormat, *exportOutput); err != nil {
+		if err := store.Export(store.NewFileStore(userDataDir), *exportFormat, *exportOutput); err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -54,14 +89,93 @@ func runCLI() {
 			os.Exit(1)
 		}
 		userDataDir := filepath.Join(*importDataDir, *importUser)
-		os.MkdirAll(userDataDir, 0700)
-		model := models.NewMainModel(*importUser, userDataDir)
-		if err := model.ImportNotes(*importFormat, *importInput); err != nil {
+		if err := store.Import(store.NewFileStore(userDataDir), *importFormat, *importInput); err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("Imported notes from %s\n", *importInput)
 
+	case "passwd":
+		passwdCmd.Parse(os.Args[2:])
+		if err := utils.ValidateUsername(*passwdUser); err != nil {
+			fmt.Printf("Error: -user: %v\n", err)
+			os.Exit(1)
+		}
+		cfg, err := config.LoadConfig(*passwdConfig)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		path := *passwdFile
+		if path == "" {
+			path = cfg.Security.PasswordFile
+		}
+		algorithm := *passwdAlgo
+		if algorithm == "" {
+			algorithm = cfg.Security.PasswordAlgorithm
+		}
+		if path == "" {
+			fmt.Println("Error: no password file; pass -file or set security.password_file")
+			os.Exit(1)
+		}
+		if err := runPasswd(path, *passwdUser, algorithm, *passwdDelete); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "replace":
+		replaceCmd.Parse(os.Args[2:])
+		if *replaceUser == "" {
+			fmt.Println("Error: -user is required")
+			os.Exit(1)
+		}
+		if replaceCmd.NArg() != 2 {
+			fmt.Println("Error: expected a pattern and a replacement")
+			os.Exit(1)
+		}
+		userDataDir := filepath.Join(*replaceDataDir, *replaceUser)
+		opts := store.ReplaceOptions{
+			CaseSensitive: *replaceCase,
+			Regex:         *replaceRegex,
+			Titles:        *replaceTitles,
+		}
+		err := runReplace(store.NewFileStore(userDataDir), replaceCmd.Arg(0), replaceCmd.Arg(1), opts,
+			replaceInclude, replaceExclude, *replaceDryRun, *replaceYes)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "links":
+		linksCmd.Parse(os.Args[2:])
+		if *linksUser == "" {
+			fmt.Println("Error: -user is required")
+			os.Exit(1)
+		}
+		userDataDir := filepath.Join(*linksDataDir, *linksUser)
+		report, err := store.CheckLinks(store.NewFileStore(userDataDir))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		printLinkReport(report)
+		// Broken links fail the command, so it can be used in scripts
+		if len(report.Broken) > 0 {
+			os.Exit(1)
+		}
+
+	case "tasks":
+		tasksCmd.Parse(os.Args[2:])
+		if *tasksUser == "" {
+			fmt.Println("Error: -user is required")
+			os.Exit(1)
+		}
+		userDataDir := filepath.Join(*tasksDataDir, *tasksUser)
+		if err := runTasks(store.NewFileStore(userDataDir), *tasksDue, *tasksAssignee, time.Now()); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
 	default:
 		printUsage()
 		os.Exit(1)
@@ -73,8 +187,290 @@ func printUsage() {
 	fmt.Println("\nUsage:")
 	fmt.Println("  ssh-notes export -user <username> -format <format> -output <path>")
 	fmt.Println("  ssh-notes import -user <username> -format <format> -input <path>")
+	fmt.Println("  ssh-notes passwd -user <username> [-file <path>] [-algo bcrypt|argon2id] [-delete]")
+	fmt.Println("  ssh-notes replace -user <username> [-regex] [-case] [-titles] [-include <path>] [-exclude <path>] [-dry-run] [-yes] <pattern> <replacement>")
+	fmt.Println("  ssh-notes links -user <username>")
+	fmt.Println("  ssh-notes tasks -user <username> [-due overdue|today|week] [-assignee <user>]")
 	fmt.Println("\nFormats:")
 	fmt.Println("  export: markdown, json, tar, zip")
 	fmt.Println("  import: markdown, json")
 }
 
+
+// runPasswd sets or removes a user's password hash in the password file
+func runPasswd(path, username, algorithm string, remove bool) error {
+	users := make(map[string]string)
+	if _, err := os.Stat(path); err == nil {
+		existing, err := readPasswordFile(path)
+		if err != nil {
+			return err
+		}
+		users = existing
+	}
+	
+	if remove {
+		if _, ok := users[username]; !ok {
+			return fmt.Errorf("user %s not found", username)
+		}
+		delete(users, username)
+		if err := writePasswordFile(path, users); err != nil {
+			return err
+		}
+		fmt.Printf("Removed user %s from %s\n", username, path)
+		return nil
+	}
+	
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	
+	hash, err := hashPassword(password, algorithm)
+	if err != nil {
+		return err
+	}
+	users[username] = hash
+	
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := writePasswordFile(path, users); err != nil {
+		return err
+	}
+	fmt.Printf("Updated password for %s in %s\n", username, path)
+	return nil
+}
+
+// readNewPassword prompts twice on a terminal, or reads one line from stdin
+// when input is piped (for scripting)
+func readNewPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		password := strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return "", fmt.Errorf("password cannot be empty")
+		}
+		return password, nil
+	}
+	
+	fmt.Print("New password: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	fmt.Print("Retype password: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	
+	if string(first) != string(second) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	if len(first) == 0 {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	return string(first), nil
+}
+
+// pathList is a repeatable flag of note paths or folders
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(value string) error {
+	*p = append(*p, strings.Trim(filepath.ToSlash(value), "/"))
+	return nil
+}
+
+// contains reports whether notePath is one of the paths or inside one of
+// the folders
+func (p pathList) contains(notePath string) bool {
+	for _, entry := range p {
+		if notePath == entry || strings.HasPrefix(notePath, entry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// runReplace previews a replace across every note as a diff per note and,
+// once confirmed, applies it
+func runReplace(s store.NoteStore, pattern, replacement string, opts store.ReplaceOptions,
+	include, exclude pathList, dryRun, yes bool) error {
+	plan, err := store.PlanReplace(s, pattern, replacement, opts)
+	if err != nil {
+		return err
+	}
+	
+	var selected []store.Replacement
+	for _, change := range plan {
+		if len(include) > 0 && !include.contains(change.Path) {
+			continue
+		}
+		if exclude.contains(change.Path) {
+			continue
+		}
+		selected = append(selected, change)
+	}
+	
+	if len(selected) == 0 {
+		fmt.Println("No matches.")
+		return nil
+	}
+	
+	for _, change := range selected {
+		printReplacement(change)
+	}
+	fmt.Printf("\n%s\n", store.Summarize(selected))
+	
+	if dryRun {
+		return nil
+	}
+	if !yes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("not a terminal; pass -yes to apply without confirmation")
+		}
+		fmt.Print("Apply these changes? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("Nothing changed.")
+			return nil
+		}
+	}
+	
+	if err := store.ApplyReplace(s, selected); err != nil {
+		return err
+	}
+	fmt.Printf("Replaced %s. The previous text of each note is in its version history.\n", store.Summarize(selected))
+	return nil
+}
+
+// printReplacement prints the diff of one planned change
+func printReplacement(change store.Replacement) {
+	fmt.Printf("\n=== %s: %s\n", change.Path, change.Title)
+	if change.NewTitle != change.Title {
+		fmt.Printf("title: %s -> %s\n", change.Title, change.NewTitle)
+	}
+	if change.Before == change.After {
+		return
+	}
+	
+	for _, line := range store.Diff(change.Before, change.After, 2) {
+		switch line.Kind {
+		case store.DiffGap:
+			fmt.Println("  ...")
+		case store.DiffRemoved:
+			fmt.Printf("-%4d  %s\n", line.Line, line.Text)
+		case store.DiffAdded:
+			fmt.Printf("+%4d  %s\n", line.Line, line.Text)
+		default:
+			fmt.Printf(" %4d  %s\n", line.Line, line.Text)
+		}
+	}
+}
+
+// printLinkReport prints broken and fuzzy links as path:line:column, the
+// way compilers report errors, followed by the orphan notes
+func printLinkReport(report *store.LinkReport) {
+	if report.Problems() == 0 {
+		fmt.Println("Every link leads to a note and every note is linked.")
+		return
+	}
+	
+	if len(report.Broken) > 0 {
+		fmt.Printf("Broken links (%d):\n", len(report.Broken))
+		for _, link := range report.Broken {
+			fmt.Printf("  %s:%d:%d: [[%s]] in %q\n", link.Path, link.Line+1, link.Column+1, link.Link, link.Title)
+		}
+	}
+	if len(report.Fuzzy) > 0 {
+		fmt.Printf("Fuzzy links (%d):\n", len(report.Fuzzy))
+		for _, link := range report.Fuzzy {
+			fmt.Printf("  %s:%d:%d: [[%s]] in %q only matches %q (%s)\n", link.Path, link.Line+1, link.Column+1,
+				link.Link, link.Title, link.Target.Title, link.Target.Path)
+		}
+	}
+	if len(report.Orphans) > 0 {
+		fmt.Printf("Orphans (%d):\n", len(report.Orphans))
+		for _, meta := range report.Orphans {
+			fmt.Printf("  %s: %q\n", meta.Path, meta.Title)
+		}
+	}
+}
+
+// runTasks prints the open todos of every note, one per line as
+// path:line: text, soonest due first
+func runTasks(s store.NoteStore, due, assignee string, now time.Time) error {
+	var within func(todo store.TodoItem) bool
+	switch due {
+	case "":
+		within = func(todo store.TodoItem) bool { return true }
+	case "overdue":
+		within = func(todo store.TodoItem) bool { return todo.Overdue(now) }
+	case "today":
+		within = func(todo store.TodoItem) bool { return todo.Overdue(now) || todo.DueWithin(now, 0) }
+	case "week":
+		within = func(todo store.TodoItem) bool { return todo.Overdue(now) || todo.DueWithin(now, 6) }
+	default:
+		return fmt.Errorf("unknown -due %q: use overdue, today or week", due)
+	}
+	assignee = strings.TrimPrefix(assignee, "@")
+	
+	type task struct {
+		path string
+		todo store.TodoItem
+	}
+	var tasks []task
+	err := s.Walk(func(note *store.Note) error {
+		for _, todo := range note.ExtractTodos() {
+			if todo.Completed || !within(todo) {
+				continue
+			}
+			if assignee != "" && !containsFold(todo.Assignees, assignee) {
+				continue
+			}
+			tasks = append(tasks, task{note.Path, todo})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	
+	// Undated tasks last
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i].todo.Due, tasks[j].todo.Due
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		if tasks[i].path != tasks[j].path {
+			return tasks[i].path < tasks[j].path
+		}
+		return tasks[i].todo.LineNum < tasks[j].todo.LineNum
+	})
+	for _, t := range tasks {
+		fmt.Printf("%s:%d: %s\n", t.path, t.todo.LineNum+1, t.todo.Text)
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -24,9 +24,11 @@ type SecurityConfig struct {
 	AuthMode          string `json:"auth_mode"` // "password", "key", "both", "none"
 	RequirePassword   bool   `json:"require_password"`
 	PasswordFile      string `json:"password_file"`
-	AuthorizedKeysFile string `json:"authorized_keys_file"`
+	PasswordAlgorithm string `json:"password_algorithm"` // "bcrypt" or "argon2id", for new hashes
+	AuthorizedKeysFile string `json:"authorized_keys_file"` // shared file; each key logs in only as the user its comment names
 	MaxLoginAttempts  int    `json:"max_login_attempts"`
-	SessionTimeout    int    `json:"session_timeout"` // seconds
+	SessionTimeout    int    `json:"session_timeout"` // idle seconds
+	MaxSessionDuration int    `json:"max_session_duration"` // absolute seconds
 }
 
 type DataConfig struct {
@@ -55,8 +57,10 @@ var DefaultConfig = Config{
 	Security: SecurityConfig{
 		AuthMode:         "none",
 		RequirePassword:  false,
+		PasswordAlgorithm: "bcrypt",
 		MaxLoginAttempts: 5,
 		SessionTimeout:   3600,
+		MaxSessionDuration: 8 * 3600,
 	},
 	Data: DataConfig{
 		BaseDir:         "./data",
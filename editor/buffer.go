@@ -0,0 +1,430 @@
+package editor
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// tabWidth is the number of cells a tab is drawn with
+const tabWidth = 4
+
+// runeWidth returns the number of terminal cells r takes up
+func runeWidth(r rune) int {
+	if r == '\t' {
+		return tabWidth
+	}
+	return runewidth.RuneWidth(r)
+}
+
+// splitLines turns text into the line buffer, normalising line endings
+func splitLines(text string) [][]rune {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	parts := strings.Split(text, "\n")
+	lines := make([][]rune, len(parts))
+	for i, part := range parts {
+		lines[i] = []rune(part)
+	}
+	return lines
+}
+
+// SetValue replaces the whole text and moves the cursor to the start. It
+// isn't recorded in the history; use Replace for an undoable change, or
+// SetHistory when loading a different text.
+func (m *Model) SetValue(text string) {
+	m.lines = splitLines(text)
+	m.row, m.col = 0, 0
+	m.goalX = -1
+	m.offset = 0
+	m.history.breakMerge()
+}
+
+// Value returns the whole text
+func (m Model) Value() string {
+	var b strings.Builder
+	for i, line := range m.lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(string(line))
+	}
+	return b.String()
+}
+
+// LineCount returns the number of lines
+func (m Model) LineCount() int {
+	return len(m.lines)
+}
+
+// Line returns line row, or "" if it doesn't exist
+func (m Model) Line(row int) string {
+	if row < 0 || row >= len(m.lines) {
+		return ""
+	}
+	return string(m.lines[row])
+}
+
+// Cursor returns the cursor position as a line and a rune offset in it
+func (m Model) Cursor() (row, col int) {
+	return m.row, m.col
+}
+
+// SetCursor moves the cursor, clamping it to the text
+func (m *Model) SetCursor(row, col int) {
+	if row < 0 {
+		row = 0
+	}
+	if row >= len(m.lines) {
+		row = len(m.lines) - 1
+	}
+	if col < 0 {
+		col = 0
+	}
+	if col > len(m.lines[row]) {
+		col = len(m.lines[row])
+	}
+	m.row, m.col = row, col
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// InsertString inserts text at the cursor and moves the cursor past it
+func (m *Model) InsertString(text string) {
+	if text == "" {
+		return
+	}
+
+	inserted := splitLines(text)
+	line := m.lines[m.row]
+	before := append([]rune{}, line[:m.col]...)
+	after := append([]rune{}, line[m.col:]...)
+
+	if len(inserted) == 1 {
+		m.lines[m.row] = append(append(before, inserted[0]...), after...)
+		m.col += len(inserted[0])
+	} else {
+		last := len(inserted) - 1
+		newLines := make([][]rune, 0, len(inserted))
+		newLines = append(newLines, append(before, inserted[0]...))
+		newLines = append(newLines, inserted[1:last]...)
+		newLines = append(newLines, append(append([]rune{}, inserted[last]...), after...))
+
+		m.lines = append(m.lines[:m.row], append(newLines, m.lines[m.row+1:]...)...)
+		m.row += last
+		m.col = len(inserted[last])
+	}
+
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// DeleteBackward deletes the rune before the cursor, joining lines at the
+// start of a line
+func (m *Model) DeleteBackward() {
+	switch {
+	case m.col > 0:
+		start := m.col - 1
+		for start > 0 && runeWidth(m.lines[m.row][start]) == 0 {
+			start--
+		}
+		m.lines[m.row] = append(m.lines[m.row][:start], m.lines[m.row][m.col:]...)
+		m.col = start
+	case m.row > 0:
+		prev := m.lines[m.row-1]
+		m.col = len(prev)
+		m.lines[m.row-1] = append(prev, m.lines[m.row]...)
+		m.lines = append(m.lines[:m.row], m.lines[m.row+1:]...)
+		m.row--
+	}
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// DeleteForward deletes the rune under the cursor, joining lines at the end
+// of a line
+func (m *Model) DeleteForward() {
+	line := m.lines[m.row]
+	switch {
+	case m.col < len(line):
+		end := m.col + 1
+		for end < len(line) && runeWidth(line[end]) == 0 {
+			end++
+		}
+		m.lines[m.row] = append(line[:m.col], line[end:]...)
+	case m.row < len(m.lines)-1:
+		m.lines[m.row] = append(line, m.lines[m.row+1]...)
+		m.lines = append(m.lines[:m.row+1], m.lines[m.row+2:]...)
+	}
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// DeleteWordBackward deletes from the start of the previous word to the
+// cursor
+func (m *Model) DeleteWordBackward() {
+	if m.col == 0 {
+		m.DeleteBackward()
+		return
+	}
+
+	line := m.lines[m.row]
+	start := m.col
+	for start > 0 && !isWordRune(line[start-1]) {
+		start--
+	}
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+	m.lines[m.row] = append(line[:start], line[m.col:]...)
+	m.col = start
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// DeleteToLineStart deletes from the start of the line to the cursor
+func (m *Model) DeleteToLineStart() {
+	m.lines[m.row] = append([]rune{}, m.lines[m.row][m.col:]...)
+	m.col = 0
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// DeleteToLineEnd deletes from the cursor to the end of the line
+func (m *Model) DeleteToLineEnd() {
+	m.lines[m.row] = m.lines[m.row][:m.col]
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// CursorLeft moves left one character, wrapping to the previous line
+func (m *Model) CursorLeft() {
+	switch {
+	case m.col > 0:
+		m.col--
+		for m.col > 0 && runeWidth(m.lines[m.row][m.col]) == 0 {
+			m.col--
+		}
+	case m.row > 0:
+		m.row--
+		m.col = len(m.lines[m.row])
+	}
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// CursorRight moves right one character, wrapping to the next line
+func (m *Model) CursorRight() {
+	line := m.lines[m.row]
+	switch {
+	case m.col < len(line):
+		m.col++
+		for m.col < len(line) && runeWidth(line[m.col]) == 0 {
+			m.col++
+		}
+	case m.row < len(m.lines)-1:
+		m.row++
+		m.col = 0
+	}
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// CursorLineStart moves to the start of the line
+func (m *Model) CursorLineStart() {
+	m.col = 0
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// CursorLineEnd moves to the end of the line
+func (m *Model) CursorLineEnd() {
+	m.col = len(m.lines[m.row])
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// MoveToBegin moves to the start of the text
+func (m *Model) MoveToBegin() {
+	m.SetCursor(0, 0)
+}
+
+// MoveToEnd moves to the end of the text
+func (m *Model) MoveToEnd() {
+	last := len(m.lines) - 1
+	m.SetCursor(last, len(m.lines[last]))
+}
+
+// isWordRune reports whether r is part of a word for word motions
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// WordLeft moves to the start of the current or previous word
+func (m *Model) WordLeft() {
+	for {
+		if m.col == 0 {
+			if m.row == 0 {
+				break
+			}
+			m.row--
+			m.col = len(m.lines[m.row])
+			continue
+		}
+		if isWordRune(m.lines[m.row][m.col-1]) {
+			break
+		}
+		m.col--
+	}
+	for m.col > 0 && isWordRune(m.lines[m.row][m.col-1]) {
+		m.col--
+	}
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// WordRight moves to the end of the current or next word
+func (m *Model) WordRight() {
+	for {
+		line := m.lines[m.row]
+		if m.col >= len(line) {
+			if m.row == len(m.lines)-1 {
+				break
+			}
+			m.row++
+			m.col = 0
+			continue
+		}
+		if isWordRune(line[m.col]) {
+			break
+		}
+		m.col++
+	}
+	line := m.lines[m.row]
+	for m.col < len(line) && isWordRune(line[m.col]) {
+		m.col++
+	}
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// Pos is a position in the text: a line and a rune offset within it. The
+// offset may equal the line length, meaning the end of the line.
+type Pos struct {
+	Row int
+	Col int
+}
+
+// Before reports whether p comes before q
+func (p Pos) Before(q Pos) bool {
+	return p.Row < q.Row || (p.Row == q.Row && p.Col < q.Col)
+}
+
+// orderPos returns a and b in text order
+func orderPos(a, b Pos) (Pos, Pos) {
+	if b.Before(a) {
+		return b, a
+	}
+	return a, b
+}
+
+// CursorPos returns the cursor position
+func (m Model) CursorPos() Pos {
+	return Pos{Row: m.row, Col: m.col}
+}
+
+// clampPos moves p inside the text
+func (m Model) clampPos(p Pos) Pos {
+	if p.Row < 0 {
+		p.Row = 0
+	}
+	if p.Row >= len(m.lines) {
+		p.Row = len(m.lines) - 1
+	}
+	if p.Col < 0 {
+		p.Col = 0
+	}
+	if p.Col > len(m.lines[p.Row]) {
+		p.Col = len(m.lines[p.Row])
+	}
+	return p
+}
+
+// Slice returns the text from from up to, but not including, to
+func (m Model) Slice(from, to Pos) string {
+	from, to = orderPos(m.clampPos(from), m.clampPos(to))
+	if from.Row == to.Row {
+		return string(m.lines[from.Row][from.Col:to.Col])
+	}
+
+	var b strings.Builder
+	b.WriteString(string(m.lines[from.Row][from.Col:]))
+	for row := from.Row + 1; row < to.Row; row++ {
+		b.WriteByte('\n')
+		b.WriteString(string(m.lines[row]))
+	}
+	b.WriteByte('\n')
+	b.WriteString(string(m.lines[to.Row][:to.Col]))
+	return b.String()
+}
+
+// DeleteRange deletes the text from from up to, but not including, to and
+// leaves the cursor where it started
+func (m *Model) DeleteRange(from, to Pos) {
+	from, to = orderPos(m.clampPos(from), m.clampPos(to))
+
+	joined := append(append([]rune{}, m.lines[from.Row][:from.Col]...), m.lines[to.Row][to.Col:]...)
+	m.lines = append(m.lines[:from.Row+1], m.lines[to.Row+1:]...)
+	m.lines[from.Row] = joined
+
+	m.row, m.col = from.Row, from.Col
+	m.goalX = -1
+	m.ensureVisible()
+}
+
+// ReplaceLines replaces lines from through to (inclusive) with text, which
+// may span several lines. With no text the lines are removed, keeping at
+// least one empty line in the buffer.
+func (m *Model) ReplaceLines(from, to int, text []string) {
+	if from < 0 {
+		from = 0
+	}
+	if to >= len(m.lines) {
+		to = len(m.lines) - 1
+	}
+
+	replacement := make([][]rune, len(text))
+	for i, line := range text {
+		replacement[i] = []rune(line)
+	}
+
+	tail := append([][]rune{}, m.lines[to+1:]...)
+	m.lines = append(append(m.lines[:from], replacement...), tail...)
+	if len(m.lines) == 0 {
+		m.lines = [][]rune{{}}
+	}
+
+	m.SetCursor(from, 0)
+}
+
+// InsertLines inserts whole lines before line at (at may be LineCount() to
+// append) and moves the cursor to the first of them
+func (m *Model) InsertLines(at int, text []string) {
+	if at < 0 {
+		at = 0
+	}
+	if at > len(m.lines) {
+		at = len(m.lines)
+	}
+
+	inserted := make([][]rune, len(text))
+	for i, line := range text {
+		inserted[i] = []rune(line)
+	}
+
+	tail := append([][]rune{}, m.lines[at:]...)
+	m.lines = append(append(m.lines[:at], inserted...), tail...)
+	m.SetCursor(at, 0)
+}
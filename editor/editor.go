@@ -0,0 +1,538 @@
+// Package editor implements the multi-line text editor used for note
+// content: a line buffer with soft wrapping, line numbers, scrolling and
+// wide Unicode support, driven by Bubble Tea key messages.
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Model is a multi-line text editor. Positions are a line index and a rune
+// offset within the line.
+type Model struct {
+	lines  [][]rune
+	row    int
+	col    int
+	goalX  int // display column kept across vertical moves, -1 when unset
+	offset int // first visible screen row
+
+	focused bool
+
+	sel selection
+
+	highlights   map[int][]Range // by line
+	currentMatch Range
+
+	history *History
+
+	// Width and Height are the size of the editor in cells, including the
+	// line number gutter. Set them with SetSize.
+	Width  int
+	Height int
+
+	ShowLineNumbers bool
+	Placeholder     string
+
+	// Colorize, if set, styles parts of the text, such as code. It gets the
+	// whole text and returns the styled runs of each line.
+	Colorize func(text string) [][]Colored
+
+	CursorStyle            lipgloss.Style
+	SelectionStyle         lipgloss.Style
+	MatchStyle             lipgloss.Style
+	CurrentMatchStyle      lipgloss.Style
+	LineNumberStyle        lipgloss.Style
+	CurrentLineNumberStyle lipgloss.Style
+	PlaceholderStyle       lipgloss.Style
+}
+
+// Colored is a styled run of a line, from column From up to To
+type Colored struct {
+	From  int
+	To    int
+	Style lipgloss.Style
+}
+
+// New returns an empty, focused editor
+func New() Model {
+	return Model{
+		lines:           [][]rune{{}},
+		goalX:           -1,
+		history:         NewHistory(),
+		currentMatch:    Range{From: Pos{Row: -1}},
+		focused:         true,
+		Width:           80,
+		Height:          10,
+		ShowLineNumbers: true,
+
+		CursorStyle:            lipgloss.NewStyle().Reverse(true),
+		SelectionStyle:         lipgloss.NewStyle().Background(lipgloss.Color("238")),
+		MatchStyle:             lipgloss.NewStyle().Background(lipgloss.Color("58")),
+		CurrentMatchStyle:      lipgloss.NewStyle().Background(lipgloss.Color("214")).Foreground(lipgloss.Color("0")),
+		LineNumberStyle:        lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+		CurrentLineNumberStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("250")),
+		PlaceholderStyle:       lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+	}
+}
+
+// Focus shows the cursor and makes Update handle keys
+func (m *Model) Focus() {
+	m.focused = true
+}
+
+// Blur hides the cursor and makes Update ignore keys
+func (m *Model) Blur() {
+	m.focused = false
+}
+
+// Focused reports whether the editor has focus
+func (m Model) Focused() bool {
+	return m.focused
+}
+
+// SetSize resizes the editor, keeping the cursor in view
+func (m *Model) SetSize(width, height int) {
+	if width < 4 {
+		width = 4
+	}
+	if height < 1 {
+		height = 1
+	}
+	m.Width, m.Height = width, height
+	m.ensureVisible()
+}
+
+// selection is a highlighted range of text, from and to both inclusive;
+// a linewise selection covers whole lines
+type selection struct {
+	active   bool
+	from     Pos
+	to       Pos
+	linewise bool
+}
+
+// SetSelection highlights the text between from and to, inclusive. With
+// linewise set, every line between them is highlighted in full.
+func (m *Model) SetSelection(from, to Pos, linewise bool) {
+	from, to = orderPos(from, to)
+	m.sel = selection{active: true, from: from, to: to, linewise: linewise}
+}
+
+// ClearSelection removes the selection highlight
+func (m *Model) ClearSelection() {
+	m.sel = selection{}
+}
+
+// selected reports whether the rune at row, col is highlighted
+func (m Model) selected(row, col int) bool {
+	if !m.sel.active || row < m.sel.from.Row || row > m.sel.to.Row {
+		return false
+	}
+	if m.sel.linewise {
+		return true
+	}
+	p := Pos{Row: row, Col: col}
+	return !p.Before(m.sel.from) && !m.sel.to.Before(p)
+}
+
+// gutterWidth is the width of the line number column, including its
+// trailing space
+func (m Model) gutterWidth() int {
+	if !m.ShowLineNumbers {
+		return 0
+	}
+	return len(fmt.Sprint(len(m.lines))) + 1
+}
+
+// wrapWidth is the number of cells text wraps at. One cell is kept free so
+// the cursor can sit after the last character of a full row.
+func (m Model) wrapWidth() int {
+	w := m.Width - m.gutterWidth() - 1
+	if w < 1 {
+		w = 1
+	}
+	return w
+}
+
+// segment is one screen row of a line: runes [start, end)
+type segment struct {
+	start int
+	end   int
+}
+
+// wrapLine splits a line into screen rows no wider than width cells,
+// breaking after spaces where possible
+func wrapLine(line []rune, width int) []segment {
+	var segments []segment
+
+	start, x, lastSpace := 0, 0, -1
+	for i, r := range line {
+		w := runeWidth(r)
+		if x+w > width && i > start {
+			end := i
+			if lastSpace >= start {
+				end = lastSpace + 1
+			}
+			segments = append(segments, segment{start, end})
+			start = end
+			x = 0
+			for _, r := range line[start:i] {
+				x += runeWidth(r)
+			}
+			lastSpace = -1
+		}
+		if r == ' ' {
+			lastSpace = i
+		}
+		x += w
+	}
+
+	return append(segments, segment{start, len(line)})
+}
+
+// segmentOf returns the index of the segment holding col
+func segmentOf(segments []segment, col int) int {
+	for i, seg := range segments {
+		if col < seg.end || i == len(segments)-1 {
+			return i
+		}
+	}
+	return len(segments) - 1
+}
+
+// displayX returns the display column of col within its segment
+func displayX(line []rune, seg segment, col int) int {
+	x := 0
+	for _, r := range line[seg.start:col] {
+		x += runeWidth(r)
+	}
+	return x
+}
+
+// colAtX returns the rune offset in seg closest to display column x without
+// passing it. The end of a segment belongs to the next one, so only the
+// last segment of a line can return its end.
+func colAtX(line []rune, seg segment, x int, last bool) int {
+	limit := seg.end
+	if !last && limit > seg.start {
+		limit--
+	}
+
+	col, cx := seg.start, 0
+	for col < limit {
+		w := runeWidth(line[col])
+		if cx+w > x {
+			break
+		}
+		cx += w
+		col++
+		for col < limit && runeWidth(line[col]) == 0 {
+			col++
+		}
+	}
+	return col
+}
+
+// cursorScreenRow returns the screen row of the cursor counted from the top
+// of the text
+func (m Model) cursorScreenRow() int {
+	width := m.wrapWidth()
+	row := 0
+	for i := 0; i < m.row; i++ {
+		row += len(wrapLine(m.lines[i], width))
+	}
+	return row + segmentOf(wrapLine(m.lines[m.row], width), m.col)
+}
+
+// ensureVisible scrolls so the cursor is on screen
+func (m *Model) ensureVisible() {
+	row := m.cursorScreenRow()
+	if row < m.offset {
+		m.offset = row
+	}
+	if row >= m.offset+m.Height {
+		m.offset = row - m.Height + 1
+	}
+}
+
+// moveVertical moves the cursor n screen rows down (or up when negative),
+// keeping its display column
+func (m *Model) moveVertical(n int) {
+	width := m.wrapWidth()
+	segments := wrapLine(m.lines[m.row], width)
+	seg := segmentOf(segments, m.col)
+	if m.goalX < 0 {
+		m.goalX = displayX(m.lines[m.row], segments[seg], m.col)
+	}
+
+	for ; n > 0; n-- {
+		if seg < len(segments)-1 {
+			seg++
+		} else if m.row < len(m.lines)-1 {
+			m.row++
+			segments = wrapLine(m.lines[m.row], width)
+			seg = 0
+		} else {
+			m.col = len(m.lines[m.row])
+			m.ensureVisible()
+			return
+		}
+	}
+	for ; n < 0; n++ {
+		if seg > 0 {
+			seg--
+		} else if m.row > 0 {
+			m.row--
+			segments = wrapLine(m.lines[m.row], width)
+			seg = len(segments) - 1
+		} else {
+			m.col = 0
+			m.ensureVisible()
+			return
+		}
+	}
+
+	m.col = colAtX(m.lines[m.row], segments[seg], m.goalX, seg == len(segments)-1)
+	m.ensureVisible()
+}
+
+// CursorUp moves up one screen row
+func (m *Model) CursorUp() {
+	m.moveVertical(-1)
+}
+
+// CursorDown moves down one screen row
+func (m *Model) CursorDown() {
+	m.moveVertical(1)
+}
+
+// PageUp moves up one screen
+func (m *Model) PageUp() {
+	m.moveVertical(-m.Height)
+}
+
+// PageDown moves down one screen
+func (m *Model) PageDown() {
+	m.moveVertical(m.Height)
+}
+
+// Update handles editing and cursor keys
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if !m.focused {
+		return m, nil
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	before := m.state()
+	kind, typed := editOther, ""
+
+	switch key.String() {
+	case "ctrl+z":
+		m.Undo()
+		return m, nil
+	case "ctrl+y":
+		m.Redo()
+		return m, nil
+	case "left", "ctrl+b":
+		m.CursorLeft()
+	case "right", "ctrl+f":
+		m.CursorRight()
+	case "up":
+		m.CursorUp()
+	case "down":
+		m.CursorDown()
+	case "alt+left", "ctrl+left", "alt+b":
+		m.WordLeft()
+	case "alt+right", "ctrl+right", "alt+f":
+		m.WordRight()
+	case "home", "ctrl+a":
+		m.CursorLineStart()
+	case "end", "ctrl+e":
+		m.CursorLineEnd()
+	case "ctrl+home":
+		m.MoveToBegin()
+	case "ctrl+end":
+		m.MoveToEnd()
+	case "pgup":
+		m.PageUp()
+	case "pgdown":
+		m.PageDown()
+	case "enter", "ctrl+m":
+		m.InsertString("\n")
+		kind, typed = editTyping, "\n"
+	case "tab":
+		m.InsertString("\t")
+		kind, typed = editTyping, "\t"
+	case "backspace", "ctrl+h":
+		m.DeleteBackward()
+		kind = editDeleting
+	case "delete", "ctrl+d":
+		m.DeleteForward()
+		kind = editDeleting
+	case "ctrl+w", "alt+backspace":
+		m.DeleteWordBackward()
+	case "ctrl+u":
+		m.DeleteToLineStart()
+	case "ctrl+k":
+		m.DeleteToLineEnd()
+	default:
+		if key.Type == tea.KeyRunes || key.Type == tea.KeySpace {
+			m.InsertString(string(key.Runes))
+			// A paste is a step of its own
+			if len(key.Runes) == 1 {
+				kind, typed = editTyping, string(key.Runes)
+			}
+		}
+	}
+
+	if m.Value() != before.text {
+		m.record(before, kind, typed)
+	}
+	return m, nil
+}
+
+// View renders the visible rows of the editor
+func (m Model) View() string {
+	width := m.wrapWidth()
+	gutter := m.gutterWidth()
+
+	if len(m.lines) == 1 && len(m.lines[0]) == 0 && m.Placeholder != "" {
+		cursor := " "
+		if m.focused {
+			cursor = m.CursorStyle.Render(" ")
+		}
+		line := m.PlaceholderStyle.Render(m.Placeholder)
+		if gutter > 0 {
+			line = m.CurrentLineNumberStyle.Render(fmt.Sprintf("%*d ", gutter-1, 1)) + cursor + line
+		} else {
+			line = cursor + line
+		}
+		return line + strings.Repeat("\n", m.Height-1)
+	}
+
+	// Keep the cursor in view even if the size changed since the last update
+	offset := m.offset
+	if cursorRow := m.cursorScreenRow(); cursorRow < offset {
+		offset = cursorRow
+	} else if cursorRow >= offset+m.Height {
+		offset = cursorRow - m.Height + 1
+	}
+
+	var colors [][]Colored
+	if m.Colorize != nil {
+		colors = m.Colorize(m.Value())
+	}
+
+	rows := make([]string, 0, m.Height)
+	screenRow := 0
+	for i := 0; i < len(m.lines) && len(rows) < m.Height; i++ {
+		line := m.lines[i]
+		segments := wrapLine(line, width)
+		if screenRow+len(segments) <= offset {
+			screenRow += len(segments)
+			continue
+		}
+
+		for s, seg := range segments {
+			if screenRow < offset {
+				screenRow++
+				continue
+			}
+			if len(rows) >= m.Height {
+				break
+			}
+
+			var b strings.Builder
+			if gutter > 0 {
+				switch {
+				case s > 0:
+					b.WriteString(strings.Repeat(" ", gutter))
+				case i == m.row:
+					b.WriteString(m.CurrentLineNumberStyle.Render(fmt.Sprintf("%*d ", gutter-1, i+1)))
+				default:
+					b.WriteString(m.LineNumberStyle.Render(fmt.Sprintf("%*d ", gutter-1, i+1)))
+				}
+			}
+
+			cursorHere := m.focused && i == m.row && segmentOf(segments, m.col) == s
+			var lineColors []Colored
+			if i < len(colors) {
+				lineColors = colors[i]
+			}
+			b.WriteString(m.renderSegment(i, seg, cursorHere, lineColors))
+
+			rows = append(rows, b.String())
+			screenRow++
+		}
+	}
+
+	for len(rows) < m.Height {
+		rows = append(rows, "")
+	}
+	return strings.Join(rows, "\n")
+}
+
+// renderSegment draws one screen row of line row, with the cursor if it is
+// on it, styling the colored runs of the line
+func (m Model) renderSegment(row int, seg segment, cursorHere bool, colors []Colored) string {
+	line := m.lines[row]
+	var b strings.Builder
+	for col := seg.start; col < seg.end; col++ {
+		r := line[col]
+
+		// Keep combining marks with the character they modify
+		end := col + 1
+		for end < seg.end && runeWidth(line[end]) == 0 {
+			end++
+		}
+		cell := string(line[col:end])
+		if r == '\t' {
+			cell = strings.Repeat(" ", tabWidth)
+		}
+
+		switch {
+		case cursorHere && col == m.col:
+			b.WriteString(m.CursorStyle.Render(cell))
+		case m.selected(row, col):
+			b.WriteString(m.SelectionStyle.Render(cell))
+		case m.highlightAt(row, col) == 2:
+			b.WriteString(m.CurrentMatchStyle.Render(cell))
+		case m.highlightAt(row, col) == 1:
+			b.WriteString(m.MatchStyle.Render(cell))
+		default:
+			if style, ok := colorAt(colors, col); ok {
+				b.WriteString(style.Render(cell))
+			} else {
+				b.WriteString(cell)
+			}
+		}
+		col = end - 1
+	}
+
+	switch {
+	case cursorHere && m.col >= seg.end:
+		b.WriteString(m.CursorStyle.Render(" "))
+	case seg.end == len(line) && m.selected(row, len(line)) && (m.sel.linewise || row < m.sel.to.Row || len(line) == 0):
+		// Show that the line break (or an empty line) is selected
+		b.WriteString(m.SelectionStyle.Render(" "))
+	}
+	return b.String()
+}
+
+// colorAt returns the style of the colored run containing col, if any
+func colorAt(colors []Colored, col int) (lipgloss.Style, bool) {
+	for _, c := range colors {
+		if col >= c.From && col < c.To {
+			return c.Style, true
+		}
+	}
+	return lipgloss.Style{}, false
+}
@@ -0,0 +1,198 @@
+package editor
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Range is the text from From up to, but not including, To
+type Range struct {
+	From Pos
+	To   Pos
+}
+
+// FindOptions controls how FindAll matches
+type FindOptions struct {
+	CaseSensitive bool
+	Regex         bool // the query is a Go regular expression
+}
+
+// compileFind turns a query into a regular expression
+func compileFind(query string, opts FindOptions) (*regexp.Regexp, error) {
+	expr := query
+	if !opts.Regex {
+		expr = regexp.QuoteMeta(query)
+	}
+	if !opts.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// lineMatches returns the byte offsets of the non-empty matches of re in
+// line, with their groups
+func lineMatches(re *regexp.Regexp, line string) [][]int {
+	var matches [][]int
+	for _, loc := range re.FindAllStringSubmatchIndex(line, -1) {
+		if loc[1] > loc[0] {
+			matches = append(matches, loc)
+		}
+	}
+	return matches
+}
+
+// FindAll returns every match of query in text order. Matches don't span
+// lines, and empty matches are skipped.
+func (m Model) FindAll(query string, opts FindOptions) ([]Range, error) {
+	if query == "" {
+		return nil, nil
+	}
+	re, err := compileFind(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranges []Range
+	for row, line := range m.lines {
+		text := string(line)
+		for _, loc := range lineMatches(re, text) {
+			ranges = append(ranges, Range{
+				From: Pos{Row: row, Col: utf8.RuneCountInString(text[:loc[0]])},
+				To:   Pos{Row: row, Col: utf8.RuneCountInString(text[:loc[1]])},
+			})
+		}
+	}
+	return ranges, nil
+}
+
+// expand returns the replacement for one match: in regex mode $1, ${1} and
+// ${name} refer to the match's groups, otherwise it is used as is
+func expand(re *regexp.Regexp, replacement, line string, loc []int, opts FindOptions) string {
+	if !opts.Regex {
+		return replacement
+	}
+	return string(re.ExpandString(nil, replacement, line, loc))
+}
+
+// ReplaceMatch replaces a match returned by FindAll as one undoable edit
+// and leaves the cursor after the replacement. It returns false if the
+// text no longer matches there.
+func (m *Model) ReplaceMatch(r Range, query, replacement string, opts FindOptions) (bool, error) {
+	re, err := compileFind(query, opts)
+	if err != nil {
+		return false, err
+	}
+	if r.From.Row < 0 || r.From.Row >= len(m.lines) {
+		return false, nil
+	}
+
+	line := m.lines[r.From.Row]
+	text := string(line)
+	if r.From.Col > len(line) || r.To.Col > len(line) {
+		return false, nil
+	}
+	start := len(string(line[:r.From.Col]))
+	end := len(string(line[:r.To.Col]))
+
+	for _, loc := range lineMatches(re, text) {
+		if loc[0] != start || loc[1] != end {
+			continue
+		}
+
+		before := m.state()
+		with := expand(re, replacement, text, loc, opts)
+		m.ReplaceLines(r.From.Row, r.From.Row, strings.Split(text[:start]+with+text[end:], "\n"))
+		m.moveTo(r.From, with)
+
+		m.history.breakMerge()
+		m.record(before, editOther, "")
+		return true, nil
+	}
+	return false, nil
+}
+
+// moveTo puts the cursor just past text inserted at p
+func (m *Model) moveTo(p Pos, inserted string) {
+	lines := strings.Split(inserted, "\n")
+	if len(lines) == 1 {
+		m.SetCursor(p.Row, p.Col+utf8.RuneCountInString(inserted))
+		return
+	}
+	m.SetCursor(p.Row+len(lines)-1, utf8.RuneCountInString(lines[len(lines)-1]))
+}
+
+// ReplaceAll replaces every match of query as a single undoable edit and
+// returns how many were replaced
+func (m *Model) ReplaceAll(query, replacement string, opts FindOptions) (int, error) {
+	if query == "" {
+		return 0, nil
+	}
+	re, err := compileFind(query, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	out := make([]string, len(m.lines))
+	for row, line := range m.lines {
+		text := string(line)
+		var b strings.Builder
+		prev := 0
+		for _, loc := range lineMatches(re, text) {
+			b.WriteString(text[prev:loc[0]])
+			b.WriteString(expand(re, replacement, text, loc, opts))
+			prev = loc[1]
+			count++
+		}
+		b.WriteString(text[prev:])
+		out[row] = b.String()
+	}
+
+	if count > 0 {
+		m.Replace(strings.Join(out, "\n"))
+	}
+	return count, nil
+}
+
+// SetHighlights marks ranges, such as search matches, with MatchStyle, and
+// the one at index current with CurrentMatchStyle (-1 for none)
+func (m *Model) SetHighlights(ranges []Range, current int) {
+	m.highlights = make(map[int][]Range)
+	for i, r := range ranges {
+		m.highlights[r.From.Row] = append(m.highlights[r.From.Row], r)
+		if i == current {
+			m.currentMatch = r
+		}
+	}
+	for _, rowRanges := range m.highlights {
+		sort.Slice(rowRanges, func(i, j int) bool { return rowRanges[i].From.Before(rowRanges[j].From) })
+	}
+	if current < 0 || current >= len(ranges) {
+		m.currentMatch = Range{From: Pos{Row: -1}}
+	}
+}
+
+// ClearHighlights removes the highlights set with SetHighlights
+func (m *Model) ClearHighlights() {
+	m.highlights = nil
+	m.currentMatch = Range{From: Pos{Row: -1}}
+}
+
+// highlightAt returns 1 if the rune at row, col is in a highlighted range,
+// 2 if it is in the current one, and 0 otherwise
+func (m Model) highlightAt(row, col int) int {
+	for _, r := range m.highlights[row] {
+		if col < r.From.Col {
+			break
+		}
+		if col < r.To.Col {
+			if r == m.currentMatch {
+				return 2
+			}
+			return 1
+		}
+	}
+	return 0
+}
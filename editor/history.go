@@ -0,0 +1,202 @@
+package editor
+
+import "unicode"
+
+// historyLimit is the number of undo steps kept
+const historyLimit = 500
+
+// editState is the text and cursor at one point in the history
+type editState struct {
+	text     string
+	row, col int
+}
+
+// editKind classifies an edit so runs of the same kind can be undone as
+// one step
+type editKind int
+
+const (
+	editOther editKind = iota
+	editTyping
+	editDeleting
+)
+
+// History is the undo and redo stack of an editor. It belongs to a text
+// rather than to an editor, so the owner can keep one per note and hand it
+// back when the note is reopened.
+type History struct {
+	undo []editState
+	redo []editState
+
+	// Consecutive typing or deleting merges into one step until the cursor
+	// jumps or a new word starts
+	lastKind  editKind
+	lastPos   Pos
+	lastSpace bool
+
+	// An open group collects every change until it is closed
+	depth int
+	start editState
+}
+
+// NewHistory returns an empty history
+func NewHistory() *History {
+	return &History{}
+}
+
+// CanUndo reports whether there is anything to undo
+func (h *History) CanUndo() bool {
+	return len(h.undo) > 0
+}
+
+// CanRedo reports whether there is anything to redo
+func (h *History) CanRedo() bool {
+	return len(h.redo) > 0
+}
+
+func (h *History) push(state editState) {
+	h.undo = append(h.undo, state)
+	if len(h.undo) > historyLimit {
+		h.undo = append([]editState{}, h.undo[len(h.undo)-historyLimit:]...)
+	}
+}
+
+// breakMerge stops the next edit from merging into the last one
+func (h *History) breakMerge() {
+	h.lastKind = editOther
+}
+
+// state captures the text and cursor
+func (m Model) state() editState {
+	return editState{text: m.Value(), row: m.row, col: m.col}
+}
+
+// restore puts back a captured state
+func (m *Model) restore(state editState) {
+	m.lines = splitLines(state.text)
+	m.SetCursor(state.row, state.col)
+}
+
+// SetHistory makes the editor record into h, e.g. the saved history of
+// the note being opened
+func (m *Model) SetHistory(h *History) {
+	if h == nil {
+		h = NewHistory()
+	}
+	m.history = h
+}
+
+// History returns the undo history
+func (m Model) History() *History {
+	return m.history
+}
+
+// record adds the state before a change to the history. typed is the text
+// inserted by a typing edit, used to start a new step at each word.
+func (m *Model) record(before editState, kind editKind, typed string) {
+	h := m.history
+	h.redo = nil
+	if h.depth > 0 {
+		return
+	}
+
+	startsWord := false
+	if typed != "" {
+		r := []rune(typed)
+		space := unicode.IsSpace(r[len(r)-1])
+		startsWord = h.lastSpace && !unicode.IsSpace(r[0])
+		h.lastSpace = space
+	}
+
+	merge := kind != editOther && kind == h.lastKind && !startsWord &&
+		before.row == h.lastPos.Row && before.col == h.lastPos.Col
+	if !merge {
+		h.push(before)
+	}
+
+	h.lastKind = kind
+	h.lastPos = m.CursorPos()
+}
+
+// BeginGroup starts collecting changes into a single undo step, such as a
+// vim command and the insert session it starts. Groups nest; the step is
+// recorded when the outermost group ends.
+func (m *Model) BeginGroup() {
+	h := m.history
+	if h.depth == 0 {
+		h.start = m.state()
+	}
+	h.depth++
+}
+
+// EndGroup closes a group opened with BeginGroup
+func (m *Model) EndGroup() {
+	h := m.history
+	if h.depth == 0 {
+		return
+	}
+	h.depth--
+	if h.depth > 0 {
+		return
+	}
+	if m.Value() != h.start.text {
+		h.redo = nil
+		h.push(h.start)
+	}
+	h.breakMerge()
+}
+
+// Undo reverts the last change, returning false if there was none
+func (m *Model) Undo() bool {
+	h := m.history
+	if len(h.undo) == 0 {
+		return false
+	}
+
+	current := m.state()
+	prev := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, current)
+	m.restore(prev)
+
+	// An undo inside a group isn't a change of the group's own
+	if h.depth > 0 {
+		h.start = m.state()
+	}
+	h.breakMerge()
+	return true
+}
+
+// Redo reapplies the last undone change, returning false if there was none
+func (m *Model) Redo() bool {
+	h := m.history
+	if len(h.redo) == 0 {
+		return false
+	}
+
+	current := m.state()
+	next := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, current)
+	m.restore(next)
+
+	if h.depth > 0 {
+		h.start = m.state()
+	}
+	h.breakMerge()
+	return true
+}
+
+// Replace swaps in new text as a single undoable change, keeping the cursor
+// where it was as far as the new text allows. It is for changes made
+// outside of typing, such as restoring a version or a replace-all.
+func (m *Model) Replace(text string) {
+	before := m.state()
+	if text == before.text {
+		return
+	}
+	m.lines = splitLines(text)
+	m.SetCursor(before.row, before.col)
+	m.history.breakMerge()
+	m.record(before, editOther, "")
+}
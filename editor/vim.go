@@ -0,0 +1,1158 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// VimMode is the mode of the vim keymap
+type VimMode int
+
+const (
+	VimNormal VimMode = iota
+	VimInsert
+	VimVisual
+	VimVisualLine
+	VimCommandLine
+)
+
+func (mode VimMode) String() string {
+	switch mode {
+	case VimInsert:
+		return "INSERT"
+	case VimVisual:
+		return "VISUAL"
+	case VimVisualLine:
+		return "VISUAL LINE"
+	case VimCommandLine:
+		return "COMMAND"
+	}
+	return "NORMAL"
+}
+
+// VimAction is something the editor's owner has to do in response to a
+// command, such as saving for :w
+type VimAction int
+
+const (
+	VimNone VimAction = iota
+	VimWrite
+	VimQuit
+	VimWriteQuit
+	VimForceQuit
+	VimDisable // :set novim
+)
+
+// Register holds yanked or deleted text. Linewise text is whole lines
+// without the final line break.
+type Register struct {
+	Text     string
+	Linewise bool
+}
+
+// Vim implements vim-style modal editing on top of a Model: motions,
+// counts, operators, text objects, visual mode, registers, dot-repeat,
+// search and ex commands.
+type Vim struct {
+	Mode VimMode
+
+	// Message is feedback for the status line, e.g. an error from an ex
+	// command. It is cleared by the next key.
+	Message string
+
+	// CommandLine is the ex command or search being typed, after Prompt
+	CommandLine string
+	Prompt      string
+
+	registers map[rune]Register
+
+	pending     []tea.KeyMsg // keys of the normal or visual command being typed
+	want        int          // column j and k aim for; -1 means end of line
+	visualStart Pos
+	lastVisual  [2]int // first and last line of the last visual selection
+
+	lastFind   rune // last f, F, t or T
+	lastFindCh rune
+
+	lastSearch        string
+	lastSearchForward bool
+
+	lastChange []tea.KeyMsg // keys of the last change, for .
+	recording  []tea.KeyMsg // keys of a change still in insert mode
+	replaying  bool
+
+	insert insertSession
+}
+
+// insertSession is what an insert command's count repeats when the
+// session ends: 3ix<Esc> types x three times, 2ox<Esc> opens two lines
+type insertSession struct {
+	count  int
+	opens  bool   // o or O: each repeat opens a new line below
+	indent string // indent of the lines o or O open
+	typed  []tea.KeyMsg
+}
+
+// NewVim returns a vim keymap in normal mode
+func NewVim() *Vim {
+	return &Vim{
+		registers:  make(map[rune]Register),
+		want:       -1,
+		lastVisual: [2]int{-1, -1},
+	}
+}
+
+// Reset returns to normal mode, e.g. when another note is opened.
+// Registers survive so text can be yanked between notes.
+func (v *Vim) Reset(ed *Model) {
+	if v.Mode == VimInsert {
+		ed.EndGroup()
+	}
+	v.Mode = VimNormal
+	v.pending = nil
+	v.recording = nil
+	v.insert = insertSession{}
+	v.CommandLine = ""
+	v.Message = ""
+	v.want = -1
+	ed.ClearSelection()
+}
+
+// Pending returns the keys of the command being typed, for display
+func (v *Vim) Pending() string {
+	var b strings.Builder
+	for _, msg := range v.pending {
+		b.WriteString(keyToken(msg))
+	}
+	return b.String()
+}
+
+// MessageIsError reports whether Message is an error, which vim numbers
+// like "E492: Not an editor command"
+func (v *Vim) MessageIsError() bool {
+	digits := strings.TrimPrefix(v.Message, "E")
+	if digits == v.Message {
+		return false
+	}
+	n := 0
+	for n < len(digits) && digits[n] >= '0' && digits[n] <= '9' {
+		n++
+	}
+	return n > 0 && strings.HasPrefix(digits[n:], ":")
+}
+
+// Register returns the contents of a register
+func (v *Vim) Register(name rune) (Register, bool) {
+	r, ok := v.registers[registerKey(name)]
+	return r, ok
+}
+
+// keyToken turns a key into the token the command parser works on
+func keyToken(msg tea.KeyMsg) string {
+	switch {
+	case msg.Type == tea.KeyRunes && len(msg.Runes) == 1:
+		return string(msg.Runes[0])
+	case msg.Type == tea.KeySpace:
+		return " "
+	}
+	return msg.String()
+}
+
+// HandleKey handles one key press. Each command, together with the insert
+// session it starts, is a single undo step.
+func (v *Vim) HandleKey(ed *Model, msg tea.KeyMsg) VimAction {
+	if v.Mode != VimInsert {
+		ed.BeginGroup()
+	}
+	action := v.handleKey(ed, msg)
+	if v.Mode != VimInsert {
+		ed.EndGroup()
+	}
+	return action
+}
+
+func (v *Vim) handleKey(ed *Model, msg tea.KeyMsg) VimAction {
+	if v.Mode != VimCommandLine {
+		v.Message = ""
+	}
+
+	switch v.Mode {
+	case VimInsert:
+		v.handleInsert(ed, msg)
+		return VimNone
+	case VimCommandLine:
+		return v.handleCommandLine(ed, msg)
+	}
+
+	// Multi-rune input outside insert mode is a paste; ignore it
+	if msg.Type == tea.KeyRunes && len(msg.Runes) != 1 {
+		return VimNone
+	}
+
+	if keyToken(msg) == "esc" && len(v.pending) > 0 {
+		v.pending = nil
+		return VimNone
+	}
+
+	v.pending = append(v.pending, msg)
+	cmd, status := parseVimCommand(v.pending, v.visual())
+	switch status {
+	case parseIncomplete:
+		return VimNone
+	case parseInvalid:
+		v.pending = nil
+		return VimNone
+	}
+
+	keys := v.pending
+	v.pending = nil
+
+	var action VimAction
+	if v.visual() {
+		action = v.executeVisual(ed, cmd)
+	} else {
+		action = v.executeNormal(ed, cmd)
+		if !v.replaying && isChange(cmd) {
+			if v.Mode == VimInsert {
+				v.recording = append([]tea.KeyMsg{}, keys...)
+			} else {
+				v.lastChange = append([]tea.KeyMsg{}, keys...)
+			}
+		}
+	}
+
+	if v.Mode == VimNormal {
+		v.clampNormal(ed)
+	}
+	if v.visual() {
+		ed.SetSelection(v.visualStart, ed.CursorPos(), v.Mode == VimVisualLine)
+	}
+	return action
+}
+
+func (v *Vim) visual() bool {
+	return v.Mode == VimVisual || v.Mode == VimVisualLine
+}
+
+// clampNormal keeps the cursor on a character, as normal mode has no
+// position past the end of a line
+func (v *Vim) clampNormal(ed *Model) {
+	if n := len(ed.lines[ed.row]); ed.col >= n && n > 0 {
+		ed.col = n - 1
+	}
+}
+
+// handleInsert passes keys to the editor until Esc
+func (v *Vim) handleInsert(ed *Model, msg tea.KeyMsg) {
+	if v.recording != nil && !v.replaying {
+		v.recording = append(v.recording, msg)
+	}
+
+	if keyToken(msg) == "esc" {
+		v.repeatInsert(ed)
+		v.Mode = VimNormal
+		if v.recording != nil && !v.replaying {
+			v.lastChange = v.recording
+		}
+		v.recording = nil
+		if ed.col > 0 {
+			ed.col--
+		}
+		v.want = ed.col
+		return
+	}
+
+	v.insert.typed = append(v.insert.typed, msg)
+	*ed, _ = ed.Update(msg)
+}
+
+// repeatInsert types the text of the insert session that is ending again,
+// count-1 more times
+func (v *Vim) repeatInsert(ed *Model) {
+	for i := 1; i < v.insert.count; i++ {
+		if v.insert.opens {
+			row := ed.row + 1
+			ed.InsertLines(row, []string{v.insert.indent})
+			ed.SetCursor(row, len([]rune(v.insert.indent)))
+		}
+		for _, msg := range v.insert.typed {
+			*ed, _ = ed.Update(msg)
+		}
+	}
+	v.insert = insertSession{}
+}
+
+// enterInsert switches to insert mode; the text typed before Esc is
+// inserted count times
+func (v *Vim) enterInsert(count int) {
+	v.Mode = VimInsert
+	v.insert = insertSession{count: count}
+}
+
+// Command parsing
+
+type parseStatus int
+
+const (
+	parseDone parseStatus = iota
+	parseIncomplete
+	parseInvalid
+)
+
+// vimCommand is a parsed normal or visual mode command:
+// ["x][count](operator[count](motion|text object|operator)|motion|command)
+type vimCommand struct {
+	register rune
+	count    int // 0 when no count was typed
+	op       string
+	doubled  bool // dd, cc, yy
+	name     string
+	char     rune // argument of f, t, F, T and r
+}
+
+// n returns the count, defaulting to 1
+func (c vimCommand) n() int {
+	if c.count == 0 {
+		return 1
+	}
+	return c.count
+}
+
+var (
+	vimOperators = map[string]bool{"d": true, "c": true, "y": true}
+
+	vimMotions = map[string]bool{
+		"h": true, "j": true, "k": true, "l": true,
+		"left": true, "right": true, "up": true, "down": true,
+		"backspace": true, " ": true, "enter": true, "+": true, "-": true, "_": true,
+		"w": true, "W": true, "b": true, "B": true, "e": true, "E": true,
+		"0": true, "^": true, "$": true, "home": true, "end": true,
+		"G": true, "{": true, "}": true, ";": true, ",": true, "n": true, "N": true,
+		"ctrl+d": true, "ctrl+u": true, "ctrl+f": true, "ctrl+b": true,
+		"pgup": true, "pgdown": true,
+	}
+
+	vimGMotions = map[string]bool{"g": true, "e": true, "E": true, "j": true, "k": true}
+
+	vimCharMotions = map[string]bool{"f": true, "F": true, "t": true, "T": true}
+
+	vimTextObjects = map[string]bool{
+		"w": true, "W": true, "p": true,
+		"\"": true, "'": true, "`": true,
+		"(": true, ")": true, "b": true,
+		"[": true, "]": true,
+		"{": true, "}": true, "B": true,
+		"<": true, ">": true,
+	}
+
+	vimNormalCommands = map[string]bool{
+		"x": true, "X": true, "s": true, "S": true, "D": true, "C": true, "Y": true,
+		"p": true, "P": true, "i": true, "a": true, "I": true, "A": true, "o": true, "O": true,
+		"J": true, "~": true, ".": true, "v": true, "V": true, ":": true, "/": true, "?": true,
+		"u": true, "ctrl+r": true, "esc": true, "insert": true, "delete": true,
+	}
+
+	vimVisualCommands = map[string]bool{
+		"d": true, "x": true, "X": true, "D": true, "delete": true,
+		"c": true, "s": true, "C": true, "S": true, "R": true,
+		"y": true, "Y": true, "p": true, "P": true,
+		"~": true, "u": true, "U": true, "J": true, "o": true, "O": true,
+		"v": true, "V": true, "esc": true, ":": true,
+	}
+)
+
+// parseCount reads a count starting at tokens[*i]
+func parseCount(tokens []string, i *int) int {
+	count := 0
+	for *i < len(tokens) {
+		t := tokens[*i]
+		if len(t) != 1 || t[0] < '0' || t[0] > '9' || (t == "0" && count == 0) {
+			break
+		}
+		count = count*10 + int(t[0]-'0')
+		*i++
+	}
+	return count
+}
+
+// singleRune returns the rune of a one-character token
+func singleRune(t string) (rune, bool) {
+	r, size := utf8.DecodeRuneInString(t)
+	if r == utf8.RuneError || size != len(t) {
+		return 0, false
+	}
+	return r, true
+}
+
+func validRegister(r rune) bool {
+	return unicode.IsLetter(r) && r < unicode.MaxASCII || unicode.IsDigit(r) ||
+		strings.ContainsRune("\"-_+*", r)
+}
+
+// parseTarget reads a motion, or with textObjects set a text object,
+// starting at tokens[*i]
+func parseTarget(tokens []string, i *int, textObjects bool) (name string, char rune, status parseStatus) {
+	t := tokens[*i]
+	*i++
+
+	switch {
+	case vimMotions[t]:
+		return t, 0, parseDone
+	case t == "g":
+		if *i >= len(tokens) {
+			return "", 0, parseIncomplete
+		}
+		next := tokens[*i]
+		*i++
+		if vimGMotions[next] {
+			return "g" + next, 0, parseDone
+		}
+		return "", 0, parseInvalid
+	case vimCharMotions[t]:
+		if *i >= len(tokens) {
+			return "", 0, parseIncomplete
+		}
+		r, ok := singleRune(tokens[*i])
+		*i++
+		if !ok {
+			return "", 0, parseInvalid
+		}
+		return t, r, parseDone
+	case textObjects && (t == "i" || t == "a"):
+		if *i >= len(tokens) {
+			return "", 0, parseIncomplete
+		}
+		next := tokens[*i]
+		*i++
+		if vimTextObjects[next] {
+			return t + next, 0, parseDone
+		}
+		return "", 0, parseInvalid
+	}
+
+	*i--
+	return "", 0, parseInvalid
+}
+
+// parseVimCommand parses the keys typed so far
+func parseVimCommand(keys []tea.KeyMsg, visual bool) (vimCommand, parseStatus) {
+	tokens := make([]string, len(keys))
+	for i, key := range keys {
+		tokens[i] = keyToken(key)
+	}
+
+	var cmd vimCommand
+	i := 0
+
+	if tokens[0] == "\"" {
+		if len(tokens) < 2 {
+			return cmd, parseIncomplete
+		}
+		r, ok := singleRune(tokens[1])
+		if !ok || !validRegister(r) {
+			return cmd, parseInvalid
+		}
+		cmd.register = r
+		i = 2
+	}
+
+	cmd.count = parseCount(tokens, &i)
+	if i >= len(tokens) {
+		return cmd, parseIncomplete
+	}
+	t := tokens[i]
+
+	// Operator pending
+	if vimOperators[t] && !visual {
+		cmd.op = t
+		i++
+		if count := parseCount(tokens, &i); count > 0 {
+			cmd.count = cmd.n() * count
+		}
+		if i >= len(tokens) {
+			return cmd, parseIncomplete
+		}
+		if tokens[i] == t {
+			cmd.doubled = true
+			return cmd, parseDone
+		}
+		name, char, status := parseTarget(tokens, &i, true)
+		cmd.name, cmd.char = name, char
+		return cmd, status
+	}
+
+	// Commands taking a character or a second key
+	switch t {
+	case "r":
+		if i+1 >= len(tokens) {
+			return cmd, parseIncomplete
+		}
+		r, ok := singleRune(tokens[i+1])
+		if !ok {
+			return cmd, parseInvalid
+		}
+		cmd.name, cmd.char = "r", r
+		return cmd, parseDone
+	case "Z":
+		if visual {
+			return cmd, parseInvalid
+		}
+		if i+1 >= len(tokens) {
+			return cmd, parseIncomplete
+		}
+		if next := tokens[i+1]; next == "Z" || next == "Q" {
+			cmd.name = "Z" + next
+			return cmd, parseDone
+		}
+		return cmd, parseInvalid
+	}
+
+	if visual && vimVisualCommands[t] {
+		cmd.name = t
+		return cmd, parseDone
+	}
+	if !visual && vimNormalCommands[t] {
+		cmd.name = t
+		return cmd, parseDone
+	}
+
+	name, char, status := parseTarget(tokens, &i, visual)
+	cmd.name, cmd.char = name, char
+	return cmd, status
+}
+
+// isChange reports whether a normal mode command changes the text, so it
+// can be repeated with .
+func isChange(cmd vimCommand) bool {
+	if cmd.op != "" {
+		return cmd.op != "y"
+	}
+	switch cmd.name {
+	case "x", "X", "s", "S", "D", "C", "p", "P", "r", "J", "~",
+		"i", "a", "I", "A", "o", "O", "insert", "delete":
+		return true
+	}
+	return false
+}
+
+// Registers
+
+// registerKey maps register aliases onto the register they read from
+func registerKey(name rune) rune {
+	switch name {
+	case 0, '+', '*':
+		return '"'
+	}
+	return unicode.ToLower(name)
+}
+
+// store records yanked or deleted text the way vim does: named registers
+// when given (uppercase appends), "0 for yanks, "1-"9 for deletes of whole
+// lines, "- for small deletes, and always the unnamed register
+func (v *Vim) store(name rune, text string, lines bool, yank bool) {
+	if name == '_' {
+		return
+	}
+	r := Register{Text: text, Linewise: lines}
+
+	if name >= 'A' && name <= 'Z' {
+		prev := v.registers[unicode.ToLower(name)]
+		switch {
+		case prev.Text == "":
+		case prev.Linewise || lines:
+			r = Register{Text: prev.Text + "\n" + text, Linewise: true}
+		default:
+			r = Register{Text: prev.Text + text}
+		}
+		name = unicode.ToLower(name)
+	}
+
+	v.registers['"'] = r
+	switch {
+	case name >= 'a' && name <= 'z':
+		v.registers[name] = r
+	case yank:
+		v.registers['0'] = r
+	case lines || strings.Contains(text, "\n"):
+		for i := '9'; i > '1'; i-- {
+			if prev, ok := v.registers[i-1]; ok {
+				v.registers[i] = prev
+			}
+		}
+		v.registers['1'] = r
+	default:
+		v.registers['-'] = r
+	}
+}
+
+// Operators
+
+// lineText returns rows first through last joined by line breaks
+func (m *Model) lineText(first, last int) string {
+	parts := make([]string, 0, last-first+1)
+	for row := first; row <= last; row++ {
+		parts = append(parts, string(m.lines[row]))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// indentOf returns the leading whitespace of row
+func (m *Model) indentOf(row int) string {
+	line := m.lines[row]
+	n := 0
+	for n < len(line) && (line[n] == ' ' || line[n] == '\t') {
+		n++
+	}
+	return string(line[:n])
+}
+
+// operateLines applies op to rows first through last
+func (v *Vim) operateLines(ed *Model, op string, reg rune, first, last int) {
+	if last >= len(ed.lines) {
+		last = len(ed.lines) - 1
+	}
+	text := ed.lineText(first, last)
+
+	switch op {
+	case "y":
+		v.store(reg, text, true, true)
+		ed.SetCursor(first, ed.col)
+	case "d":
+		v.store(reg, text, true, false)
+		ed.ReplaceLines(first, last, nil)
+		row := first
+		if row >= len(ed.lines) {
+			row = len(ed.lines) - 1
+		}
+		ed.SetCursor(row, ed.firstNonBlank(row))
+	case "c":
+		v.store(reg, text, true, false)
+		indent := ed.indentOf(first)
+		ed.ReplaceLines(first, last, []string{indent})
+		ed.SetCursor(first, len([]rune(indent)))
+		v.enterInsert(1)
+	}
+}
+
+// operateRange applies op to the text from from up to to
+func (v *Vim) operateRange(ed *Model, op string, reg rune, from, to Pos) {
+	from, to = orderPos(from, to)
+	text := ed.Slice(from, to)
+
+	switch op {
+	case "y":
+		v.store(reg, text, false, true)
+		ed.SetCursor(from.Row, from.Col)
+	case "d":
+		v.store(reg, text, false, false)
+		ed.DeleteRange(from, to)
+	case "c":
+		v.store(reg, text, false, false)
+		ed.DeleteRange(from, to)
+		v.enterInsert(1)
+	}
+}
+
+// operatorMotion applies cmd.op over the motion or text object in cmd
+func (v *Vim) operatorMotion(ed *Model, cmd vimCommand) {
+	p := ed.CursorPos()
+
+	if cmd.doubled {
+		v.operateLines(ed, cmd.op, cmd.register, p.Row, p.Row+cmd.n()-1)
+		return
+	}
+
+	if len(cmd.name) == 2 && (cmd.name[0] == 'i' || cmd.name[0] == 'a') {
+		from, to, lines, ok := v.textObject(ed, cmd.name, cmd.n())
+		if !ok {
+			return
+		}
+		if lines {
+			v.operateLines(ed, cmd.op, cmd.register, from.Row, to.Row)
+		} else {
+			v.operateRange(ed, cmd.op, cmd.register, from, to)
+		}
+		return
+	}
+
+	target, kind, ok := v.motion(ed, cmd, true)
+	if !ok {
+		return
+	}
+
+	switch kind {
+	case linewise:
+		first, last := orderPos(p, target)
+		v.operateLines(ed, cmd.op, cmd.register, first.Row, last.Row)
+	case inclusive:
+		from, to := orderPos(p, target)
+		if to.Col < len(ed.lines[to.Row]) {
+			to.Col++
+		}
+		v.operateRange(ed, cmd.op, cmd.register, from, to)
+	default:
+		from, to := orderPos(p, target)
+		// An exclusive motion ending at the start of a later line stops at
+		// the end of the line before, so dw on a line's last word keeps the
+		// line break
+		if to.Col == 0 && to.Row > from.Row {
+			to = Pos{Row: to.Row - 1, Col: len(ed.lines[to.Row-1])}
+		}
+		v.operateRange(ed, cmd.op, cmd.register, from, to)
+	}
+}
+
+// Normal mode
+
+func (v *Vim) executeNormal(ed *Model, cmd vimCommand) VimAction {
+	if cmd.op != "" {
+		v.operatorMotion(ed, cmd)
+		v.want = ed.col
+		return VimNone
+	}
+
+	p := ed.CursorPos()
+	n := cmd.n()
+
+	// Shorthands for operator commands
+	shorthand := map[string]vimCommand{
+		"x":      {op: "d", name: "l"},
+		"delete": {op: "d", name: "l"},
+		"X":      {op: "d", name: "h"},
+		"s":      {op: "c", name: "l"},
+		"S":      {op: "c", doubled: true},
+		"D":      {op: "d", name: "$"},
+		"C":      {op: "c", name: "$"},
+		"Y":      {op: "y", doubled: true},
+	}
+	if short, ok := shorthand[cmd.name]; ok {
+		short.register, short.count = cmd.register, cmd.count
+		if len(ed.lines[p.Row]) == 0 && (cmd.name == "x" || cmd.name == "delete" || cmd.name == "s") {
+			if cmd.name == "s" {
+				v.enterInsert(1)
+			}
+			return VimNone
+		}
+		v.operatorMotion(ed, short)
+		v.want = ed.col
+		return VimNone
+	}
+
+	switch cmd.name {
+	case "esc":
+		return VimNone
+
+	case "i", "insert":
+		v.enterInsert(n)
+	case "a":
+		if len(ed.lines[p.Row]) > 0 {
+			ed.SetCursor(p.Row, p.Col+1)
+		}
+		v.enterInsert(n)
+	case "I":
+		ed.SetCursor(p.Row, ed.firstNonBlank(p.Row))
+		v.enterInsert(n)
+	case "A":
+		ed.SetCursor(p.Row, len(ed.lines[p.Row]))
+		v.enterInsert(n)
+	case "o", "O":
+		indent := ed.indentOf(p.Row)
+		row := p.Row + 1
+		if cmd.name == "O" {
+			row = p.Row
+		}
+		ed.InsertLines(row, []string{indent})
+		ed.SetCursor(row, len([]rune(indent)))
+		v.enterInsert(n)
+		v.insert.opens, v.insert.indent = true, indent
+
+	case "p", "P":
+		v.put(ed, cmd.register, n, cmd.name == "P")
+
+	case "r":
+		line := ed.lines[p.Row]
+		if p.Col+n > len(line) {
+			return VimNone
+		}
+		for i := 0; i < n; i++ {
+			line[p.Col+i] = cmd.char
+		}
+		ed.SetCursor(p.Row, p.Col+n-1)
+
+	case "J":
+		joins := n - 1
+		if joins < 1 {
+			joins = 1
+		}
+		for i := 0; i < joins && p.Row < len(ed.lines)-1; i++ {
+			ed.joinLines(p.Row)
+		}
+
+	case "~":
+		line := ed.lines[p.Row]
+		end := p.Col + n
+		if end > len(line) {
+			end = len(line)
+		}
+		for i := p.Col; i < end; i++ {
+			line[i] = toggleCase(line[i])
+		}
+		ed.SetCursor(p.Row, end)
+
+	case ".":
+		v.repeat(ed, cmd.count)
+
+	case "u":
+		for i := 0; i < n; i++ {
+			if !ed.Undo() {
+				v.Message = "Already at oldest change"
+				break
+			}
+		}
+	case "ctrl+r":
+		for i := 0; i < n; i++ {
+			if !ed.Redo() {
+				v.Message = "Already at newest change"
+				break
+			}
+		}
+
+	case "v":
+		v.Mode = VimVisual
+		v.visualStart = p
+	case "V":
+		v.Mode = VimVisualLine
+		v.visualStart = p
+
+	case ":":
+		v.Mode = VimCommandLine
+		v.Prompt = ":"
+		v.CommandLine = ""
+	case "/", "?":
+		v.Mode = VimCommandLine
+		v.Prompt = cmd.name
+		v.CommandLine = ""
+
+	case "ZZ":
+		return VimWriteQuit
+	case "ZQ":
+		return VimForceQuit
+
+	default:
+		// A plain motion
+		target, kind, ok := v.motion(ed, cmd, false)
+		if !ok {
+			return VimNone
+		}
+		if target.Col > ed.lastCol(target.Row) {
+			target.Col = ed.lastCol(target.Row)
+		}
+		ed.SetCursor(target.Row, target.Col)
+		switch {
+		case cmd.name == "$" || cmd.name == "end":
+			v.want = -1
+		case kind != linewise || cmd.name == "G" || cmd.name == "gg" ||
+			cmd.name == "+" || cmd.name == "-" || cmd.name == "_" || cmd.name == "enter":
+			v.want = ed.col
+		}
+		return VimNone
+	}
+
+	v.want = ed.col
+	return VimNone
+}
+
+// put pastes a register after (or before) the cursor
+func (v *Vim) put(ed *Model, name rune, count int, before bool) {
+	r, ok := v.registers[registerKey(name)]
+	if !ok || r.Text == "" && !r.Linewise {
+		reg := name
+		if reg == 0 {
+			reg = '"'
+		}
+		v.Message = fmt.Sprintf("E353: Nothing in register %c", reg)
+		return
+	}
+
+	p := ed.CursorPos()
+	if r.Linewise {
+		var lines []string
+		for i := 0; i < count; i++ {
+			lines = append(lines, strings.Split(r.Text, "\n")...)
+		}
+		row := p.Row + 1
+		if before {
+			row = p.Row
+		}
+		ed.InsertLines(row, lines)
+		ed.SetCursor(row, ed.firstNonBlank(row))
+		return
+	}
+
+	if !before && len(ed.lines[p.Row]) > 0 {
+		p.Col++
+	}
+	ed.SetCursor(p.Row, p.Col)
+	ed.InsertString(strings.Repeat(r.Text, count))
+	if ed.col > 0 {
+		ed.col--
+	}
+}
+
+// joinLines joins row with the line after it like J: leading blanks of the
+// next line are replaced by a single space
+func (m *Model) joinLines(row int) {
+	if row >= len(m.lines)-1 {
+		return
+	}
+	current := []rune(strings.TrimRight(string(m.lines[row]), " \t"))
+	next := []rune(strings.TrimLeft(string(m.lines[row+1]), " \t"))
+
+	col := len(current)
+	joined := append([]rune{}, current...)
+	if len(current) > 0 && len(next) > 0 && next[0] != ')' {
+		joined = append(joined, ' ')
+	}
+	joined = append(joined, next...)
+
+	m.lines[row] = joined
+	m.lines = append(m.lines[:row+1], m.lines[row+2:]...)
+	m.SetCursor(row, col)
+}
+
+func toggleCase(r rune) rune {
+	if unicode.IsUpper(r) {
+		return unicode.ToLower(r)
+	}
+	return unicode.ToUpper(r)
+}
+
+// repeat replays the last change; a count replaces the one it was typed
+// with
+func (v *Vim) repeat(ed *Model, count int) {
+	if len(v.lastChange) == 0 {
+		return
+	}
+
+	keys := v.lastChange
+	if count > 0 {
+		// Drop the original count after an optional register
+		i := 0
+		if keyToken(keys[0]) == "\"" && len(keys) > 1 {
+			i = 2
+		}
+		j := i
+		for j < len(keys) {
+			t := keyToken(keys[j])
+			if len(t) != 1 || t[0] < '0' || t[0] > '9' || (t == "0" && j == i) {
+				break
+			}
+			j++
+		}
+		rewritten := append([]tea.KeyMsg{}, keys[:i]...)
+		for _, r := range strconv.Itoa(count) {
+			rewritten = append(rewritten, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		keys = append(rewritten, keys[j:]...)
+	}
+
+	v.replaying = true
+	for _, key := range keys {
+		v.HandleKey(ed, key)
+	}
+	if v.Mode == VimInsert {
+		v.HandleKey(ed, tea.KeyMsg{Type: tea.KeyEsc})
+	}
+	v.replaying = false
+}
+
+// Visual mode
+
+// visualRange returns the selection as a range, to exclusive
+func (v *Vim) visualRange(ed *Model) (Pos, Pos) {
+	from, to := orderPos(v.visualStart, ed.CursorPos())
+	if to.Col < len(ed.lines[to.Row]) {
+		to.Col++
+	} else if to.Row < len(ed.lines)-1 {
+		to = Pos{Row: to.Row + 1}
+	}
+	return from, to
+}
+
+// exitVisual leaves visual mode, remembering the selected lines for '<,'>
+func (v *Vim) exitVisual(ed *Model) {
+	from, to := orderPos(v.visualStart, ed.CursorPos())
+	v.lastVisual = [2]int{from.Row, to.Row}
+	v.Mode = VimNormal
+	ed.ClearSelection()
+}
+
+func (v *Vim) executeVisual(ed *Model, cmd vimCommand) VimAction {
+	from, to := orderPos(v.visualStart, ed.CursorPos())
+	lines := v.Mode == VimVisualLine
+
+	op := ""
+	switch cmd.name {
+	case "d", "x", "delete":
+		op = "d"
+	case "c", "s":
+		op = "c"
+	case "y":
+		op = "y"
+	case "D", "X":
+		op, lines = "d", true
+	case "C", "S", "R":
+		op, lines = "c", true
+	case "Y":
+		op, lines = "y", true
+	}
+
+	if op != "" {
+		v.exitVisual(ed)
+		if lines {
+			v.operateLines(ed, op, cmd.register, from.Row, to.Row)
+		} else {
+			v.visualStart = from
+			ed.SetCursor(to.Row, to.Col)
+			rangeFrom, rangeTo := v.visualRange(ed)
+			v.operateRange(ed, op, cmd.register, rangeFrom, rangeTo)
+		}
+		if v.Mode == VimNormal {
+			v.clampNormal(ed)
+		}
+		v.want = ed.col
+		return VimNone
+	}
+
+	switch cmd.name {
+	case "esc":
+		v.exitVisual(ed)
+	case "v":
+		if v.Mode == VimVisual {
+			v.exitVisual(ed)
+		} else {
+			v.Mode = VimVisual
+		}
+	case "V":
+		if v.Mode == VimVisualLine {
+			v.exitVisual(ed)
+		} else {
+			v.Mode = VimVisualLine
+		}
+	case "o", "O":
+		cursor := ed.CursorPos()
+		ed.SetCursor(v.visualStart.Row, v.visualStart.Col)
+		v.visualStart = cursor
+
+	case "p", "P":
+		r, ok := v.registers[registerKey(cmd.register)]
+		if !ok {
+			v.exitVisual(ed)
+			return VimNone
+		}
+		v.exitVisual(ed)
+		if lines {
+			v.operateLines(ed, "d", 0, from.Row, to.Row)
+			v.registers['"'] = r
+			v.put(ed, cmd.register, 1, from.Row < len(ed.lines)-1 || from.Row == 0 && len(ed.lines) == 1)
+		} else {
+			v.visualStart = from
+			ed.SetCursor(to.Row, to.Col)
+			rangeFrom, rangeTo := v.visualRange(ed)
+			v.operateRange(ed, "d", 0, rangeFrom, rangeTo)
+			v.registers['"'] = r
+			v.put(ed, cmd.register, 1, true)
+		}
+
+	case "r", "~", "u", "U":
+		for row := from.Row; row <= to.Row; row++ {
+			line := ed.lines[row]
+			start, end := 0, len(line)
+			if !lines {
+				if row == from.Row {
+					start = from.Col
+				}
+				if row == to.Row && to.Col+1 < end {
+					end = to.Col + 1
+				}
+			}
+			for i := start; i < end; i++ {
+				switch cmd.name {
+				case "r":
+					line[i] = cmd.char
+				case "~":
+					line[i] = toggleCase(line[i])
+				case "u":
+					line[i] = unicode.ToLower(line[i])
+				case "U":
+					line[i] = unicode.ToUpper(line[i])
+				}
+			}
+		}
+		v.exitVisual(ed)
+		ed.SetCursor(from.Row, from.Col)
+
+	case "J":
+		joins := to.Row - from.Row
+		if joins < 1 {
+			joins = 1
+		}
+		v.exitVisual(ed)
+		for i := 0; i < joins; i++ {
+			ed.joinLines(from.Row)
+		}
+
+	case ":":
+		v.exitVisual(ed)
+		v.Mode = VimCommandLine
+		v.Prompt = ":"
+		v.CommandLine = "'<,'>"
+
+	default:
+		if len(cmd.name) == 2 && (cmd.name[0] == 'i' || cmd.name[0] == 'a') {
+			start, end, objLines, ok := v.textObject(ed, cmd.name, cmd.n())
+			if !ok {
+				return VimNone
+			}
+			if objLines {
+				v.Mode = VimVisualLine
+				v.visualStart = start
+				ed.SetCursor(end.Row, 0)
+			} else {
+				v.visualStart = start
+				last, _ := ed.prevPos(end)
+				if last.Before(start) {
+					last = start
+				}
+				ed.SetCursor(last.Row, last.Col)
+			}
+			return VimNone
+		}
+
+		target, kind, ok := v.motion(ed, cmd, false)
+		if !ok {
+			return VimNone
+		}
+		if target.Col > ed.lastCol(target.Row) {
+			target.Col = ed.lastCol(target.Row)
+		}
+		ed.SetCursor(target.Row, target.Col)
+		if cmd.name == "$" || cmd.name == "end" {
+			v.want = -1
+		} else if kind != linewise {
+			v.want = ed.col
+		}
+		return VimNone
+	}
+
+	if v.Mode == VimNormal {
+		v.clampNormal(ed)
+	}
+	v.want = ed.col
+	return VimNone
+}
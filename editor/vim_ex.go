@@ -0,0 +1,437 @@
+package editor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Command line
+
+func (v *Vim) handleCommandLine(ed *Model, msg tea.KeyMsg) VimAction {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		v.Mode = VimNormal
+		v.CommandLine = ""
+		return VimNone
+	case "backspace":
+		if v.CommandLine == "" {
+			v.Mode = VimNormal
+			return VimNone
+		}
+		runes := []rune(v.CommandLine)
+		v.CommandLine = string(runes[:len(runes)-1])
+		return VimNone
+	case "enter":
+		line := v.CommandLine
+		v.Mode = VimNormal
+		v.CommandLine = ""
+		v.Message = ""
+
+		var action VimAction
+		if v.Prompt == ":" {
+			action = v.runEx(ed, line)
+		} else {
+			v.search(ed, line, v.Prompt == "/")
+		}
+		v.clampNormal(ed)
+		v.want = ed.col
+		return action
+	}
+
+	if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+		v.CommandLine += string(msg.Runes)
+	}
+	return VimNone
+}
+
+// vimPattern translates the parts of vim's pattern syntax people reach for
+// most (\( \) groups, \| alternation and \< \> word boundaries) into Go
+// regular expression syntax, which patterns otherwise use
+var vimPattern = strings.NewReplacer(`\(`, `(`, `\)`, `)`, `\|`, `|`, `\<`, `\b`, `\>`, `\b`)
+
+// compileSearch compiles a search pattern; all-lowercase patterns ignore
+// case
+func compileSearch(pattern string) (*regexp.Regexp, error) {
+	expr := vimPattern.Replace(pattern)
+	if strings.ToLower(pattern) == pattern {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// search runs a / or ? search from the command line
+func (v *Vim) search(ed *Model, pattern string, forward bool) {
+	if pattern == "" {
+		pattern = v.lastSearch
+	}
+	if pattern == "" {
+		v.Message = "E35: No previous regular expression"
+		return
+	}
+	v.lastSearch = pattern
+	v.lastSearchForward = forward
+
+	if target, ok := v.searchFrom(ed, ed.CursorPos(), pattern, forward, 1); ok {
+		ed.SetCursor(target.Row, target.Col)
+	}
+}
+
+// searchFrom finds the count-th match of pattern after (or before) p,
+// wrapping around the text
+func (v *Vim) searchFrom(ed *Model, p Pos, pattern string, forward bool, count int) (Pos, bool) {
+	re, err := compileSearch(pattern)
+	if err != nil {
+		v.Message = "E486: Invalid pattern: " + pattern
+		return p, false
+	}
+
+	// Every match start, in text order
+	var matches []Pos
+	for row, line := range ed.lines {
+		text := string(line)
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			matches = append(matches, Pos{Row: row, Col: utf8.RuneCountInString(text[:loc[0]])})
+		}
+	}
+	if len(matches) == 0 {
+		v.Message = "E486: Pattern not found: " + pattern
+		return p, false
+	}
+
+	cur := p
+	for ; count > 0; count-- {
+		found := -1
+		if forward {
+			for i, match := range matches {
+				if cur.Before(match) {
+					found = i
+					break
+				}
+			}
+			if found < 0 {
+				found = 0
+				v.Message = "search hit BOTTOM, continuing at TOP"
+			}
+		} else {
+			for i := len(matches) - 1; i >= 0; i-- {
+				if matches[i].Before(cur) {
+					found = i
+					break
+				}
+			}
+			if found < 0 {
+				found = len(matches) - 1
+				v.Message = "search hit TOP, continuing at BOTTOM"
+			}
+		}
+		cur = matches[found]
+	}
+	return cur, true
+}
+
+// exRange matches an optional ex line range: %, or one or two addresses
+var exRange = regexp.MustCompile(`^(%|([.$]|\d+|'[<>])(,([.$]|\d+|'[<>]))?)?`)
+
+// runEx runs an ex command line
+func (v *Vim) runEx(ed *Model, line string) VimAction {
+	line = strings.TrimSpace(strings.TrimLeft(line, ":"))
+
+	cur := ed.row
+	first, last := cur, cur
+	hasRange := false
+
+	address := func(a string) int {
+		switch a {
+		case ".":
+			return cur
+		case "$":
+			return len(ed.lines) - 1
+		case "'<":
+			return v.lastVisual[0]
+		case "'>":
+			return v.lastVisual[1]
+		}
+		n, _ := strconv.Atoi(a)
+		return n - 1
+	}
+
+	if m := exRange.FindStringSubmatch(line); m[0] != "" {
+		hasRange = true
+		if m[1] == "%" {
+			first, last = 0, len(ed.lines)-1
+		} else {
+			first = address(m[2])
+			last = first
+			if m[4] != "" {
+				last = address(m[4])
+			}
+		}
+		line = strings.TrimSpace(line[len(m[0]):])
+	}
+
+	if first < 0 || last < 0 {
+		v.Message = "E20: Mark not set"
+		return VimNone
+	}
+	if first > last {
+		first, last = last, first
+	}
+	if last >= len(ed.lines) {
+		last = len(ed.lines) - 1
+	}
+	if first >= len(ed.lines) {
+		first = len(ed.lines) - 1
+	}
+
+	// ":42" jumps to a line
+	if line == "" {
+		if hasRange {
+			ed.SetCursor(last, ed.firstNonBlank(last))
+		}
+		return VimNone
+	}
+
+	name, args := line, ""
+	for i, r := range line {
+		if !unicode.IsLetter(r) {
+			name, args = line[:i], line[i:]
+			break
+		}
+	}
+	// :s/a/b/ and :s#a#b# take their delimiter straight after the name
+	if strings.HasPrefix("substitute", name) && name != "" && args != "" && !strings.HasPrefix(args, "!") &&
+		!unicode.IsSpace([]rune(args)[0]) {
+		return v.substitute(ed, first, last, args)
+	}
+
+	bang := strings.HasPrefix(args, "!")
+	args = strings.TrimSpace(strings.TrimPrefix(args, "!"))
+
+	switch name {
+	case "w", "write":
+		return VimWrite
+	case "q", "quit", "clo", "close":
+		if bang {
+			return VimForceQuit
+		}
+		return VimQuit
+	case "wq", "x", "xit", "exi", "exit":
+		return VimWriteQuit
+	case "d", "de", "del", "delete":
+		v.operateLines(ed, "d", registerArg(args), first, last)
+	case "y", "ya", "yank":
+		v.store(registerArg(args), ed.lineText(first, last), true, true)
+	case "noh", "nohl", "nohlsearch":
+	case "reg", "registers", "di", "display":
+		v.Message = v.describeRegisters()
+	case "set", "se":
+		return v.set(ed, args)
+	default:
+		v.Message = "E492: Not an editor command: " + line
+	}
+	return VimNone
+}
+
+// registerArg reads the register name given to :d or :y
+func registerArg(args string) rune {
+	if r, ok := singleRune(strings.TrimSpace(args)); ok && validRegister(r) {
+		return r
+	}
+	return 0
+}
+
+// describeRegisters summarises the non-empty registers on one line
+func (v *Vim) describeRegisters() string {
+	var parts []string
+	for _, name := range "\"0123456789abcdefghijklmnopqrstuvwxyz-" {
+		r, ok := v.registers[name]
+		if !ok || r.Text == "" && !r.Linewise {
+			continue
+		}
+		text := strings.ReplaceAll(r.Text, "\n", "^J")
+		if r.Linewise {
+			text += "^J"
+		}
+		if len([]rune(text)) > 20 {
+			text = string([]rune(text)[:20]) + "…"
+		}
+		parts = append(parts, fmt.Sprintf("\"%c %s", name, text))
+	}
+	if len(parts) == 0 {
+		return "All registers are empty"
+	}
+	return strings.Join(parts, "  ")
+}
+
+// set handles :set number, :set nonumber and :set novim
+func (v *Vim) set(ed *Model, args string) VimAction {
+	switch args {
+	case "nu", "number":
+		ed.ShowLineNumbers = true
+	case "nonu", "nonumber":
+		ed.ShowLineNumbers = false
+	case "invnu", "invnumber", "nu!", "number!":
+		ed.ShowLineNumbers = !ed.ShowLineNumbers
+	case "novim":
+		return VimDisable
+	default:
+		v.Message = "E518: Unknown option: " + args
+	}
+	return VimNone
+}
+
+// splitSubstitute splits "/pattern/replacement/flags" on its delimiter,
+// honouring backslash escapes of the delimiter
+func splitSubstitute(args string) (pattern, replacement, flags string, ok bool) {
+	delim, size := utf8.DecodeRuneInString(args)
+	rest := args[size:]
+
+	var parts []string
+	var current strings.Builder
+	escaped := false
+	for _, r := range rest {
+		switch {
+		case escaped:
+			if r != delim {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == delim && len(parts) < 2:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	parts = append(parts, current.String())
+
+	switch len(parts) {
+	case 1:
+		return parts[0], "", "", true
+	case 2:
+		return parts[0], parts[1], "", true
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// vimReplacement turns a vim replacement string into a regexp template:
+// & and \0 are the whole match, \1-\9 groups, \n a line break
+func vimReplacement(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			next := runes[i]
+			switch {
+			case next >= '0' && next <= '9':
+				b.WriteString("${" + string(next) + "}")
+			case next == 'n' || next == 'r':
+				b.WriteByte('\n')
+			case next == 't':
+				b.WriteByte('\t')
+			case next == '$':
+				b.WriteString("$$")
+			default:
+				b.WriteRune(next)
+			}
+		case r == '&':
+			b.WriteString("${0}")
+		case r == '$':
+			b.WriteString("$$")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// substitute runs :[range]s/pattern/replacement/[flags]
+func (v *Vim) substitute(ed *Model, first, last int, args string) VimAction {
+	pattern, replacement, flags, _ := splitSubstitute(args)
+	if pattern == "" {
+		pattern = v.lastSearch
+	}
+	if pattern == "" {
+		v.Message = "E35: No previous regular expression"
+		return VimNone
+	}
+
+	expr := vimPattern.Replace(pattern)
+	global := false
+	for _, f := range flags {
+		switch f {
+		case 'g':
+			global = true
+		case 'i':
+			expr = "(?i)" + expr
+		case 'I', ' ':
+		default:
+			v.Message = fmt.Sprintf("E488: Trailing characters: %c", f)
+			return VimNone
+		}
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		v.Message = "E486: Invalid pattern: " + pattern
+		return VimNone
+	}
+	v.lastSearch = pattern
+	template := vimReplacement(replacement)
+
+	var out []string
+	substitutions, changedLines, lastChanged := 0, 0, -1
+	for row := first; row <= last; row++ {
+		text := string(ed.lines[row])
+		matches := re.FindAllStringSubmatchIndex(text, -1)
+		if !global && len(matches) > 1 {
+			matches = matches[:1]
+		}
+		if len(matches) == 0 {
+			out = append(out, text)
+			continue
+		}
+
+		var b []byte
+		prev := 0
+		for _, loc := range matches {
+			b = append(b, text[prev:loc[0]]...)
+			b = re.ExpandString(b, template, text, loc)
+			prev = loc[1]
+		}
+		b = append(b, text[prev:]...)
+
+		substitutions += len(matches)
+		changedLines++
+		newLines := strings.Split(string(b), "\n")
+		out = append(out, newLines...)
+		lastChanged = first + len(out) - 1
+	}
+
+	if substitutions == 0 {
+		v.Message = "E486: Pattern not found: " + pattern
+		return VimNone
+	}
+
+	ed.ReplaceLines(first, last, out)
+	ed.SetCursor(lastChanged, ed.firstNonBlank(lastChanged))
+	if changedLines > 1 {
+		v.Message = fmt.Sprintf("%d substitutions on %d lines", substitutions, changedLines)
+	}
+	return VimNone
+}
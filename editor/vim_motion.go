@@ -0,0 +1,640 @@
+package editor
+
+import "unicode"
+
+// Motions
+
+type motionKind int
+
+const (
+	exclusive motionKind = iota
+	inclusive
+	linewise
+)
+
+// charClass groups characters for word motions: 0 for blanks, 1 for
+// punctuation and 2 for word characters. WORD motions treat every
+// non-blank alike.
+func charClass(r rune, bigWord bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case bigWord:
+		return 2
+	case isWordRune(r):
+		return 2
+	}
+	return 1
+}
+
+// classAt returns the class at p; the end of a line counts as a blank
+func (m *Model) classAt(p Pos, bigWord bool) int {
+	line := m.lines[p.Row]
+	if p.Col >= len(line) {
+		return 0
+	}
+	return charClass(line[p.Col], bigWord)
+}
+
+// nextPos steps forward one position, including the end of each line
+func (m *Model) nextPos(p Pos) (Pos, bool) {
+	switch {
+	case p.Col < len(m.lines[p.Row]):
+		p.Col++
+	case p.Row < len(m.lines)-1:
+		p.Row++
+		p.Col = 0
+	default:
+		return p, false
+	}
+	return p, true
+}
+
+// prevPos steps back one position, including the end of each line
+func (m *Model) prevPos(p Pos) (Pos, bool) {
+	switch {
+	case p.Col > 0:
+		p.Col--
+	case p.Row > 0:
+		p.Row--
+		p.Col = len(m.lines[p.Row])
+	default:
+		return p, false
+	}
+	return p, true
+}
+
+// emptyLineAt reports whether p is on an empty line, which word motions
+// treat as a word of its own
+func (m *Model) emptyLineAt(p Pos) bool {
+	return p.Col == 0 && len(m.lines[p.Row]) == 0
+}
+
+// firstNonBlank returns the column of the first non-blank on row
+func (m *Model) firstNonBlank(row int) int {
+	for col, r := range m.lines[row] {
+		if !unicode.IsSpace(r) {
+			return col
+		}
+	}
+	return 0
+}
+
+// lastCol returns the last column normal mode can be on in row
+func (m *Model) lastCol(row int) int {
+	if n := len(m.lines[row]); n > 0 {
+		return n - 1
+	}
+	return 0
+}
+
+func (m *Model) wordForward(p Pos, count int, bigWord bool) Pos {
+	for ; count > 0; count-- {
+		start := p
+		ok := true
+		if cls := m.classAt(p, bigWord); cls != 0 {
+			for ok && m.classAt(p, bigWord) == cls {
+				p, ok = m.nextPos(p)
+			}
+		}
+		for ok && m.classAt(p, bigWord) == 0 {
+			if p != start && m.emptyLineAt(p) {
+				break
+			}
+			p, ok = m.nextPos(p)
+		}
+		if !ok {
+			return p
+		}
+	}
+	return p
+}
+
+func (m *Model) wordBackward(p Pos, count int, bigWord bool) Pos {
+	for ; count > 0; count-- {
+		var ok bool
+		if p, ok = m.prevPos(p); !ok {
+			return p
+		}
+		for m.classAt(p, bigWord) == 0 && !m.emptyLineAt(p) {
+			if p, ok = m.prevPos(p); !ok {
+				return p
+			}
+		}
+		cls := m.classAt(p, bigWord)
+		if cls == 0 {
+			continue
+		}
+		for {
+			q, ok := m.prevPos(p)
+			if !ok || m.classAt(q, bigWord) != cls {
+				break
+			}
+			p = q
+		}
+	}
+	return p
+}
+
+// wordEnd moves to the end of a word. With stay set, the first step
+// doesn't leave the current word (cw changes to the end of the word under
+// the cursor, even when it is a single character).
+func (m *Model) wordEnd(p Pos, count int, bigWord, stay bool) Pos {
+	for ; count > 0; count-- {
+		var ok bool
+		if !stay {
+			if p, ok = m.nextPos(p); !ok {
+				return p
+			}
+		}
+		stay = false
+		for m.classAt(p, bigWord) == 0 {
+			if p, ok = m.nextPos(p); !ok {
+				return p
+			}
+		}
+		cls := m.classAt(p, bigWord)
+		for {
+			q, ok := m.nextPos(p)
+			if !ok || m.classAt(q, bigWord) != cls {
+				break
+			}
+			p = q
+		}
+	}
+	return p
+}
+
+func (m *Model) wordEndBackward(p Pos, count int, bigWord bool) Pos {
+	for ; count > 0; count-- {
+		start := p
+		ok := true
+		if cls := m.classAt(p, bigWord); cls != 0 {
+			for ok && m.classAt(p, bigWord) == cls {
+				p, ok = m.prevPos(p)
+			}
+		}
+		for ok && m.classAt(p, bigWord) == 0 && (p == start || !m.emptyLineAt(p)) {
+			p, ok = m.prevPos(p)
+		}
+		if !ok {
+			return p
+		}
+	}
+	return p
+}
+
+// findChar finds the count-th ch on the line for f, F, t and T
+func (m *Model) findChar(p Pos, kind, ch rune, count int, repeat bool) (Pos, bool) {
+	line := m.lines[p.Row]
+	col := p.Col
+	forward := kind == 'f' || kind == 't'
+
+	// Repeating t or T from just before the character must skip it
+	if repeat && (kind == 't' || kind == 'T') {
+		if forward {
+			col++
+		} else {
+			col--
+		}
+	}
+
+	for ; count > 0; count-- {
+		found := false
+		if forward {
+			for c := col + 1; c < len(line); c++ {
+				if line[c] == ch {
+					col, found = c, true
+					break
+				}
+			}
+		} else {
+			for c := col - 1; c >= 0; c-- {
+				if line[c] == ch {
+					col, found = c, true
+					break
+				}
+			}
+		}
+		if !found {
+			return p, false
+		}
+	}
+
+	switch kind {
+	case 't':
+		col--
+	case 'T':
+		col++
+	}
+	return Pos{Row: p.Row, Col: col}, true
+}
+
+// paragraphForward finds the count-th empty line after p
+func (m *Model) paragraphForward(p Pos, count int) Pos {
+	row := p.Row
+	for ; count > 0; count-- {
+		for row < len(m.lines)-1 && len(m.lines[row]) == 0 {
+			row++
+		}
+		for row < len(m.lines)-1 && len(m.lines[row]) > 0 {
+			row++
+		}
+	}
+	if row == len(m.lines)-1 && len(m.lines[row]) > 0 {
+		return Pos{Row: row, Col: len(m.lines[row])}
+	}
+	return Pos{Row: row}
+}
+
+// paragraphBackward finds the count-th empty line before p
+func (m *Model) paragraphBackward(p Pos, count int) Pos {
+	row := p.Row
+	for ; count > 0; count-- {
+		for row > 0 && len(m.lines[row]) == 0 {
+			row--
+		}
+		for row > 0 && len(m.lines[row]) > 0 {
+			row--
+		}
+	}
+	return Pos{Row: row}
+}
+
+// motion returns where a motion goes from the cursor. ok is false when the
+// motion can't move, e.g. h in the first column.
+func (v *Vim) motion(ed *Model, cmd vimCommand, forOperator bool) (target Pos, kind motionKind, ok bool) {
+	p := ed.CursorPos()
+	n := cmd.n()
+	last := len(ed.lines) - 1
+
+	vertical := func(rows int) (Pos, motionKind, bool) {
+		row := p.Row + rows
+		if row < 0 {
+			row = 0
+		}
+		if row > last {
+			row = last
+		}
+		if row == p.Row && rows != 0 {
+			return p, linewise, false
+		}
+		col := v.want
+		if col < 0 || col > ed.lastCol(row) {
+			col = ed.lastCol(row)
+		}
+		return Pos{Row: row, Col: col}, linewise, true
+	}
+
+	switch cmd.name {
+	case "h", "left", "backspace":
+		if p.Col == 0 {
+			return p, exclusive, false
+		}
+		p.Col -= n
+		if p.Col < 0 {
+			p.Col = 0
+		}
+		return p, exclusive, true
+
+	case "l", "right", " ":
+		limit := ed.lastCol(p.Row)
+		if forOperator {
+			limit = len(ed.lines[p.Row])
+		}
+		if p.Col >= limit {
+			return p, exclusive, false
+		}
+		p.Col += n
+		if p.Col > limit {
+			p.Col = limit
+		}
+		return p, exclusive, true
+
+	case "j", "down":
+		return vertical(n)
+	case "k", "up":
+		return vertical(-n)
+	case "ctrl+d":
+		return vertical(ed.Height / 2)
+	case "ctrl+u":
+		return vertical(-ed.Height / 2)
+	case "ctrl+f", "pgdown":
+		return vertical(ed.Height)
+	case "ctrl+b", "pgup":
+		return vertical(-ed.Height)
+
+	case "+", "enter", "-", "_":
+		rows := n
+		switch cmd.name {
+		case "-":
+			rows = -n
+		case "_":
+			rows = n - 1
+		}
+		target, kind, ok := vertical(rows)
+		target.Col = ed.firstNonBlank(target.Row)
+		return target, kind, ok || rows == 0
+
+	case "gj", "gk":
+		screen := *ed
+		screen.goalX = -1
+		if cmd.name == "gj" {
+			screen.moveVertical(n)
+		} else {
+			screen.moveVertical(-n)
+		}
+		return screen.CursorPos(), exclusive, screen.CursorPos() != p
+
+	case "w", "W":
+		bigWord := cmd.name == "W"
+		if forOperator && cmd.op == "c" && ed.classAt(p, bigWord) != 0 {
+			// cw works like ce
+			return ed.wordEnd(p, n, bigWord, true), inclusive, true
+		}
+		target := ed.wordForward(p, n, bigWord)
+		if !forOperator && target.Col >= len(ed.lines[target.Row]) {
+			target.Col = ed.lastCol(target.Row)
+		}
+		return target, exclusive, target != p
+	case "b", "B":
+		target := ed.wordBackward(p, n, cmd.name == "B")
+		return target, exclusive, target != p
+	case "e", "E":
+		target := ed.wordEnd(p, n, cmd.name == "E", false)
+		if target.Col >= len(ed.lines[target.Row]) {
+			target.Col = ed.lastCol(target.Row)
+		}
+		return target, inclusive, target != p
+	case "ge", "gE":
+		target := ed.wordEndBackward(p, n, cmd.name == "gE")
+		return target, inclusive, target != p
+
+	case "0", "home":
+		return Pos{Row: p.Row}, exclusive, true
+	case "^":
+		return Pos{Row: p.Row, Col: ed.firstNonBlank(p.Row)}, exclusive, true
+	case "$", "end":
+		row := p.Row + n - 1
+		if row > last {
+			row = last
+		}
+		return Pos{Row: row, Col: ed.lastCol(row)}, inclusive, true
+
+	case "gg", "G":
+		row := 0
+		if cmd.name == "G" {
+			row = last
+		}
+		if cmd.count > 0 {
+			row = cmd.count - 1
+		}
+		if row > last {
+			row = last
+		}
+		return Pos{Row: row, Col: ed.firstNonBlank(row)}, linewise, true
+
+	case "}":
+		return ed.paragraphForward(p, n), exclusive, true
+	case "{":
+		return ed.paragraphBackward(p, n), exclusive, true
+
+	case "f", "F", "t", "T":
+		kind := rune(cmd.name[0])
+		v.lastFind, v.lastFindCh = kind, cmd.char
+		target, ok := ed.findChar(p, kind, cmd.char, n, false)
+		if kind == 'f' || kind == 't' {
+			return target, inclusive, ok
+		}
+		return target, exclusive, ok
+	case ";", ",":
+		if v.lastFind == 0 {
+			return p, exclusive, false
+		}
+		kind := v.lastFind
+		if cmd.name == "," {
+			kind = map[rune]rune{'f': 'F', 'F': 'f', 't': 'T', 'T': 't'}[kind]
+		}
+		target, ok := ed.findChar(p, kind, v.lastFindCh, n, true)
+		if kind == 'f' || kind == 't' {
+			return target, inclusive, ok
+		}
+		return target, exclusive, ok
+
+	case "n", "N":
+		if v.lastSearch == "" {
+			v.Message = "E35: No previous regular expression"
+			return p, exclusive, false
+		}
+		forward := v.lastSearchForward == (cmd.name == "n")
+		target, ok := v.searchFrom(ed, p, v.lastSearch, forward, n)
+		return target, exclusive, ok
+	}
+
+	return p, exclusive, false
+}
+
+// Text objects
+
+// textObject returns the range of a text object around the cursor, to
+// exclusive
+func (v *Vim) textObject(ed *Model, name string, count int) (from, to Pos, lines bool, ok bool) {
+	p := ed.CursorPos()
+	around := name[0] == 'a'
+	obj := name[1:]
+
+	switch obj {
+	case "w", "W":
+		from, to = ed.wordObject(p, obj == "W", around)
+		return from, to, false, to != from
+
+	case "p":
+		first, lastRow := ed.paragraphObject(p.Row, around)
+		return Pos{Row: first}, Pos{Row: lastRow}, true, true
+
+	case "\"", "'", "`":
+		return ed.quoteObject(p, []rune(obj)[0], around)
+
+	case "(", ")", "b":
+		return ed.bracketObject(p, '(', ')', around, count)
+	case "[", "]":
+		return ed.bracketObject(p, '[', ']', around, count)
+	case "{", "}", "B":
+		return ed.bracketObject(p, '{', '}', around, count)
+	case "<", ">":
+		return ed.bracketObject(p, '<', '>', around, count)
+	}
+	return p, p, false, false
+}
+
+// wordObject selects the word (or run of blanks) under p; around adds the
+// blanks after it, or before it when there are none after
+func (m *Model) wordObject(p Pos, bigWord, around bool) (Pos, Pos) {
+	line := m.lines[p.Row]
+	if len(line) == 0 {
+		return p, p
+	}
+	if p.Col >= len(line) {
+		p.Col = len(line) - 1
+	}
+
+	cls := charClass(line[p.Col], bigWord)
+	start, end := p.Col, p.Col+1
+	for start > 0 && charClass(line[start-1], bigWord) == cls {
+		start--
+	}
+	for end < len(line) && charClass(line[end], bigWord) == cls {
+		end++
+	}
+
+	if around {
+		if cls == 0 {
+			// On blanks, "aw" takes the blanks and the following word
+			if end < len(line) {
+				next := charClass(line[end], bigWord)
+				for end < len(line) && charClass(line[end], bigWord) == next {
+					end++
+				}
+			}
+		} else {
+			trailing := end
+			for trailing < len(line) && unicode.IsSpace(line[trailing]) {
+				trailing++
+			}
+			if trailing > end {
+				end = trailing
+			} else {
+				for start > 0 && unicode.IsSpace(line[start-1]) {
+					start--
+				}
+			}
+		}
+	}
+
+	return Pos{Row: p.Row, Col: start}, Pos{Row: p.Row, Col: end}
+}
+
+// paragraphObject returns the rows of the paragraph (or run of empty
+// lines) holding row; around adds the run that follows, or the one before
+// it at the end of the text
+func (m *Model) paragraphObject(row int, around bool) (int, int) {
+	empty := func(row int) bool { return len(m.lines[row]) == 0 }
+
+	kind := empty(row)
+	first, last := row, row
+	for first > 0 && empty(first-1) == kind {
+		first--
+	}
+	for last < len(m.lines)-1 && empty(last+1) == kind {
+		last++
+	}
+
+	if around {
+		if last < len(m.lines)-1 {
+			for last < len(m.lines)-1 && empty(last+1) != kind {
+				last++
+			}
+		} else {
+			for first > 0 && empty(first-1) != kind {
+				first--
+			}
+		}
+	}
+	return first, last
+}
+
+// quoteObject selects the quoted string on the line around p, or the next
+// one after it
+func (m *Model) quoteObject(p Pos, quote rune, around bool) (Pos, Pos, bool, bool) {
+	line := m.lines[p.Row]
+
+	var quotes []int
+	for col, r := range line {
+		if r == quote && (col == 0 || line[col-1] != '\\') {
+			quotes = append(quotes, col)
+		}
+	}
+
+	for i := 0; i+1 < len(quotes); i += 2 {
+		open, close := quotes[i], quotes[i+1]
+		if p.Col > close {
+			continue
+		}
+		if around {
+			end := close + 1
+			for end < len(line) && unicode.IsSpace(line[end]) {
+				end++
+			}
+			return Pos{Row: p.Row, Col: open}, Pos{Row: p.Row, Col: end}, false, true
+		}
+		return Pos{Row: p.Row, Col: open + 1}, Pos{Row: p.Row, Col: close}, false, true
+	}
+	return p, p, false, false
+}
+
+// bracketObject selects the count-th enclosing pair of open and close
+// around p
+func (m *Model) bracketObject(p Pos, open, close rune, around bool, count int) (Pos, Pos, bool, bool) {
+	at := func(q Pos) rune {
+		if q.Col < len(m.lines[q.Row]) {
+			return m.lines[q.Row][q.Col]
+		}
+		return '\n'
+	}
+
+	// Find the unmatched opening bracket at or before the cursor. A closing
+	// bracket under the cursor belongs to the pair.
+	start := p
+	ok := true
+	if at(start) == close {
+		if start, ok = m.prevPos(start); !ok {
+			return p, p, false, false
+		}
+	}
+	for depth := 0; ; {
+		switch at(start) {
+		case close:
+			depth++
+		case open:
+			if depth == 0 {
+				count--
+			} else {
+				depth--
+			}
+		}
+		if count == 0 {
+			break
+		}
+		if start, ok = m.prevPos(start); !ok {
+			return p, p, false, false
+		}
+	}
+
+	// And its closing bracket
+	end := start
+	for depth := 0; ; {
+		if end, ok = m.nextPos(end); !ok {
+			return p, p, false, false
+		}
+		switch at(end) {
+		case open:
+			depth++
+		case close:
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if around {
+				end.Col++
+				return start, end, false, true
+			}
+			// A block whose brackets sit on their own lines works on the
+			// lines in between, so di{ keeps the braces on separate lines
+			if start.Col == len(m.lines[start.Row])-1 && end.Col == m.firstNonBlank(end.Row) && end.Row > start.Row+1 {
+				return Pos{Row: start.Row + 1}, Pos{Row: end.Row - 1}, true, true
+			}
+			inner, _ := m.nextPos(start)
+			return inner, end, false, true
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package editor
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeKeys sends keys to v one at a time; \x1b is Esc and \n is Enter
+func typeKeys(v *Vim, ed *Model, keys string) {
+	for _, r := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+		switch r {
+		case '\x1b':
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case '\n':
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case ' ':
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+		}
+		v.HandleKey(ed, msg)
+	}
+}
+
+func TestVimInsertCounts(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		keys string
+		want string
+	}{
+		{"i", "", "3ix\x1b", "xxx"},
+		{"i before text", "ab", "2iyz\x1b", "yzyzab"},
+		{"a", "ab", "3a-\x1b", "a---b"},
+		{"I", "  ab", "$2I>\x1b", "  >>ab"},
+		{"A", "x", "3Aab\x1b", "xababab"},
+		{"o", "a", "2ofoo\x1b", "a\nfoo\nfoo"},
+		{"O", "a", "2Ofoo\x1b", "foo\nfoo\na"},
+		{"o keeps indent", "  a", "2ox\x1b", "  a\n  x\n  x"},
+		{"insert with a line break", "", "2ia\nb\x1b", "a\nba\nb"},
+		{"no count", "", "ix\x1b", "x"},
+		{"change count is for the motion", "one two three four", "3cwX\x1b", "X four"},
+		{"dot with a count", "", "ix\x1b3.", "xxxx"},
+		{"dot after a", "ab", "aX\x1b2.", "aXXXb"},
+		{"dot repeats the count", "", "3ix\x1b.", "xxxxxx"},
+		{"dot count replaces the count", "", "3ix\x1b2.", "xxxxx"},
+		{"dot after o", "a", "ob\x1b2.", "a\nb\nb\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ed := New()
+			ed.SetValue(tt.text)
+			ed.SetCursor(0, 0)
+			v := NewVim()
+
+			typeKeys(v, &ed, tt.keys)
+			if got := ed.Value(); got != tt.want {
+				t.Errorf("after %q: %q, want %q", tt.keys, got, tt.want)
+			}
+			if v.Mode != VimNormal {
+				t.Errorf("mode = %v, want normal", v.Mode)
+			}
+		})
+	}
+}
+
+func TestVimInsertCountIsOneUndoStep(t *testing.T) {
+	ed := New()
+	ed.SetValue("ab")
+	ed.SetCursor(0, 0)
+	v := NewVim()
+
+	typeKeys(v, &ed, "3ix\x1b")
+	if got := ed.Value(); got != "xxxab" {
+		t.Fatalf("after 3ix: %q, want %q", got, "xxxab")
+	}
+	typeKeys(v, &ed, "u")
+	if got := ed.Value(); got != "ab" {
+		t.Errorf("after undo: %q, want %q", got, "ab")
+	}
+}
+
+func TestVimResetDropsInsertCount(t *testing.T) {
+	ed := New()
+	v := NewVim()
+
+	typeKeys(v, &ed, "3ix")
+	v.Reset(&ed)
+	typeKeys(v, &ed, "iy\x1b")
+	if got := ed.Value(); got != "xy" {
+		t.Errorf("got %q, want %q", got, "xy")
+	}
+}
@@ -0,0 +1,391 @@
+// Package figlet draws text as large ASCII-art banners using FIGlet fonts.
+// The standard and small fonts are bundled.
+package figlet
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+//go:embed fonts/*.flf
+var fontFiles embed.FS
+
+// Layout bits of a font header, from the FIGfont specification
+const (
+	smushEqual     = 1
+	smushLowline   = 2
+	smushHierarchy = 4
+	smushPair      = 8
+	smushBigX      = 16
+	smushHardblank = 32
+	layoutKern     = 64
+	layoutSmush    = 128
+)
+
+// Font is a parsed FIGlet font
+type Font struct {
+	height    int
+	hardblank rune
+	layout    int
+	chars     map[rune][]string
+}
+
+var (
+	fontsMu sync.Mutex
+	fonts   = make(map[string]*Font)
+)
+
+// Lookup returns a bundled font by name, such as "standard" or "small".
+// Fonts are parsed on first use.
+func Lookup(name string) (*Font, error) {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	if font, ok := fonts[name]; ok {
+		return font, nil
+	}
+	f, err := fontFiles.Open("fonts/" + name + ".flf")
+	if err != nil {
+		return nil, fmt.Errorf("unknown font %q", name)
+	}
+	defer f.Close()
+
+	font, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("font %q: %w", name, err)
+	}
+	fonts[name] = font
+	return font, nil
+}
+
+// Parse reads a font in the FIGfont (flf2a) format
+func Parse(r io.Reader) (*Font, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("empty font")
+	}
+	header := strings.Fields(scanner.Text())
+	if len(header) < 6 || !strings.HasPrefix(header[0], "flf2a") || utf8.RuneCountInString(header[0]) < 6 {
+		return nil, fmt.Errorf("not a FIGlet font")
+	}
+
+	numbers := make([]int, len(header)-1)
+	for i, field := range header[1:] {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("bad header field %q", field)
+		}
+		numbers[i] = n
+	}
+	font := &Font{
+		height:    numbers[0],
+		hardblank: []rune(header[0])[5],
+		chars:     make(map[rune][]string),
+	}
+	if font.height < 1 {
+		return nil, fmt.Errorf("bad height %d", font.height)
+	}
+
+	// The full layout, when present, supersedes the old one
+	switch old := numbers[3]; {
+	case len(numbers) >= 7:
+		font.layout = numbers[6]
+	case old < 0:
+		font.layout = 0
+	case old == 0:
+		font.layout = layoutKern
+	default:
+		font.layout = old&63 | layoutSmush
+	}
+
+	for i := 0; i < numbers[4]; i++ {
+		scanner.Scan()
+	}
+
+	readChar := func() ([]string, bool) {
+		rows := make([]string, font.height)
+		for i := range rows {
+			if !scanner.Scan() {
+				return nil, false
+			}
+			rows[i] = trimEndmark(scanner.Text())
+		}
+		return rows, true
+	}
+
+	// ASCII 32-126 and seven German letters come first, in order
+	required := []rune{196, 214, 220, 228, 246, 252, 223}
+	for c := rune(32); c <= 126; c++ {
+		rows, ok := readChar()
+		if !ok {
+			return nil, fmt.Errorf("missing character %q", c)
+		}
+		font.chars[c] = rows
+	}
+	for _, c := range required {
+		rows, ok := readChar()
+		if !ok {
+			return font, nil
+		}
+		font.chars[c] = rows
+	}
+
+	// Then any number of characters, each after a line with its code
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		code, err := strconv.ParseInt(fields[0], 0, 64)
+		rows, ok := readChar()
+		if !ok {
+			break
+		}
+		if err == nil && code >= 0 {
+			font.chars[rune(code)] = rows
+		}
+	}
+	return font, scanner.Err()
+}
+
+// trimEndmark strips the end marks closing each row of a character
+func trimEndmark(line string) string {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return line
+	}
+	mark, _ := utf8.DecodeLastRuneInString(line)
+	return strings.TrimRight(line, string(mark))
+}
+
+// Height is the number of rows in each line of a banner
+func (f *Font) Height() int {
+	return f.height
+}
+
+// Render draws text as a banner no wider than width, moving words that
+// don't fit to further banner lines, up to maxLines of them. It returns
+// false if the text doesn't fit or the font lacks one of its characters.
+func (f *Font) Render(text string, width, maxLines int) (string, bool) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return "", false
+	}
+
+	var lines [][]string
+	var current []string
+	for _, word := range words {
+		if _, ok := f.line(word); !ok {
+			return "", false
+		}
+		candidate := append(append([]string{}, current...), word)
+		if rows, _ := f.line(strings.Join(candidate, " ")); rowWidth(rows) <= width {
+			current = candidate
+			continue
+		}
+		if len(current) == 0 {
+			return "", false
+		}
+		rows, _ := f.line(strings.Join(current, " "))
+		lines = append(lines, rows)
+		current = []string{word}
+		if rows, _ := f.line(word); rowWidth(rows) > width {
+			return "", false
+		}
+	}
+	rows, _ := f.line(strings.Join(current, " "))
+	lines = append(lines, rows)
+	if len(lines) > maxLines {
+		return "", false
+	}
+
+	var out []string
+	for _, rows := range lines {
+		out = append(out, trimBlankRows(rows)...)
+	}
+	return strings.Join(out, "\n"), true
+}
+
+// line draws text on a single banner line
+func (f *Font) line(text string) ([]string, bool) {
+	rows := make([][]rune, f.height)
+	prevWidth := 0
+	for _, c := range text {
+		glyph, ok := f.chars[c]
+		if !ok {
+			return nil, false
+		}
+		char := make([][]rune, f.height)
+		for i := range char {
+			char[i] = []rune(glyph[i])
+		}
+		width := 0
+		if len(char) > 0 {
+			width = len(char[0])
+		}
+
+		overlap := f.overlap(rows, char, prevWidth, width)
+		for i := range rows {
+			for k := 0; k < overlap && k < len(char[i]); k++ {
+				col := len(rows[i]) - overlap + k
+				if col < 0 {
+					continue
+				}
+				if merged := f.smush(rows[i][col], char[i][k], prevWidth, width); merged != 0 {
+					rows[i][col] = merged
+				}
+			}
+			if overlap < len(char[i]) {
+				rows[i] = append(rows[i], char[i][overlap:]...)
+			}
+		}
+		prevWidth = width
+	}
+
+	out := make([]string, f.height)
+	for i, row := range rows {
+		out[i] = strings.TrimRight(strings.ReplaceAll(string(row), string(f.hardblank), " "), " ")
+	}
+	return out, true
+}
+
+// overlap is how many columns the next character can be moved left into
+// the line: past the blanks between them when kerning, and one further
+// when the touching characters smush
+func (f *Font) overlap(rows, char [][]rune, prevWidth, width int) int {
+	if f.layout&(layoutKern|layoutSmush) == 0 {
+		return 0
+	}
+
+	amount := width
+	for i := range rows {
+		line := rows[i]
+		start := 0
+		for start < len(char[i]) && char[i][start] == ' ' {
+			start++
+		}
+
+		// At the start of the line only the character's own margin goes
+		n := start
+		if len(line) > 0 {
+			end := len(line) - 1
+			for end > 0 && line[end] == ' ' {
+				end--
+			}
+			n = start + len(line) - 1 - end
+			if left := line[end]; left == ' ' {
+				n++
+			} else if start < len(char[i]) && f.smush(left, char[i][start], prevWidth, width) != 0 {
+				n++
+			}
+		}
+		if n < amount {
+			amount = n
+		}
+	}
+	return amount
+}
+
+// smush returns the character that two touching characters merge into, or
+// 0 if they don't merge under the font's rules
+func (f *Font) smush(left, right rune, prevWidth, width int) rune {
+	switch {
+	case left == ' ':
+		return right
+	case right == ' ':
+		return left
+	case prevWidth < 2 || width < 2:
+		return 0
+	case f.layout&layoutSmush == 0:
+		return 0
+	}
+
+	rules := f.layout & 63
+	if rules == 0 {
+		// Universal smushing: the right character wins over all but hardblanks
+		if left == f.hardblank {
+			return right
+		}
+		if right == f.hardblank {
+			return left
+		}
+		return right
+	}
+
+	if rules&smushHardblank != 0 && left == f.hardblank && right == f.hardblank {
+		return left
+	}
+	if left == f.hardblank || right == f.hardblank {
+		return 0
+	}
+	if rules&smushEqual != 0 && left == right {
+		return left
+	}
+	if rules&smushLowline != 0 {
+		if left == '_' && strings.ContainsRune(`|/\[]{}()<>`, right) {
+			return right
+		}
+		if right == '_' && strings.ContainsRune(`|/\[]{}()<>`, left) {
+			return left
+		}
+	}
+	if rules&smushHierarchy != 0 {
+		classes := []string{`|`, `/\`, `[]`, `{}`, `()`, `<>`}
+		rank := func(c rune) int {
+			for i, class := range classes {
+				if strings.ContainsRune(class, c) {
+					return i
+				}
+			}
+			return -1
+		}
+		if l, r := rank(left), rank(right); l >= 0 && r >= 0 && l != r {
+			if l > r {
+				return left
+			}
+			return right
+		}
+	}
+	if rules&smushPair != 0 {
+		switch string([]rune{left, right}) {
+		case "[]", "][", "{}", "}{", "()", ")(":
+			return '|'
+		}
+	}
+	if rules&smushBigX != 0 {
+		switch string([]rune{left, right}) {
+		case `/\`:
+			return '|'
+		case `\/`:
+			return 'Y'
+		case "><":
+			return 'X'
+		}
+	}
+	return 0
+}
+
+func rowWidth(rows []string) int {
+	width := 0
+	for _, row := range rows {
+		if n := utf8.RuneCountInString(row); n > width {
+			width = n
+		}
+	}
+	return width
+}
+
+// trimBlankRows drops empty rows below the text, such as the descender
+// row of a line without descenders
+func trimBlankRows(rows []string) []string {
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
@@ -0,0 +1,101 @@
+// Package highlight splits source code into classified tokens with the
+// chroma lexers, so callers can colour each class as they like.
+package highlight
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Class is the kind of a token, which decides its colour
+type Class int
+
+const (
+	Plain Class = iota
+	Keyword
+	Type
+	Function
+	Builtin
+	Variable
+	String
+	Number
+	Comment
+	Operator
+	numClasses
+)
+
+// Span is a run of code of one class
+type Span struct {
+	Text  string
+	Class Class
+}
+
+// Palette is the style of each class
+type Palette [numClasses]lipgloss.Style
+
+// Lex splits code into lines of tokens, using the lexer for language, such
+// as "go", "sql" or "sh". It returns false if there is no lexer for it.
+func Lex(language, code string) ([][]Span, bool) {
+	language = strings.TrimSpace(language)
+	if language == "" {
+		return nil, false
+	}
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return nil, false
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return nil, false
+	}
+
+	lines := [][]Span{nil}
+	for _, token := range iterator.Tokens() {
+		class := classify(token.Type)
+		for i, part := range strings.Split(token.Value, "\n") {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if part != "" {
+				last := len(lines) - 1
+				lines[last] = append(lines[last], Span{Text: part, Class: class})
+			}
+		}
+	}
+
+	// Lexers end the text with a newline if it lacks one
+	if want := strings.Count(code, "\n") + 1; len(lines) > want {
+		lines = lines[:want]
+	}
+	return lines, true
+}
+
+// classify maps chroma's fine-grained token types onto classes
+func classify(t chroma.TokenType) Class {
+	switch {
+	case t == chroma.KeywordType || t == chroma.NameClass || t == chroma.NameNamespace:
+		return Type
+	case t.InCategory(chroma.Keyword) || t == chroma.OperatorWord || t == chroma.NameTag ||
+		t.InSubCategory(chroma.CommentPreproc):
+		return Keyword
+	case t == chroma.NameFunction || t == chroma.NameFunctionMagic || t == chroma.NameAttribute ||
+		t == chroma.NameDecorator:
+		return Function
+	case t == chroma.NameBuiltin || t == chroma.NameBuiltinPseudo || t == chroma.NameConstant:
+		return Builtin
+	case t >= chroma.NameVariable && t <= chroma.NameVariableMagic:
+		return Variable
+	case t.InSubCategory(chroma.LiteralString) || t == chroma.GenericInserted:
+		return String
+	case t.InSubCategory(chroma.LiteralNumber) || t == chroma.Literal || t == chroma.LiteralDate:
+		return Number
+	case t.InCategory(chroma.Comment) || t == chroma.GenericDeleted:
+		return Comment
+	case t.InCategory(chroma.Operator):
+		return Operator
+	}
+	return Plain
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/ssh-notes/terminal-notes/logger"
+)
+
+const (
+	lockoutBase   = time.Minute    // first lockout duration
+	lockoutMax    = time.Hour      // cap for exponential backoff
+	attemptExpiry = 24 * time.Hour // forget idle counters after this long
+)
+
+// pubkeyFailureContextKey marks a connection whose public key failure has
+// already been counted; clients offer several keys per connection.
+var pubkeyFailureContextKey = &struct{ name string }{"pubkey-failure-counted"}
+
+// attemptRecord tracks failures for a single IP or username
+type attemptRecord struct {
+	failures    int
+	lockouts    int // consecutive lockouts, drives the backoff
+	lockedUntil time.Time
+	lastSeen    time.Time
+}
+
+// LoginTracker counts failed logins per remote IP and per username and
+// locks either out with exponential backoff once maxAttempts is reached
+type LoginTracker struct {
+	mu          sync.Mutex
+	maxAttempts int
+	byIP        map[string]*attemptRecord
+	byUser      map[string]*attemptRecord
+}
+
+func NewLoginTracker(maxAttempts int) *LoginTracker {
+	return &LoginTracker{
+		maxAttempts: maxAttempts,
+		byIP:        make(map[string]*attemptRecord),
+		byUser:      make(map[string]*attemptRecord),
+	}
+}
+
+// Locked reports whether the IP or the username is currently locked out
+func (lt *LoginTracker) Locked(ip, username string) bool {
+	if lt.maxAttempts <= 0 {
+		return false
+	}
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := time.Now()
+	if rec := lt.byIP[ip]; rec != nil && now.Before(rec.lockedUntil) {
+		return true
+	}
+	if rec := lt.byUser[username]; rec != nil && now.Before(rec.lockedUntil) {
+		return true
+	}
+	return false
+}
+
+// Failure records a failed attempt for both the IP and the username
+func (lt *LoginTracker) Failure(ip, username string) {
+	if lt.maxAttempts <= 0 {
+		return
+	}
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := time.Now()
+	lt.expire(now)
+	lt.fail(lt.byIP, ip, "ip", now)
+	lt.fail(lt.byUser, username, "user", now)
+}
+
+// Success clears the failure counters after a successful login
+func (lt *LoginTracker) Success(ip, username string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	delete(lt.byIP, ip)
+	delete(lt.byUser, username)
+}
+
+func (lt *LoginTracker) fail(records map[string]*attemptRecord, key, kind string, now time.Time) {
+	rec := records[key]
+	if rec == nil {
+		rec = &attemptRecord{}
+		records[key] = rec
+	}
+	rec.lastSeen = now
+	rec.failures++
+
+	if rec.failures < lt.maxAttempts {
+		return
+	}
+
+	// Exponential backoff: 1m, 2m, 4m, ... capped at lockoutMax
+	duration := lockoutBase << rec.lockouts
+	if duration > lockoutMax || duration <= 0 {
+		duration = lockoutMax
+	}
+	rec.lockouts++
+	rec.failures = 0
+	rec.lockedUntil = now.Add(duration)
+
+	logger.Warn("Login lockout: %s=%s locked for %v after %d failed attempts", kind, key, duration, lt.maxAttempts)
+}
+
+// expire drops records that have been idle and unlocked for attemptExpiry
+func (lt *LoginTracker) expire(now time.Time) {
+	for _, records := range []map[string]*attemptRecord{lt.byIP, lt.byUser} {
+		for key, rec := range records {
+			if now.After(rec.lockedUntil) && now.Sub(rec.lastSeen) > attemptExpiry {
+				delete(records, key)
+			}
+		}
+	}
+}
+
+// WrapPassword guards a password handler with the tracker
+func (lt *LoginTracker) WrapPassword(next ssh.PasswordHandler) ssh.PasswordHandler {
+	return func(ctx ssh.Context, password string) bool {
+		ip, username := remoteIP(ctx.RemoteAddr()), ctx.User()
+		if lt.Locked(ip, username) {
+			logger.Warn("Rejected password login for %s from %s: locked out", username, ip)
+			return false
+		}
+
+		if !next(ctx, password) {
+			lt.Failure(ip, username)
+			return false
+		}
+		return true
+	}
+}
+
+// WrapPublicKey guards a public key handler with the tracker. Only the first
+// rejected key of a connection counts, since clients try every key they have.
+// Accepted keys don't clear the counters: the handler also answers unsigned
+// queries, which prove nothing about who is connecting.
+func (lt *LoginTracker) WrapPublicKey(next ssh.PublicKeyHandler) ssh.PublicKeyHandler {
+	return func(ctx ssh.Context, key ssh.PublicKey) bool {
+		ip, username := remoteIP(ctx.RemoteAddr()), ctx.User()
+		if lt.Locked(ip, username) {
+			logger.Warn("Rejected public key login for %s from %s: locked out", username, ip)
+			return false
+		}
+
+		if !next(ctx, key) {
+			if ctx.Value(pubkeyFailureContextKey) == nil {
+				ctx.SetValue(pubkeyFailureContextKey, true)
+				lt.Failure(ip, username)
+			}
+			return false
+		}
+		return true
+	}
+}
+
+// WrapSession clears the failure counters once a connection has fully
+// authenticated and its session starts
+func (lt *LoginTracker) WrapSession(next ssh.Handler) ssh.Handler {
+	return func(s ssh.Session) {
+		lt.Success(remoteIP(s.RemoteAddr()), s.User())
+		next(s)
+	}
+}
+
+// remoteIP strips the port from a remote address
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -30,7 +30,7 @@ func main() {
 	defer utils.RecoverPanic()
 	
 	// Check if running as CLI command
-	if len(os.Args) > 1 && (os.Args[1] == "export" || os.Args[1] == "import") {
+	if len(os.Args) > 1 && (os.Args[1] == "export" || os.Args[1] == "import" || os.Args[1] == "passwd" || os.Args[1] == "replace" || os.Args[1] == "links" || os.Args[1] == "tasks") {
 		runCLI()
 		return
 	}
@@ -117,18 +117,26 @@ func main() {
 }
 
 func setupAuth(server *ssh.Server, cfg *config.Config) {
+	tracker := NewLoginTracker(cfg.Security.MaxLoginAttempts)
+	
 	switch cfg.Security.AuthMode {
 	case "password":
-		server.PasswordHandler = setupPasswordAuth(cfg)
+		server.PasswordHandler = tracker.WrapPassword(setupPasswordAuth(cfg))
 	case "key":
-		server.PublicKeyHandler = setupKeyAuth(cfg)
+		server.PublicKeyHandler = tracker.WrapPublicKey(setupKeyAuth(cfg))
+		server.PtyCallback = checkKeyPTY
 	case "both":
-		server.PasswordHandler = setupPasswordAuth(cfg)
-		server.PublicKeyHandler = setupKeyAuth(cfg)
+		server.PasswordHandler = tracker.WrapPassword(setupPasswordAuth(cfg))
+		server.PublicKeyHandler = tracker.WrapPublicKey(setupKeyAuth(cfg))
+		server.PtyCallback = checkKeyPTY
 	default: // "none"
 		server.PasswordHandler = func(ctx ssh.Context, password string) bool { return true }
 		server.PublicKeyHandler = func(ctx ssh.Context, key ssh.PublicKey) bool { return true }
+		return
 	}
+	
+	// Only a session proves the login went all the way through
+	server.Handler = tracker.WrapSession(server.Handler)
 }
 
 func setupPasswordAuth(cfg *config.Config) func(ssh.Context, string) bool {
@@ -137,11 +145,13 @@ func setupPasswordAuth(cfg *config.Config) func(ssh.Context, string) bool {
 	}
 	
 	// Load password file if specified
-	auth := NewUserAuth()
+	auth := NewUserAuth(cfg.Security.PasswordAlgorithm)
 	if cfg.Security.PasswordFile != "" {
 		if err := auth.LoadUsers(cfg.Security.PasswordFile); err != nil {
 			logger.Warn("Failed to load password file: %v", err)
 		}
+	} else {
+		logger.Warn("require_password is set but no password_file is configured; all logins will be rejected")
 	}
 	
 	return func(ctx ssh.Context, password string) bool {
@@ -156,13 +166,60 @@ func setupPasswordAuth(cfg *config.Config) func(ssh.Context, string) bool {
 
 func setupKeyAuth(cfg *config.Config) func(ssh.Context, ssh.PublicKey) bool {
 	return func(ctx ssh.Context, key ssh.PublicKey) bool {
-		// In production, check against authorized_keys file
-		// For now, accept all keys if no file specified
-		if cfg.Security.AuthorizedKeysFile == "" {
+		username := ctx.User()
+		if err := utils.ValidateUsername(username); err != nil {
+			logger.Warn("Invalid username: %s", username)
+			return false
+		}
+		
+		// Keys are looked up in the global file (if configured), where they
+		// only count for the user their comment names, and in the user's own
+		// <data_dir>/<user>/.ssh/authorized_keys
+		var files []string
+		if cfg.Security.AuthorizedKeysFile != "" {
+			files = append(files, cfg.Security.AuthorizedKeysFile)
+		}
+		userFile := userAuthorizedKeysPath(cfg.Server.DataDir, username)
+		if _, err := os.Stat(userFile); err == nil {
+			files = append(files, userFile)
+		}
+		
+		// No key files at all: nothing to check the key against
+		if len(files) == 0 {
+			logger.Warn("Public key rejected for user %s: no authorized_keys file", username)
+			return false
+		}
+		
+		for _, file := range files {
+			keys, err := loadAuthorizedKeys(file)
+			if err != nil {
+				logger.Warn("Failed to load authorized keys %s: %v", file, err)
+				continue
+			}
+			if file == cfg.Security.AuthorizedKeysFile {
+				keys = keysForUser(keys, username)
+			}
+			
+			entry := findAuthorizedKey(keys, key)
+			if entry == nil {
+				continue
+			}
+			
+			if entry.options.From != "" && !matchFrom(entry.options.From, ctx.RemoteAddr()) {
+				logger.Warn("Key for %s rejected: %s not allowed by from=%q", username, ctx.RemoteAddr(), entry.options.From)
+				continue
+			}
+			if !entry.options.ExpiryTime.IsZero() && time.Now().After(entry.options.ExpiryTime) {
+				logger.Warn("Key for %s rejected: expired at %s", username, entry.options.ExpiryTime.Format(time.RFC3339))
+				continue
+			}
+			
+			ctx.SetValue(keyOptionsContextKey, entry.options)
 			return true
 		}
-		// TODO: Implement authorized_keys checking
-		return true
+		
+		logger.Warn("Public key rejected for user %s from %s", username, ctx.RemoteAddr())
+		return false
 	}
 }
 
@@ -186,9 +243,17 @@ func handleSSHSession(s ssh.Session) {
 	}
 	
 	logger.LogConnection(username, remoteAddr)
+	
 	defer func() {
 		logger.LogDisconnection(username, time.Since(startTime))
 	}()
+	
+	// The server only ever runs the notes TUI, which no-pty keys may not use
+	if opts, ok := keyOptionsFromContext(s.Context()); ok && opts.NoPTY {
+		logger.Warn("Session refused for %s from %s: key does not allow a terminal", username, remoteAddr)
+		fmt.Fprintf(s, "Error: This key is not allowed an interactive session\r\n")
+		return
+	}
 
 	// Load config to get data directory
 	cfg, err := config.LoadConfig(*cfgPath)
@@ -197,6 +262,8 @@ func handleSSHSession(s ssh.Session) {
 	}
 	
 	userDataDir := filepath.Join(cfg.Server.DataDir, username)
+	idleTimeout := time.Duration(cfg.Security.SessionTimeout) * time.Second
+	maxDuration := time.Duration(cfg.Security.MaxSessionDuration) * time.Second
 
 	// Create user directory if it doesn't exist
 	if err := os.MkdirAll(userDataDir, 0700); err != nil {
@@ -213,9 +280,11 @@ func handleSSHSession(s ssh.Session) {
 		fmt.Fprintf(s, "Using default terminal size...\r\n")
 		width, height := 80, 24
 		app := NewApp(username, userDataDir)
+		app.SetSessionLimits(idleTimeout, maxDuration)
 		if err := app.Run(s, width, height); err != nil {
 			fmt.Fprintf(s, "Error: %v\r\n", err)
 		}
+		reportTimeout(s, app)
 		return
 	}
 
@@ -224,6 +293,7 @@ func handleSSHSession(s ssh.Session) {
 
 	// Initialize and run TUI
 	app := NewApp(username, userDataDir)
+	app.SetSessionLimits(idleTimeout, maxDuration)
 	
 	// Handle window resize
 	go func() {
@@ -242,6 +312,14 @@ func handleSSHSession(s ssh.Session) {
 		logger.Error("App error for user %s: %v", username, err)
 		fmt.Fprintf(s, "Error: %v\r\n", err)
 	}
+	reportTimeout(s, app)
+}
+
+// reportTimeout tells the user why they were disconnected
+func reportTimeout(s ssh.Session, app *App) {
+	if reason := app.TimeoutReason(); reason != "" {
+		fmt.Fprintf(s, "\x1b[?25hDisconnected: %s.\r\n", reason)
+	}
 }
 
 // Authentication removed - using NoClientAuth for terminal.shop-like experience
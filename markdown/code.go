@@ -0,0 +1,141 @@
+package markdown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/ssh-notes/terminal-notes/highlight"
+	"github.com/yuin/goldmark/ast"
+)
+
+// codeBlock renders code behind a bar, highlighted when the language is
+// known and numbered if CodeLineNumbers is set. Lines too long for the
+// width are broken.
+func (r *renderer) codeBlock(language string, code []string, width int) []string {
+	var lines []string
+	if language != "" {
+		lines = append(lines, r.style.CodeLabel.Render(language))
+	}
+
+	var tokens [][]highlight.Span
+	if r.style.Syntax != nil {
+		tokens, _ = highlight.Lex(language, strings.Join(code, "\n"))
+	}
+
+	bar := r.style.Rule.Render("│") + " "
+	numberWidth := len(fmt.Sprint(len(code)))
+	used := 2
+	if r.style.CodeLineNumbers {
+		used += numberWidth + 1
+	}
+	textWidth := childWidth(width, used)
+
+	for i, line := range code {
+		var spans []span
+		if i < len(tokens) {
+			for _, token := range tokens[i] {
+				style := layer(r.style.Syntax[token.Class], r.style.CodeBlock)
+				spans = append(spans, span{expandTabs(token.Text), style})
+			}
+		} else {
+			spans = []span{{expandTabs(line), r.style.CodeBlock}}
+		}
+
+		for j, part := range breakSpans(spans, textWidth) {
+			prefix := bar
+			if r.style.CodeLineNumbers {
+				number := strings.Repeat(" ", numberWidth)
+				if j == 0 {
+					number = fmt.Sprintf("%*d", numberWidth, i+1)
+				}
+				prefix += r.style.LineNumber.Render(number) + " "
+			}
+			lines = append(lines, prefix+part)
+		}
+	}
+	return lines
+}
+
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+// breakSpans draws spans as lines no wider than width, breaking anywhere
+func breakSpans(spans []span, width int) []string {
+	var lines []string
+	var line []piece
+	lineWidth := 0
+	for i, s := range spans {
+		for _, r := range s.text {
+			w := runewidth.RuneWidth(r)
+			if lineWidth+w > width && lineWidth > 0 {
+				lines = append(lines, renderPieces(line, spans))
+				line = nil
+				lineWidth = 0
+			}
+			if n := len(line); n > 0 && line[n-1].span == i {
+				line[n-1].text += string(r)
+			} else {
+				line = append(line, piece{text: string(r), span: i})
+			}
+			lineWidth += w
+		}
+	}
+	return append(lines, renderPieces(line, spans))
+}
+
+// CodeLine is a line of a fenced code block: its row in the source, the
+// column (in runes) where the code starts, and the code
+type CodeLine struct {
+	Row  int
+	Col  int
+	Text string
+}
+
+// CodeBlock is a fenced code block found by CodeBlocks
+type CodeBlock struct {
+	Language string
+	Lines    []CodeLine
+}
+
+// CodeBlocks finds the fenced code blocks of markdown source, including
+// those nested in lists and quotes, so an editor can highlight them in
+// place
+func CodeBlocks(source string) []CodeBlock {
+	src := []byte(source)
+	doc := parse(src)
+
+	// Byte offset of the start of each row
+	starts := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+
+	var blocks []CodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		fenced, ok := n.(*ast.FencedCodeBlock)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		block := CodeBlock{Language: string(fenced.Language(src))}
+		segments := fenced.Lines()
+		for i := 0; i < segments.Len(); i++ {
+			segment := segments.At(i)
+			row := sort.Search(len(starts), func(j int) bool { return starts[j] > segment.Start }) - 1
+			block.Lines = append(block.Lines, CodeLine{
+				Row:  row,
+				Col:  utf8.RuneCount(src[starts[row]:segment.Start]),
+				Text: strings.TrimRight(string(segment.Value(src)), "\r\n"),
+			})
+		}
+		blocks = append(blocks, block)
+		return ast.WalkSkipChildren, nil
+	})
+	return blocks
+}
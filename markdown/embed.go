@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Embed is a ![[note]] in a document, standing for the text of that note,
+// or of one of its sections with ![[note#heading]]
+type Embed struct {
+	Start, End int // offsets of the embed in the source
+	Target     string
+	Heading    string
+	Alone      bool // whether the embed is the only thing on its line
+}
+
+// Embeds returns the embeds in source in the order they appear. Embeds in
+// code are not counted.
+func Embeds(source string) []Embed {
+	src := []byte(source)
+	var embeds []Embed
+	for _, link := range findWikiLinks(parse(src)) {
+		if !link.embed {
+			continue
+		}
+		target, heading, _ := strings.Cut(link.target, "#")
+		lineStart := bytes.LastIndexByte(src[:link.start], '\n') + 1
+		lineEnd := len(src)
+		if i := bytes.IndexByte(src[link.end:], '\n'); i >= 0 {
+			lineEnd = link.end + i
+		}
+		embeds = append(embeds, Embed{
+			Start:   link.start,
+			End:     link.end,
+			Target:  strings.TrimSpace(target),
+			Heading: strings.TrimSpace(heading),
+			Alone: len(bytes.TrimSpace(src[lineStart:link.start])) == 0 &&
+				len(bytes.TrimSpace(src[link.end:lineEnd])) == 0,
+		})
+	}
+	return embeds
+}
+
+// Section returns the part of source under the top-level heading with the
+// given text, ignoring case: the heading and what follows it up to the
+// next heading of the same or a higher level
+func Section(source, heading string) (string, bool) {
+	src := []byte(source)
+	heading = strings.TrimSpace(heading)
+	start, level := -1, 0
+	for n := parse(src).FirstChild(); n != nil; n = n.NextSibling() {
+		h, ok := n.(*ast.Heading)
+		if !ok || h.Lines().Len() == 0 {
+			continue
+		}
+		at := bytes.LastIndexByte(src[:h.Lines().At(0).Start], '\n') + 1
+		if start >= 0 && h.Level <= level {
+			return strings.TrimSpace(source[start:at]), true
+		}
+		if start < 0 && strings.EqualFold(strings.TrimSpace(nodeText(h, src)), heading) {
+			start, level = at, h.Level
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(source[start:]), true
+}
+
+// nodeText returns the text of an inline node and its children, without
+// markup
+func nodeText(n ast.Node, src []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(src))
+			if n.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *wikiLink:
+			if n.alias != "" {
+				b.WriteString(n.alias)
+			} else {
+				b.WriteString(n.target)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
@@ -0,0 +1,220 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// span is a run of inline text in one style. A span of "\n" is a hard line
+// break.
+type span struct {
+	text  string
+	style lipgloss.Style
+}
+
+// inline flattens the inline content of a block into styled spans
+func (r *renderer) inline(n ast.Node, style lipgloss.Style) []span {
+	var spans []span
+	r.collect(n, style, &spans)
+	return spans
+}
+
+func (r *renderer) collect(n ast.Node, style lipgloss.Style, spans *[]span) {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		switch c := child.(type) {
+		case *ast.Text:
+			value := c.Segment.Value(r.src)
+			if !c.IsRaw() {
+				value = unescape(value)
+			}
+			*spans = append(*spans, span{string(value), style})
+			switch {
+			case c.HardLineBreak():
+				*spans = append(*spans, span{"\n", style})
+			case c.SoftLineBreak():
+				*spans = append(*spans, span{" ", style})
+			}
+		case *ast.String:
+			value := c.Value
+			if !c.IsRaw() && !c.IsCode() {
+				value = unescape(value)
+			}
+			*spans = append(*spans, span{string(value), style})
+		case *ast.CodeSpan:
+			r.collect(c, layer(r.style.Code, style), spans)
+		case *ast.Emphasis:
+			if c.Level >= 2 {
+				r.collect(c, layer(r.style.Bold, style), spans)
+			} else {
+				r.collect(c, layer(r.style.Italic, style), spans)
+			}
+		case *east.Strikethrough:
+			r.collect(c, layer(r.style.Strike, style), spans)
+		case *ast.Link:
+			start := len(*spans)
+			r.collect(c, layer(r.style.Link, style), spans)
+			// Show where the link goes unless its text already says so
+			destination := string(c.Destination)
+			if destination != "" && plainText((*spans)[start:]) != destination {
+				*spans = append(*spans, span{" (" + destination + ")", layer(r.style.LinkURL, style)})
+			}
+		case *ast.AutoLink:
+			*spans = append(*spans, span{string(c.Label(r.src)), layer(r.style.Link, style)})
+		case *ast.Image:
+			alt := plainText(r.inline(c, style))
+			if alt == "" {
+				alt = string(c.Destination)
+			}
+			*spans = append(*spans, span{"[image: " + alt + "]", layer(r.style.LinkURL, style)})
+		case *ast.RawHTML:
+			for i := 0; i < c.Segments.Len(); i++ {
+				segment := c.Segments.At(i)
+				*spans = append(*spans, span{string(segment.Value(r.src)), layer(r.style.HTML, style)})
+			}
+		case *wikiLink:
+			linkStyle := r.style.WikiLink
+			text := c.alias
+			if r.style.ResolveLink != nil {
+				shown, ok := r.style.ResolveLink(c.target)
+				if !ok {
+					linkStyle = r.style.BrokenLink
+				} else if text == "" {
+					text = shown
+				}
+			}
+			if text == "" {
+				text = c.target
+			}
+			if c == r.selected {
+				linkStyle = layer(r.style.SelectedLink, linkStyle)
+				text = selectMarker + text
+			}
+			*spans = append(*spans, span{text, layer(linkStyle, style)})
+		case *east.TaskCheckBox:
+			// Drawn in place of the list marker
+		default:
+			r.collect(c, style, spans)
+		}
+	}
+}
+
+// unescape resolves backslash escapes and character references
+func unescape(b []byte) []byte {
+	return util.ResolveEntityNames(util.ResolveNumericReferences(util.UnescapePunctuations(b)))
+}
+
+// plainText returns the text of spans without styling
+func plainText(spans []span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(s.text)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// piece is part of a word or space, with the index of the span it came
+// from so neighbouring pieces of one span can be drawn together
+type piece struct {
+	text  string
+	width int
+	span  int
+}
+
+// wrap lays spans out in lines no wider than width, breaking at spaces and
+// at hard line breaks. Words wider than a line are broken up.
+func wrap(spans []span, width int) []string {
+	var lines []string
+	var line []piece
+	lineWidth := 0
+
+	var word []piece
+	wordWidth := 0
+	space := -1 // span of the space before the word, -1 if none
+
+	emit := func() {
+		lines = append(lines, renderPieces(line, spans))
+		line = nil
+		lineWidth = 0
+	}
+
+	flushWord := func() {
+		if len(word) == 0 {
+			return
+		}
+		if lineWidth > 0 && lineWidth+1+wordWidth > width {
+			emit()
+		}
+		if lineWidth > 0 && space >= 0 {
+			line = append(line, piece{text: " ", width: 1, span: space})
+			lineWidth++
+		}
+		for _, p := range word {
+			for _, part := range breakWidth(p.text, width) {
+				partWidth := runewidth.StringWidth(part)
+				if lineWidth+partWidth > width && lineWidth > 0 {
+					emit()
+				}
+				line = append(line, piece{text: part, width: partWidth, span: p.span})
+				lineWidth += partWidth
+			}
+		}
+		word = nil
+		wordWidth = 0
+		space = -1
+	}
+
+	for i, s := range spans {
+		if s.text == "\n" {
+			flushWord()
+			emit()
+			continue
+		}
+		start := 0
+		runes := []rune(s.text)
+		for j := 0; j <= len(runes); j++ {
+			if j < len(runes) && !unicode.IsSpace(runes[j]) {
+				continue
+			}
+			if j > start {
+				text := string(runes[start:j])
+				word = append(word, piece{text: text, width: runewidth.StringWidth(text), span: i})
+				wordWidth += runewidth.StringWidth(text)
+			}
+			if j < len(runes) {
+				flushWord()
+				if lineWidth > 0 {
+					space = i
+				}
+			}
+			start = j + 1
+		}
+	}
+	flushWord()
+	if len(line) > 0 || len(lines) == 0 {
+		emit()
+	}
+	return lines
+}
+
+// renderPieces draws a line, styling each run of pieces from the same span
+// at once
+func renderPieces(line []piece, spans []span) string {
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		j := i
+		var text strings.Builder
+		for j < len(line) && line[j].span == line[i].span {
+			text.WriteString(line[j].text)
+			j++
+		}
+		b.WriteString(spans[line[i].span].style.Render(text.String()))
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,329 @@
+// Package markdown renders CommonMark, with the GitHub extensions for
+// tables, strikethrough, task lists and bare links, as styled terminal text
+// wrapped to a given width.
+package markdown
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/ssh-notes/terminal-notes/highlight"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Style is the look of each element. Inline styles are layered on top of
+// the style of the block they appear in, so a link in a heading keeps the
+// heading's colour unless Link sets its own.
+type Style struct {
+	Text     lipgloss.Style
+	Headings [6]lipgloss.Style
+	Bold     lipgloss.Style
+	Italic   lipgloss.Style
+	Strike   lipgloss.Style
+	Code     lipgloss.Style // inline code
+	Link     lipgloss.Style
+	LinkURL  lipgloss.Style // the destination shown after a link
+	Quote    lipgloss.Style // text in block quotes
+	Bullet   lipgloss.Style // list markers and task checkboxes
+
+	CodeBlock   lipgloss.Style
+	CodeLabel   lipgloss.Style // the language above a fenced code block
+	Rule        lipgloss.Style // thematic breaks and the bars of quotes and code blocks
+	TableBorder lipgloss.Style
+	TableHeader lipgloss.Style
+	HTML        lipgloss.Style // raw HTML, which is shown as is
+
+	// WikiLink styles [[links]] to other notes, and BrokenLink those
+	// ResolveLink finds no note for. SelectedLink marks the link picked
+	// with RenderWithLink.
+	WikiLink     lipgloss.Style
+	BrokenLink   lipgloss.Style
+	SelectedLink lipgloss.Style
+
+	// ResolveLink, if set, reports whether there is a note for a link
+	// target and returns the text to show for the link if it has no alias,
+	// such as the note's title in place of an ID
+	ResolveLink func(target string) (text string, ok bool)
+
+	// Syntax, if set, colours fenced code blocks in languages it has a
+	// lexer for
+	Syntax          *highlight.Palette
+	CodeLineNumbers bool
+	LineNumber      lipgloss.Style
+
+	// Banner, if set, draws top-level headings large, e.g. with FIGlet. It
+	// returns "" for headings it doesn't handle or that don't fit.
+	Banner func(level int, text string, width int) string
+}
+
+// bullets are the list markers at each level of nesting
+var bullets = []string{"•", "◦", "▪"}
+
+var md = goldmark.New(goldmark.WithExtensions(extension.GFM, wikiLinks{}))
+
+// selectMarker is put before the selected link while rendering, to find
+// the line it ends up on. It takes no space, so it doesn't upset wrapping.
+const selectMarker = "\u200b"
+
+// Render renders markdown source to lines no wider than width, where the
+// content allows it
+func Render(source string, width int, style Style) string {
+	rendered, _ := RenderWithLink(source, width, style, -1)
+	return rendered
+}
+
+// RenderWithLink renders like Render, drawing the wiki link at index link,
+// as counted by WikiLinks, in the SelectedLink style. It also returns the
+// line that link is on, or -1 if there is no such link.
+func RenderWithLink(source string, width int, style Style, link int) (string, int) {
+	if width < 10 {
+		width = 10
+	}
+	src := []byte(source)
+	doc := parse(src)
+
+	r := &renderer{src: src, style: style}
+	if links := findWikiLinks(doc); link >= 0 && link < len(links) {
+		r.selected = links[link]
+	}
+	lines := r.blocks(doc, width, blockContext{text: style.Text})
+
+	selectedLine := -1
+	for i, line := range lines {
+		if strings.Contains(line, selectMarker) {
+			selectedLine = i
+			lines[i] = strings.ReplaceAll(line, selectMarker, "")
+		}
+	}
+	return strings.Join(lines, "\n"), selectedLine
+}
+
+func parse(src []byte) ast.Node {
+	return md.Parser().Parse(text.NewReader(src))
+}
+
+type renderer struct {
+	src      []byte
+	style    Style
+	selected *wikiLink
+}
+
+// blockContext is what a block inherits from the blocks containing it
+type blockContext struct {
+	text  lipgloss.Style // base style of inline text
+	depth int            // list nesting
+	tight bool           // inside an item of a tight list
+}
+
+// blocks renders the children of a container one after another, with a
+// blank line between them unless the container is a tight list item
+func (r *renderer) blocks(container ast.Node, width int, ctx blockContext) []string {
+	var lines []string
+	for child := container.FirstChild(); child != nil; child = child.NextSibling() {
+		rendered := r.block(child, width, ctx)
+		if rendered == nil {
+			continue
+		}
+		if len(lines) > 0 && !ctx.tight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, rendered...)
+	}
+	return lines
+}
+
+func (r *renderer) block(n ast.Node, width int, ctx blockContext) []string {
+	switch n := n.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		return wrap(r.inline(n, ctx.text), width)
+	case *ast.Heading:
+		return r.heading(n, width, ctx)
+	case *ast.ThematicBreak:
+		return []string{r.style.Rule.Render(strings.Repeat("─", width))}
+	case *ast.FencedCodeBlock:
+		return r.codeBlock(string(n.Language(r.src)), r.lines(n), width)
+	case *ast.CodeBlock:
+		return r.codeBlock("", r.lines(n), width)
+	case *ast.Blockquote:
+		return r.blockquote(n, width, ctx)
+	case *ast.List:
+		return r.list(n, width, ctx)
+	case *east.Table:
+		return r.table(n, width, ctx)
+	case *ast.HTMLBlock:
+		lines := r.lines(n)
+		if n.HasClosure() {
+			lines = append(lines, strings.TrimRight(string(n.ClosureLine.Value(r.src)), "\n"))
+		}
+		for i, line := range lines {
+			lines[i] = r.style.HTML.Render(line)
+		}
+		return lines
+	}
+	return r.blocks(n, width, ctx)
+}
+
+// lines returns the source lines of a block without their line endings
+func (r *renderer) lines(n ast.Node) []string {
+	var lines []string
+	segments := n.Lines()
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		lines = append(lines, strings.TrimRight(string(segment.Value(r.src)), "\r\n"))
+	}
+	return lines
+}
+
+func (r *renderer) heading(n *ast.Heading, width int, ctx blockContext) []string {
+	level := n.Level
+	if level > len(r.style.Headings) {
+		level = len(r.style.Headings)
+	}
+	style := r.style.Headings[level-1]
+
+	// A banner would hide which link is selected
+	if title := plainText(r.inline(n, style)); r.style.Banner != nil && !strings.Contains(title, selectMarker) {
+		if banner := r.style.Banner(n.Level, title, width); banner != "" {
+			var lines []string
+			for _, line := range strings.Split(strings.TrimRight(banner, "\n"), "\n") {
+				lines = append(lines, style.Render(line))
+			}
+			return lines
+		}
+	}
+
+	lines := wrap(r.inline(n, layer(style, ctx.text)), width)
+	// Underline the two top levels so they stand out without a banner
+	underline := map[int]string{1: "═", 2: "─"}[n.Level]
+	if underline != "" && len(lines) > 0 {
+		textWidth := 0
+		for _, line := range lines {
+			if w := lipgloss.Width(line); w > textWidth {
+				textWidth = w
+			}
+		}
+		lines = append(lines, style.Render(strings.Repeat(underline, textWidth)))
+	}
+	return lines
+}
+
+func (r *renderer) blockquote(n *ast.Blockquote, width int, ctx blockContext) []string {
+	inner := ctx
+	inner.text = layer(r.style.Quote, ctx.text)
+	inner.tight = false
+
+	lines := r.blocks(n, childWidth(width, 2), inner)
+	bar := r.style.Rule.Render("│")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = bar
+		} else {
+			lines[i] = bar + " " + line
+		}
+	}
+	return lines
+}
+
+func (r *renderer) list(n *ast.List, width int, ctx blockContext) []string {
+	count := 0
+	for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+		count++
+	}
+
+	// Numbers are right-aligned so the items line up
+	markerWidth := 1
+	if n.IsOrdered() {
+		markerWidth = len(fmt.Sprintf("%d%c", n.Start+count-1, n.Marker))
+	}
+
+	inner := ctx
+	inner.depth++
+	inner.tight = n.IsTight
+
+	var lines []string
+	number := n.Start
+	for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := bullets[ctx.depth%len(bullets)]
+		if n.IsOrdered() {
+			marker = fmt.Sprintf("%*s", markerWidth, fmt.Sprintf("%d%c", number, n.Marker))
+		}
+		if box := taskCheckBox(item); box != nil {
+			marker = "☐"
+			if box.IsChecked {
+				marker = "☑"
+			}
+		}
+		number++
+
+		indent := markerWidth + 1
+		body := r.blocks(item, childWidth(width, indent), inner)
+		if len(body) == 0 {
+			body = []string{""}
+		}
+		if len(lines) > 0 && !n.IsTight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, r.style.Bullet.Render(marker)+" "+body[0])
+		for _, line := range body[1:] {
+			if line == "" {
+				lines = append(lines, "")
+			} else {
+				lines = append(lines, strings.Repeat(" ", indent)+line)
+			}
+		}
+	}
+	return lines
+}
+
+// childWidth is the width left for the contents of a block after used
+// columns of bars, markers or borders. Deep nesting can use up the whole
+// width, so it never drops below one column.
+func childWidth(width, used int) int {
+	if width-used < 1 {
+		return 1
+	}
+	return width - used
+}
+
+// taskCheckBox returns the checkbox that starts a task list item, if any
+func taskCheckBox(item ast.Node) *east.TaskCheckBox {
+	first := item.FirstChild()
+	if first == nil {
+		return nil
+	}
+	box, _ := first.FirstChild().(*east.TaskCheckBox)
+	return box
+}
+
+// breakWidth splits s into pieces no wider than width
+func breakWidth(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var pieces []string
+	var b strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := runewidth.RuneWidth(r)
+		if w+rw > width && w > 0 {
+			pieces = append(pieces, b.String())
+			b.Reset()
+			w = 0
+		}
+		b.WriteRune(r)
+		w += rw
+	}
+	return append(pieces, b.String())
+}
+
+// layer returns top with the properties of base it doesn't set itself.
+// Inheriting alone would change top too, as both share its rules.
+func layer(top, base lipgloss.Style) lipgloss.Style {
+	return top.Copy().Inherit(base)
+}
@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDeepNestingAtSmallWidths(t *testing.T) {
+	table := "| a | b | c | d |\n|---|---|---|---|\n| 1 | 2 | 3 | 4 |"
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"quoted rule", "> > > > > > > > ---"},
+		{"quoted code", "> > > > > > > > ```go\n> > > > > > > > func main() {}\n> > > > > > > > ```"},
+		{"quoted table", "> > > > > > > > " + strings.ReplaceAll(table, "\n", "\n> > > > > > > > ")},
+		{"ordered lists", nestedList("1. ", 12, "---")},
+		{"bullet lists", nestedList("- ", 12, "```\ncode\n```")},
+		{"lists in quotes", "> > > " + nestedList("10. ", 6, "text")},
+	}
+
+	style := Style{CodeLineNumbers: true}
+	for _, tt := range tests {
+		for _, width := range []int{0, 1, 5, 10, 12} {
+			t.Run(tt.name, func(t *testing.T) {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("width %d: panicked: %v", width, r)
+					}
+				}()
+				if Render(tt.source, width, style) == "" {
+					t.Errorf("width %d: rendered nothing", width)
+				}
+			})
+		}
+	}
+}
+
+// nestedList builds a list nested depth levels deep, with last in the
+// innermost item
+func nestedList(marker string, depth int, last string) string {
+	var b strings.Builder
+	indent := ""
+	for i := 0; i < depth; i++ {
+		b.WriteString(indent + marker + "item\n\n")
+		indent += strings.Repeat(" ", len(marker))
+	}
+	for _, line := range strings.Split(last, "\n") {
+		b.WriteString(indent + line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// minColumnWidth is the narrowest a column is squeezed to when a table is
+// wider than the page
+const minColumnWidth = 3
+
+// table renders a table with box-drawing borders. Columns are as wide as
+// their content; when that doesn't fit, the widest are narrowed and their
+// cells wrapped.
+func (r *renderer) table(n *east.Table, width int, ctx blockContext) []string {
+	var rows [][][]span
+	header := 0
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		style := ctx.text
+		if _, ok := row.(*east.TableHeader); ok {
+			style = layer(r.style.TableHeader, ctx.text)
+			header++
+		}
+		var cells [][]span
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, r.inline(cell, style))
+		}
+		rows = append(rows, cells)
+	}
+
+	columns := len(n.Alignments)
+	if columns == 0 {
+		return nil
+	}
+
+	widths := make([]int, columns)
+	for _, cells := range rows {
+		for i, cell := range cells {
+			if i < columns {
+				if w := lipgloss.Width(plainText(cell)); w > widths[i] {
+					widths[i] = w
+				}
+			}
+		}
+	}
+	for i := range widths {
+		if widths[i] < 1 {
+			widths[i] = 1
+		}
+	}
+
+	// Each column takes its width plus a space either side and a border
+	available := childWidth(width, 1+3*columns)
+	for total(widths) > available {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+	}
+
+	border := func(left, middle, right string) string {
+		parts := make([]string, columns)
+		for i, w := range widths {
+			parts[i] = strings.Repeat("─", w+2)
+		}
+		return r.style.TableBorder.Render(left + strings.Join(parts, middle) + right)
+	}
+
+	lines := []string{border("┌", "┬", "┐")}
+	bar := r.style.TableBorder.Render("│")
+	for i, cells := range rows {
+		if i == header && header > 0 {
+			lines = append(lines, border("├", "┼", "┤"))
+		}
+
+		wrapped := make([][]string, columns)
+		height := 1
+		for c := 0; c < columns; c++ {
+			var cell []span
+			if c < len(cells) {
+				cell = cells[c]
+			}
+			wrapped[c] = wrap(cell, widths[c])
+			if len(wrapped[c]) > height {
+				height = len(wrapped[c])
+			}
+		}
+
+		for l := 0; l < height; l++ {
+			var b strings.Builder
+			b.WriteString(bar)
+			for c := 0; c < columns; c++ {
+				text := ""
+				if l < len(wrapped[c]) {
+					text = wrapped[c][l]
+				}
+				b.WriteString(" " + align(text, widths[c], n.Alignments[c]) + " " + bar)
+			}
+			lines = append(lines, b.String())
+		}
+	}
+	return append(lines, border("└", "┴", "┘"))
+}
+
+func total(widths []int) int {
+	sum := 0
+	for _, w := range widths {
+		sum += w
+	}
+	return sum
+}
+
+// align pads rendered text to width
+func align(text string, width int, alignment east.Alignment) string {
+	gap := width - lipgloss.Width(text)
+	if gap <= 0 {
+		return text
+	}
+	switch alignment {
+	case east.AlignRight:
+		return strings.Repeat(" ", gap) + text
+	case east.AlignCenter:
+		return strings.Repeat(" ", gap/2) + text + strings.Repeat(" ", gap-gap/2)
+	}
+	return text + strings.Repeat(" ", gap)
+}
@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// wikiLink is a [[link]] to another note by its ID or title, optionally
+// shown as an alias: [[target|alias]]. Written ![[target]], it embeds the
+// note instead; see Embeds.
+type wikiLink struct {
+	ast.BaseInline
+	target string
+	alias  string
+	embed  bool
+	start  int // offsets of the link in the source
+	end    int
+}
+
+var kindWikiLink = ast.NewNodeKind("WikiLink")
+
+func (n *wikiLink) Kind() ast.NodeKind {
+	return kindWikiLink
+}
+
+func (n *wikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Target": n.target, "Alias": n.alias, "Embed": fmt.Sprint(n.embed)}, nil)
+}
+
+// wikiLinkPattern matches a link or embed at the start of the input, the
+// same way the store finds the links of a note
+var wikiLinkPattern = regexp.MustCompile(`^(!?)\[\[([^\]]+)\]\]`)
+
+// wikiLinkParser parses [[links]]. It runs before the standard link
+// parser, which would otherwise take the brackets for a link label.
+type wikiLinkParser struct{}
+
+func (wikiLinkParser) Trigger() []byte {
+	return []byte{'[', '!'}
+}
+
+func (wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	match := wikiLinkPattern.FindSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	target, alias, _ := strings.Cut(string(match[2]), "|")
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil
+	}
+	block.Advance(len(match[0]))
+	return &wikiLink{
+		target: target,
+		alias:  strings.TrimSpace(alias),
+		embed:  len(match[1]) > 0,
+		start:  segment.Start,
+		end:    segment.Start + len(match[0]),
+	}
+}
+
+type wikiLinks struct{}
+
+func (wikiLinks) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(wikiLinkParser{}, 199)))
+}
+
+// WikiLinks returns the targets of the [[links]] in source, in the order
+// they appear. Links in code are not counted.
+func WikiLinks(source string) []string {
+	var targets []string
+	for _, link := range findWikiLinks(parse([]byte(source))) {
+		targets = append(targets, link.target)
+	}
+	return targets
+}
+
+// findWikiLinks returns the links in a document in order
+func findWikiLinks(doc ast.Node) []*wikiLink {
+	var links []*wikiLink
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := n.(*wikiLink); ok && entering {
+			links = append(links, link)
+		}
+		return ast.WalkContinue, nil
+	})
+	return links
+}
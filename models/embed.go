@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ssh-notes/terminal-notes/markdown"
+)
+
+// maxEmbedDepth is how deeply embedded notes may embed others in turn
+const maxEmbedDepth = 4
+
+// previewMarkdown returns the text of the current note as the preview
+// shows it, with its ![[embeds]] expanded. The embeds are resolved once per
+// note and text, until the preview is opened again or the notes reloaded,
+// so moving the link selection doesn't resolve them all over again.
+func (m *MainModel) previewMarkdown() string {
+	key := m.currentNote.Path + "\x00" + m.currentNote.Content
+	if m.embedsFor != key {
+		m.embedsText = m.expandEmbeds(m.currentNote.Content, []string{m.currentNote.Path})
+		m.embedsFor = key
+	}
+	return m.embedsText
+}
+
+// expandEmbeds replaces the ![[embeds]] in md with the text of the notes,
+// or sections of notes, they name. chain holds the paths of the note
+// being shown and the notes embedded on the way to md, to catch loops.
+func (m *MainModel) expandEmbeds(md string, chain []string) string {
+	embeds := markdown.Embeds(md)
+	if len(embeds) == 0 {
+		return md
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, embed := range embeds {
+		b.WriteString(md[last:embed.Start])
+		b.WriteString(m.embedText(md[embed.Start:embed.End], embed, chain))
+		last = embed.End
+	}
+	b.WriteString(md[last:])
+	return b.String()
+}
+
+// embedText returns what an embed stands for. Embeds of missing notes are
+// left as they are, to show as broken links.
+func (m *MainModel) embedText(source string, embed markdown.Embed, chain []string) string {
+	note := m.ResolveLink(embed.Target)
+	if note == nil {
+		return source
+	}
+	link := strings.TrimPrefix(source, "!")
+
+	for _, path := range chain {
+		if path == note.Path {
+			return fmt.Sprintf("*%s is already embedded here*", link)
+		}
+	}
+	if len(chain) > maxEmbedDepth {
+		return fmt.Sprintf("*%s is nested too deeply to embed*", link)
+	}
+
+	text := note.Content
+	if embed.Heading != "" {
+		section, ok := markdown.Section(text, embed.Heading)
+		if !ok {
+			return fmt.Sprintf("*%s: no such heading*", link)
+		}
+		text = section
+	}
+	text = strings.TrimSpace(m.expandEmbeds(text, append(chain[:len(chain):len(chain)], note.Path)))
+
+	// An embed on a line of its own stands for whole blocks, which need
+	// blank lines around them
+	if embed.Alone {
+		return "\n" + text + "\n"
+	}
+	return text
+}
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openFind shows the find bar in the editor, with the replace field too
+// when replacing is set. The last query is kept between uses.
+func (m *MainModel) openFind(replacing bool) (tea.Model, tea.Cmd) {
+	if !m.findActive {
+		m.findInput.Prompt = "Find: "
+		m.findInput.Placeholder = "text to find"
+		m.replaceInput.Prompt = "Replace: "
+		m.replaceInput.Placeholder = "replacement"
+		m.findAnchor = m.editor.CursorPos()
+	}
+	m.findActive = true
+	m.findReplacing = m.findReplacing || replacing
+	m.findStatus = ""
+
+	if replacing {
+		m.focusFindField(1)
+	} else {
+		m.focusFindField(0)
+	}
+	m.updateFind()
+	return m, textinput.Blink
+}
+
+// closeFind hides the find bar, leaving the cursor on the current match
+func (m *MainModel) closeFind() {
+	m.findActive = false
+	m.findReplacing = false
+	m.findMatches = nil
+	m.findInput.Blur()
+	m.replaceInput.Blur()
+	m.editor.ClearHighlights()
+}
+
+// focusFindField moves the focus to the find (0) or replace (1) field
+func (m *MainModel) focusFindField(field int) {
+	m.findFocus = field
+	if field == 1 {
+		m.findInput.Blur()
+		m.replaceInput.Focus()
+	} else {
+		m.replaceInput.Blur()
+		m.findInput.Focus()
+	}
+}
+
+// updateFind re-runs the query, selecting the first match at or after the
+// anchor so the selection stays put while the query is refined
+func (m *MainModel) updateFind() {
+	m.findMatches = nil
+	m.findCurrent = -1
+	m.findError = ""
+
+	matches, err := m.editor.FindAll(m.findInput.Value(), m.findOptions)
+	if err != nil {
+		m.findError = strings.TrimPrefix(err.Error(), "error parsing regexp: ")
+		m.editor.ClearHighlights()
+		return
+	}
+	m.findMatches = matches
+
+	if len(matches) > 0 {
+		m.findCurrent = 0
+		for i, match := range matches {
+			if !match.From.Before(m.findAnchor) {
+				m.findCurrent = i
+				break
+			}
+		}
+		current := matches[m.findCurrent].From
+		m.editor.SetCursor(current.Row, current.Col)
+	}
+	m.editor.SetHighlights(matches, m.findCurrent)
+}
+
+// findStep moves to the next (1) or previous (-1) match, wrapping around
+func (m *MainModel) findStep(delta int) {
+	if len(m.findMatches) == 0 {
+		return
+	}
+	m.findCurrent = (m.findCurrent + delta + len(m.findMatches)) % len(m.findMatches)
+	current := m.findMatches[m.findCurrent].From
+	m.findAnchor = current
+	m.editor.SetCursor(current.Row, current.Col)
+	m.editor.SetHighlights(m.findMatches, m.findCurrent)
+}
+
+// replaceCurrent replaces the selected match and moves on to the next one.
+// Replacements only edit the buffer, so they undo like typing and are
+// saved along with the rest of the note's edits.
+func (m *MainModel) replaceCurrent() {
+	if m.findCurrent < 0 || m.findCurrent >= len(m.findMatches) {
+		return
+	}
+
+	ok, err := m.editor.ReplaceMatch(m.findMatches[m.findCurrent],
+		m.findInput.Value(), m.replaceInput.Value(), m.findOptions)
+	if err != nil {
+		m.findError = err.Error()
+		return
+	}
+	if ok {
+		m.editorText = m.editor.Value()
+	}
+
+	// Carry on after the replacement rather than matching inside it
+	m.findAnchor = m.editor.CursorPos()
+	m.updateFind()
+}
+
+// replaceAllMatches replaces every match in one undoable step
+func (m *MainModel) replaceAllMatches() {
+	count, err := m.editor.ReplaceAll(m.findInput.Value(), m.replaceInput.Value(), m.findOptions)
+	if err != nil {
+		m.findError = err.Error()
+		return
+	}
+	if count > 0 {
+		m.editorText = m.editor.Value()
+	}
+
+	if count == 1 {
+		m.findStatus = "Replaced 1 match"
+	} else {
+		m.findStatus = fmt.Sprintf("Replaced %d matches", count)
+	}
+	m.findAnchor = m.editor.CursorPos()
+	m.updateFind()
+}
+
+func (m *MainModel) handleFindKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.findStatus = ""
+
+	switch msg.String() {
+	case "esc":
+		m.closeFind()
+		return m, nil
+	case "enter":
+		if m.findFocus == 1 {
+			m.replaceCurrent()
+		} else {
+			m.findStep(1)
+		}
+		return m, nil
+	case "down":
+		m.findStep(1)
+		return m, nil
+	case "up":
+		m.findStep(-1)
+		return m, nil
+	case "tab", "shift+tab":
+		if m.findReplacing {
+			m.focusFindField(1 - m.findFocus)
+		}
+		return m, nil
+	case "ctrl+f":
+		m.focusFindField(0)
+		return m, nil
+	case "ctrl+r":
+		m.findReplacing = true
+		m.focusFindField(1)
+		return m, nil
+	case "alt+c":
+		m.findOptions.CaseSensitive = !m.findOptions.CaseSensitive
+		m.updateFind()
+		return m, nil
+	case "alt+r":
+		m.findOptions.Regex = !m.findOptions.Regex
+		m.updateFind()
+		return m, nil
+	case "alt+a":
+		if m.findReplacing {
+			m.replaceAllMatches()
+		}
+		return m, nil
+	case "ctrl+z", "ctrl+y":
+		// Take back a replacement without leaving the bar
+		if msg.String() == "ctrl+z" {
+			m.editor.Undo()
+		} else {
+			m.editor.Redo()
+		}
+		m.editorText = m.editor.Value()
+		m.findAnchor = m.editor.CursorPos()
+		m.updateFind()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	if m.findFocus == 1 {
+		m.replaceInput, cmd = m.replaceInput.Update(msg)
+		return m, cmd
+	}
+
+	before := m.findInput.Value()
+	m.findInput, cmd = m.findInput.Update(msg)
+	if m.findInput.Value() != before {
+		m.updateFind()
+	}
+	return m, cmd
+}
+
+// renderFindBar renders the find bar below the editor
+func (m *MainModel) renderFindBar() string {
+	theme := Themes[m.currentTheme]
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	on := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Accent))
+
+	toggle := func(label string, enabled bool) string {
+		if enabled {
+			return on.Render("[" + label + "]")
+		}
+		return dim.Render("[" + label + "]")
+	}
+
+	var count string
+	switch {
+	case m.findError != "":
+		count = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(m.findError)
+	case m.findInput.Value() == "":
+		count = ""
+	case len(m.findMatches) == 0:
+		count = dim.Render("No matches")
+	default:
+		count = dim.Render(fmt.Sprintf("%d of %d", m.findCurrent+1, len(m.findMatches)))
+	}
+
+	var s strings.Builder
+	s.WriteString(m.findInput.View() + "  " + count + "  " +
+		toggle("Aa", m.findOptions.CaseSensitive) + " " + toggle(".*", m.findOptions.Regex))
+	if m.findReplacing {
+		s.WriteString("\n" + m.replaceInput.View())
+		if m.findStatus != "" {
+			s.WriteString("  " + dim.Render(m.findStatus))
+		}
+	}
+	return s.String()
+}
+
+// findBarHeight is the number of lines renderFindBar takes
+func (m *MainModel) findBarHeight() int {
+	switch {
+	case !m.findActive:
+		return 0
+	case m.findReplacing:
+		return 2
+	}
+	return 1
+}
+
+// findHelp is the help line while the find bar is open
+func (m *MainModel) findHelp() string {
+	if m.findReplacing {
+		return "Enter: Next/Replace | ↑/↓: Prev/Next | Alt+A: Replace all | Tab: Switch field | Alt+C: Case | Alt+R: Regex | Esc: Close"
+	}
+	return "Enter/↓: Next | ↑: Previous | Ctrl+R: Replace | Alt+C: Case | Alt+R: Regex | Esc: Close"
+}
+
@@ -0,0 +1,426 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/utils"
+)
+
+// loadTree reads every folder and note in one pass into m.folderItems
+func (m *MainModel) loadTree() error {
+	entries, err := m.store.ListAll("")
+	if err != nil {
+		return err
+	}
+
+	m.folderItems = map[string][]NoteItem{"": {}}
+	for _, entry := range entries {
+		folder := parentFolder(entry.Path)
+		m.folderItems[folder] = append(m.folderItems[folder], NoteItem{
+			title:     entry.Title,
+			path:      entry.Path,
+			isFolder:  entry.IsFolder,
+			tags:      entry.Tags,
+			todos:     entry.Todos,
+			doneTodos: entry.DoneTodos,
+			depth:     strings.Count(entry.Path, "/"),
+		})
+	}
+	return nil
+}
+
+// visibleItems flattens the tree under folder into sidebar rows, leaving
+// out the contents of collapsed folders
+func (m *MainModel) visibleItems(folder string) []NoteItem {
+	var items []NoteItem
+	for _, item := range m.folderItems[folder] {
+		items = append(items, item)
+		if item.isFolder && m.sidebarExpanded[item.path] {
+			items = append(items, m.visibleItems(item.path)...)
+		}
+	}
+	return items
+}
+
+// allItems flattens the whole tree under folder, collapsed or not
+func (m *MainModel) allItems(folder string) []NoteItem {
+	var items []NoteItem
+	for _, item := range m.folderItems[folder] {
+		items = append(items, item)
+		if item.isFolder {
+			items = append(items, m.allItems(item.path)...)
+		}
+	}
+	return items
+}
+
+// sidebarNotes returns the rows the sidebar shows
+func (m *MainModel) sidebarNotes() []NoteItem {
+	if m.showFiltered && len(m.filteredNotes) > 0 {
+		return m.filteredNotes
+	}
+	return m.notes
+}
+
+// selectedItem returns the sidebar row under the cursor
+func (m *MainModel) selectedItem() (NoteItem, bool) {
+	notes := m.sidebarNotes()
+	if m.sidebarCursor < len(notes) {
+		return notes[m.sidebarCursor], true
+	}
+	return NoteItem{}, false
+}
+
+// currentFolder is where new notes and folders go: the selected folder,
+// or the folder of the selected note
+func (m *MainModel) currentFolder() string {
+	item, ok := m.selectedItem()
+	if !ok {
+		return ""
+	}
+	if item.isFolder {
+		return item.path
+	}
+	return parentFolder(item.path)
+}
+
+// parentFolder returns the folder holding p, "" for the top level
+func parentFolder(p string) string {
+	if dir := path.Dir(p); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// inFolder reports whether p is folder or lies somewhere under it
+func inFolder(p, folder string) bool {
+	return p == folder || strings.HasPrefix(p, folder+"/")
+}
+
+// toggleFolder expands or collapses a folder in the sidebar
+func (m *MainModel) toggleFolder(folder string) {
+	m.sidebarExpanded[folder] = !m.sidebarExpanded[folder]
+	m.notes = m.visibleItems("")
+}
+
+// expandFolder opens the folder and the folders above it, so what's in it
+// shows in the sidebar
+func (m *MainModel) expandFolder(folder string) {
+	for ; folder != ""; folder = parentFolder(folder) {
+		m.sidebarExpanded[folder] = true
+	}
+	m.notes = m.visibleItems("")
+}
+
+// expandOrEnter expands the selected folder, or steps into it when it is
+// already expanded
+func (m *MainModel) expandOrEnter() {
+	item, ok := m.selectedItem()
+	if !ok || !item.isFolder {
+		return
+	}
+	if !m.sidebarExpanded[item.path] {
+		m.toggleFolder(item.path)
+		return
+	}
+	notes := m.sidebarNotes()
+	if next := m.sidebarCursor + 1; next < len(notes) && parentFolder(notes[next].path) == item.path {
+		m.sidebarCursor = next
+		m.selectNote()
+	}
+}
+
+// collapseOrLeave collapses the selected folder, or steps out to the
+// folder holding the selected row
+func (m *MainModel) collapseOrLeave() {
+	item, ok := m.selectedItem()
+	if !ok {
+		return
+	}
+	if item.isFolder && m.sidebarExpanded[item.path] {
+		m.toggleFolder(item.path)
+		return
+	}
+	if parent := parentFolder(item.path); parent != "" {
+		m.revealInSidebar(parent)
+	}
+}
+
+// openFolderName asks for the name of a new folder, or a new name for the
+// folder at renaming
+func (m *MainModel) openFolderName(renaming string) (tea.Model, tea.Cmd) {
+	m.folderRenaming = renaming
+	m.folderParent = m.currentFolder()
+	m.folderError = ""
+	m.folderInput.SetValue("")
+	if renaming != "" {
+		m.folderParent = parentFolder(renaming)
+		m.folderInput.SetValue(path.Base(renaming))
+	}
+	m.folderInput.CursorEnd()
+	m.folderInput.Focus()
+	m.currentView = "folder_name"
+	return m, textinput.Blink
+}
+
+// saveFolderName creates or renames the folder, reporting false with
+// m.folderError set when the name can't be used
+func (m *MainModel) saveFolderName() bool {
+	name := strings.TrimSpace(m.folderInput.Value())
+	if err := utils.ValidateFolderName(name); err != nil {
+		m.folderError = err.Error()
+		return false
+	}
+	folder := path.Join(m.folderParent, name)
+
+	if m.folderRenaming != "" {
+		if folder == m.folderRenaming {
+			return true
+		}
+		if err := m.store.Move(m.folderRenaming, folder); err != nil {
+			m.folderError = err.Error()
+			return false
+		}
+		m.pathMoved(m.folderRenaming, folder)
+		logger.LogRequest(m.username, "rename_folder", nil)
+	} else {
+		if err := m.store.CreateFolder(folder); err != nil {
+			m.folderError = err.Error()
+			return false
+		}
+		logger.LogRequest(m.username, "create_folder", nil)
+	}
+
+	m.loadNotes()
+	m.expandFolder(m.folderParent)
+	m.revealInSidebar(folder)
+	return true
+}
+
+// pathMoved carries what the session keeps by path over to where a note,
+// or a folder of notes, has moved
+func (m *MainModel) pathMoved(from, to string) {
+	moved := func(p string) string {
+		if inFolder(p, from) {
+			return to + strings.TrimPrefix(p, from)
+		}
+		return p
+	}
+
+	if m.currentNote != nil {
+		m.currentNote.Path = moved(m.currentNote.Path)
+	}
+	m.editorPath = moved(m.editorPath)
+	for _, paths := range [][]string{m.navBack, m.navForward} {
+		for i := range paths {
+			paths[i] = moved(paths[i])
+		}
+	}
+	for p, history := range m.histories {
+		if to := moved(p); to != p {
+			delete(m.histories, p)
+			m.histories[to] = history
+		}
+	}
+	for p, banners := range m.bannerCache {
+		if to := moved(p); to != p {
+			delete(m.bannerCache, p)
+			m.bannerCache[to] = banners
+		}
+	}
+	for p, expanded := range m.sidebarExpanded {
+		if to := moved(p); to != p {
+			delete(m.sidebarExpanded, p)
+			m.sidebarExpanded[to] = expanded
+		}
+	}
+}
+
+func (m *MainModel) handleFolderNameKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+	case "enter":
+		if m.saveFolderName() {
+			m.currentView = "main"
+		}
+		return m, nil
+	case "esc":
+		m.currentView = "main"
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.folderInput, cmd = m.folderInput.Update(msg)
+	m.folderError = ""
+	return m, cmd
+}
+
+func (m *MainModel) renderFolderName() string {
+	styles := m.getStyles()
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var s strings.Builder
+	if m.folderRenaming != "" {
+		s.WriteString(styles["title"].Render("Rename Folder") + "\n")
+	} else {
+		s.WriteString(styles["title"].Render("New Folder") + "\n")
+	}
+	s.WriteString(dim.Render("In "+folderLabel(m.folderParent)) + "\n\n")
+	s.WriteString(m.folderInput.View() + "\n")
+	if m.folderError != "" {
+		s.WriteString("\n" + errStyle.Render(m.folderError) + "\n")
+	}
+	s.WriteString("\n" + dim.Render("Enter: Save | Esc: Cancel"))
+	return s.String()
+}
+
+// folderLabel names a folder for display, "/" being the top level
+func folderLabel(folder string) string {
+	return "/" + folder
+}
+
+// folderNoteCount counts the notes anywhere under folder
+func (m *MainModel) folderNoteCount(folder string) int {
+	n := 0
+	for _, item := range m.allItems(folder) {
+		if !item.isFolder {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *MainModel) handleFolderDeleteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	folder := m.folderDelete
+	m.folderDelete = ""
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+	case "y", "Y":
+		if err := m.store.Delete(folder); err != nil {
+			logger.Error("Failed to delete folder: %v", err)
+			return m, nil
+		}
+		logger.LogRequest(m.username, "delete_folder", nil)
+		if m.currentNote != nil && inFolder(m.currentNote.Path, folder) {
+			m.currentNote = nil
+		}
+		m.loadNotes()
+		if n := len(m.sidebarNotes()); m.sidebarCursor >= n && n > 0 {
+			m.sidebarCursor = n - 1
+		}
+	}
+	return m, nil
+}
+
+// openMovePicker lists the folders the selected note or folder can move to
+func (m *MainModel) openMovePicker() (tea.Model, tea.Cmd) {
+	item, ok := m.selectedItem()
+	if !ok {
+		return m, nil
+	}
+
+	m.moveFrom = item
+	m.moveTargets = []string{""}
+	for _, other := range m.allItems("") {
+		// A folder can't go inside itself
+		if other.isFolder && !inFolder(other.path, item.path) {
+			m.moveTargets = append(m.moveTargets, other.path)
+		}
+	}
+	sort.Strings(m.moveTargets[1:])
+
+	m.moveCursor = 0
+	for i, folder := range m.moveTargets {
+		if folder == parentFolder(item.path) {
+			m.moveCursor = i
+		}
+	}
+	m.moveError = ""
+	m.currentView = "move"
+	return m, nil
+}
+
+// moveTo moves the note or folder being moved into folder, keeping its name
+func (m *MainModel) moveTo(folder string) bool {
+	from := m.moveFrom.path
+	to := path.Join(folder, path.Base(from))
+	if to == from {
+		return true
+	}
+	if err := m.store.Move(from, to); err != nil {
+		m.moveError = err.Error()
+		return false
+	}
+	logger.LogRequest(m.username, "move_note", nil)
+
+	m.pathMoved(from, to)
+	m.loadNotes()
+	m.expandFolder(folder)
+	m.revealInSidebar(to)
+	return true
+}
+
+func (m *MainModel) handleMoveKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+	case "esc", "q":
+		m.currentView = "main"
+	case "up", "k":
+		if m.moveCursor > 0 {
+			m.moveCursor--
+		}
+	case "down", "j":
+		if m.moveCursor < len(m.moveTargets)-1 {
+			m.moveCursor++
+		}
+	case "enter":
+		if m.moveCursor < len(m.moveTargets) && m.moveTo(m.moveTargets[m.moveCursor]) {
+			m.currentView = "main"
+		}
+	}
+	return m, nil
+}
+
+func (m *MainModel) renderMovePicker() string {
+	styles := m.getStyles()
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var s strings.Builder
+	s.WriteString(styles["title"].Render(fmt.Sprintf("Move %s to", m.moveFrom.title)) + "\n")
+	s.WriteString(dim.Render("Currently in "+folderLabel(parentFolder(m.moveFrom.path))) + "\n\n")
+	if m.moveError != "" {
+		s.WriteString(errStyle.Render(m.moveError) + "\n\n")
+	}
+
+	height := m.height - 6
+	if height < 3 {
+		height = 3
+	}
+	first := 0
+	if m.moveCursor >= height {
+		first = m.moveCursor - height + 1
+	}
+	for i := first; i < len(m.moveTargets) && i < first+height; i++ {
+		line := truncateToWidth(folderLabel(m.moveTargets[i]), m.width-4)
+		if i == m.moveCursor {
+			s.WriteString(styles["selected"].Render("▶ "+line) + "\n")
+		} else {
+			s.WriteString("  " + line + "\n")
+		}
+	}
+
+	s.WriteString("\n" + dim.Render("↑/↓: Navigate | Enter: Move here | Esc: Cancel"))
+	return s.String()
+}
@@ -0,0 +1,329 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/store"
+)
+
+// Depths the graph view can show around a note
+const (
+	minGraphDepth = 1
+	maxGraphDepth = 6
+)
+
+// graphRow is a line of the graph view: a note, or a heading in the
+// clusters list
+type graphRow struct {
+	path    string
+	prefix  string // tree lines leading to the note
+	arrow   string // → the parent links to the note, ← the note links to the parent, ↔ both
+	more    int    // links beyond the depth shown
+	heading string
+}
+
+// openGraph shows the links around the current note, or the clusters
+// when there is no current note
+func (m *MainModel) openGraph() (tea.Model, tea.Cmd) {
+	m.graphBack = nil
+	m.graphRoot = ""
+	m.graphClusters = m.currentNote == nil
+	if m.currentNote != nil {
+		m.graphRoot = m.currentNote.Path
+	}
+	m.loadGraph()
+	m.currentView = "graph"
+	return m, nil
+}
+
+// loadGraph reads the links of every note afresh
+func (m *MainModel) loadGraph() {
+	m.graphError = ""
+	metas, err := m.store.Metadata()
+	if err != nil {
+		m.graphError = err.Error()
+	}
+	m.graph = store.BuildGraph(metas)
+	m.buildGraphRows()
+}
+
+// buildGraphRows lays out the tree or the clusters, selecting the note
+// the tree is centered on
+func (m *MainModel) buildGraphRows() {
+	if m.graphClusters {
+		m.graphRows = clusterRows(m.graph)
+	} else {
+		m.graphRows = graphTree(m.graph, m.graphRoot, m.graphDepth)
+	}
+
+	m.graphCursor = 0
+	for i, row := range m.graphRows {
+		if row.path == m.graphRoot {
+			m.graphCursor = i
+			return
+		}
+	}
+	m.moveGraphCursor(1)
+}
+
+// graphTree lays out the notes within depth links of root, in either
+// direction, as a tree. A note reachable several ways is shown once, at
+// its shortest distance from root.
+func graphTree(g *store.Graph, root string, depth int) []graphRow {
+	if _, ok := g.Notes[root]; !ok {
+		return nil
+	}
+
+	children := make(map[string][]string)
+	seen := map[string]bool{root: true}
+	level := []string{root}
+	for d := 0; d < depth && len(level) > 0; d++ {
+		var next []string
+		for _, path := range level {
+			for _, neighbor := range g.Neighbors(path) {
+				if !seen[neighbor] {
+					seen[neighbor] = true
+					children[path] = append(children[path], neighbor)
+					next = append(next, neighbor)
+				}
+			}
+		}
+		level = next
+	}
+
+	// Count what lies beyond the notes at the edge
+	more := make(map[string]int)
+	for _, path := range level {
+		for _, neighbor := range g.Neighbors(path) {
+			if !seen[neighbor] {
+				more[path]++
+			}
+		}
+	}
+
+	rows := []graphRow{{path: root}}
+	var walk func(parent, indent string)
+	walk = func(parent, indent string) {
+		kids := children[parent]
+		for i, kid := range kids {
+			branch, next := "├─", "│  "
+			if i == len(kids)-1 {
+				branch, next = "└─", "   "
+			}
+			rows = append(rows, graphRow{
+				path:   kid,
+				prefix: indent + branch,
+				arrow:  linkArrow(g, parent, kid),
+				more:   more[kid],
+			})
+			walk(kid, indent+next)
+		}
+	}
+	walk(root, "")
+	return rows
+}
+
+// linkArrow shows which way the links between two notes go
+func linkArrow(g *store.Graph, from, to string) string {
+	out, in := false, false
+	for _, path := range g.Out[from] {
+		out = out || path == to
+	}
+	for _, path := range g.In[from] {
+		in = in || path == to
+	}
+	switch {
+	case out && in:
+		return "↔"
+	case out:
+		return "→"
+	default:
+		return "←"
+	}
+}
+
+// clusterRows lists the groups of linked notes, largest first, and then
+// the notes linked to nothing
+func clusterRows(g *store.Graph) []graphRow {
+	var rows []graphRow
+	var isolated []string
+	n := 0
+	for _, cluster := range g.Clusters() {
+		if len(cluster) == 1 {
+			isolated = append(isolated, cluster[0])
+			continue
+		}
+		n++
+		rows = append(rows, graphRow{heading: fmt.Sprintf("Cluster %d (%d notes)", n, len(cluster))})
+		for _, path := range cluster {
+			rows = append(rows, graphRow{path: path})
+		}
+	}
+
+	if len(isolated) > 0 {
+		g.SortByTitle(isolated)
+		rows = append(rows, graphRow{heading: fmt.Sprintf("Isolated notes (%d)", len(isolated))})
+		for _, path := range isolated {
+			rows = append(rows, graphRow{path: path})
+		}
+	}
+	return rows
+}
+
+// moveGraphCursor selects the next note in the given direction, skipping
+// headings
+func (m *MainModel) moveGraphCursor(step int) {
+	for i := m.graphCursor + step; i >= 0 && i < len(m.graphRows); i += step {
+		if m.graphRows[i].heading == "" {
+			m.graphCursor = i
+			return
+		}
+	}
+	if m.graphCursor < len(m.graphRows) && m.graphRows[m.graphCursor].heading != "" {
+		m.graphCursor = 0
+	}
+}
+
+// selectedGraphPath returns the path of the selected note, if any
+func (m *MainModel) selectedGraphPath() string {
+	if m.graphCursor < len(m.graphRows) {
+		return m.graphRows[m.graphCursor].path
+	}
+	return ""
+}
+
+// centerGraph shows the tree around path, remembering the note it was
+// centered on before
+func (m *MainModel) centerGraph(path string) {
+	if path == "" || (path == m.graphRoot && !m.graphClusters) {
+		return
+	}
+	if m.graphRoot != "" {
+		m.graphBack = append(m.graphBack, m.graphRoot)
+	}
+	m.graphRoot = path
+	m.graphClusters = false
+	m.buildGraphRows()
+}
+
+func (m *MainModel) handleGraphKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+	case "esc", "q":
+		// Leave with the note the graph ended up on selected
+		if m.graphRoot != "" {
+			m.revealInSidebar(m.graphRoot)
+			m.selectNote()
+		}
+		m.currentView = "main"
+	case "up", "k":
+		m.moveGraphCursor(-1)
+	case "down", "j":
+		m.moveGraphCursor(1)
+	case "enter", "right", "l":
+		m.centerGraph(m.selectedGraphPath())
+	case "left", "h", "backspace":
+		if m.graphClusters {
+			m.graphClusters = m.graphRoot == ""
+			m.buildGraphRows()
+		} else if len(m.graphBack) > 0 {
+			m.graphRoot = m.graphBack[len(m.graphBack)-1]
+			m.graphBack = m.graphBack[:len(m.graphBack)-1]
+			m.buildGraphRows()
+		}
+	case "+", "=":
+		if m.graphDepth < maxGraphDepth {
+			m.graphDepth++
+			m.buildGraphRows()
+		}
+	case "-":
+		if m.graphDepth > minGraphDepth {
+			m.graphDepth--
+			m.buildGraphRows()
+		}
+	case "c":
+		m.graphClusters = !m.graphClusters || m.graphRoot == ""
+		m.buildGraphRows()
+	case "o", "e":
+		if path := m.selectedGraphPath(); path != "" {
+			m.openNote(path)
+			m.revealInSidebar(path)
+		}
+	case "r":
+		m.loadGraph()
+	}
+	return m, nil
+}
+
+func (m *MainModel) renderGraph() string {
+	styles := m.getStyles()
+	theme := Themes[m.currentTheme]
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	accent := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Accent))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var s strings.Builder
+	if m.graphClusters {
+		s.WriteString(styles["title"].Render("Note Clusters") + "\n")
+		s.WriteString(dim.Render("Groups of notes linked to each other, largest first") + "\n\n")
+	} else {
+		s.WriteString(styles["title"].Render("Note Graph") + "\n")
+		s.WriteString(dim.Render(fmt.Sprintf("Depth %d • → links to • ← linked from • ↔ both", m.graphDepth)) + "\n\n")
+	}
+
+	switch {
+	case m.graphError != "":
+		s.WriteString(errStyle.Render(m.graphError) + "\n")
+	case len(m.graphRows) == 0:
+		s.WriteString(dim.Render("No notes") + "\n")
+	}
+
+	height := m.height - 6
+	if height < 3 {
+		height = 3
+	}
+	first := 0
+	if m.graphCursor >= height {
+		first = m.graphCursor - height + 1
+	}
+	for i := first; i < len(m.graphRows) && i < first+height; i++ {
+		row := m.graphRows[i]
+		if row.heading != "" {
+			s.WriteString(accent.Render(row.heading) + "\n")
+			continue
+		}
+
+		title := m.graph.Notes[row.path].Title
+		line := row.prefix + row.arrow + " " + title
+		switch {
+		case m.graphClusters:
+			line = title
+		case row.path == m.graphRoot:
+			line = "● " + title
+		}
+		if row.more > 0 {
+			line += fmt.Sprintf(" (+%d)", row.more)
+		}
+
+		line = truncateToWidth(line, m.width-4)
+		switch {
+		case i == m.graphCursor:
+			s.WriteString(styles["selected"].Render("▶ "+line) + "\n")
+		case row.path == m.graphRoot && !m.graphClusters:
+			s.WriteString("  " + accent.Render(line) + "\n")
+		default:
+			s.WriteString("  " + line + "\n")
+		}
+	}
+
+	help := "↑/↓: Navigate | Enter/→: Center on note | ←: Back | +/-: Depth | c: Clusters | o: Open | Esc: Close"
+	if m.graphClusters {
+		help = "↑/↓: Navigate | Enter: Show graph | c: Graph | o: Open | Esc: Close"
+	}
+	s.WriteString("\n" + dim.Render(help))
+	return s.String()
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/store"
+)
+
+// healthEntry is a line of the link health view: a section heading, a
+// bad link or an orphan note
+type healthEntry struct {
+	heading string
+	link    *store.BadLink
+	orphan  *store.NoteMeta
+}
+
+// openLinkHealth checks the links of every note and shows the report
+func (m *MainModel) openLinkHealth() (tea.Model, tea.Cmd) {
+	m.linkReport, m.healthError = nil, ""
+	report, err := store.CheckLinks(m.store)
+	if err != nil {
+		m.healthError = err.Error()
+	}
+	m.linkReport = report
+	m.healthCursor = m.nextHealthEntry(-1, 1)
+	m.currentView = "links"
+	return m, nil
+}
+
+// healthEntries lists the report section by section, leaving out empty
+// sections
+func (m *MainModel) healthEntries() []healthEntry {
+	report := m.linkReport
+	if report == nil {
+		return nil
+	}
+
+	var entries []healthEntry
+	section := func(heading string, n int) {
+		if n > 0 {
+			entries = append(entries, healthEntry{heading: fmt.Sprintf("%s (%d)", heading, n)})
+		}
+	}
+	section("Broken links", len(report.Broken))
+	for i := range report.Broken {
+		entries = append(entries, healthEntry{link: &report.Broken[i]})
+	}
+	section("Fuzzy links", len(report.Fuzzy))
+	for i := range report.Fuzzy {
+		entries = append(entries, healthEntry{link: &report.Fuzzy[i]})
+	}
+	section("Orphans", len(report.Orphans))
+	for i := range report.Orphans {
+		entries = append(entries, healthEntry{orphan: &report.Orphans[i]})
+	}
+	return entries
+}
+
+// nextHealthEntry returns the index of the next entry from i in the given
+// direction that isn't a heading, or i if there is none
+func (m *MainModel) nextHealthEntry(i, step int) int {
+	entries := m.healthEntries()
+	for j := i + step; j >= 0 && j < len(entries); j += step {
+		if entries[j].heading == "" {
+			return j
+		}
+	}
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+// openHealthEntry opens the note of the selected entry in the editor, with
+// the cursor on the link if there is one
+func (m *MainModel) openHealthEntry() {
+	entries := m.healthEntries()
+	if m.healthCursor >= len(entries) {
+		return
+	}
+	entry := entries[m.healthCursor]
+
+	switch {
+	case entry.link != nil:
+		m.openNote(entry.link.Path)
+		if m.currentView == "editor" {
+			m.editor.SetCursor(entry.link.Line, entry.link.Column)
+		}
+		m.revealInSidebar(entry.link.Path)
+	case entry.orphan != nil:
+		m.openNote(entry.orphan.Path)
+		m.revealInSidebar(entry.orphan.Path)
+	}
+}
+
+func (m *MainModel) handleLinkHealthKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+	case "esc", "q":
+		m.currentView = "main"
+	case "up", "k":
+		m.healthCursor = m.nextHealthEntry(m.healthCursor, -1)
+	case "down", "j":
+		m.healthCursor = m.nextHealthEntry(m.healthCursor, 1)
+	case "r":
+		return m.openLinkHealth()
+	case "enter", "o":
+		m.openHealthEntry()
+	}
+	return m, nil
+}
+
+func (m *MainModel) renderLinkHealth() string {
+	styles := m.getStyles()
+	theme := Themes[m.currentTheme]
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	heading := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Accent))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var s strings.Builder
+	s.WriteString(styles["title"].Render("Link Health") + "\n\n")
+
+	entries := m.healthEntries()
+	switch {
+	case m.healthError != "":
+		s.WriteString(errStyle.Render(m.healthError) + "\n")
+	case len(entries) == 0:
+		s.WriteString(dim.Render("Every link leads to a note and every note is linked") + "\n")
+	}
+
+	height := m.height - 5
+	if height < 3 {
+		height = 3
+	}
+	first := 0
+	if m.healthCursor >= height {
+		first = m.healthCursor - height + 1
+	}
+	for i := first; i < len(entries) && i < first+height; i++ {
+		entry := entries[i]
+		var line string
+		switch {
+		case entry.heading != "":
+			s.WriteString(heading.Render(entry.heading) + "\n")
+			continue
+		case entry.link != nil && entry.link.Match == store.LinkFuzzy:
+			line = fmt.Sprintf("%s:%d  [[%s]] → %s", entry.link.Title, entry.link.Line+1, entry.link.Link, entry.link.Target.Title)
+		case entry.link != nil:
+			line = fmt.Sprintf("%s:%d  [[%s]]", entry.link.Title, entry.link.Line+1, entry.link.Link)
+		default:
+			line = fmt.Sprintf("%s  (%s)", entry.orphan.Title, entry.orphan.Path)
+		}
+
+		line = truncateToWidth(line, m.width-4)
+		if i == m.healthCursor {
+			s.WriteString(styles["selected"].Render("▶ "+line) + "\n")
+		} else {
+			s.WriteString("  " + line + "\n")
+		}
+	}
+
+	s.WriteString("\n" + dim.Render("↑/↓: Navigate | Enter: Open note | r: Recheck | Esc: Back"))
+	return s.String()
+}
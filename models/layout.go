@@ -150,17 +150,21 @@ func (m *MainModel) getSidebarItems() []string {
 		notesToShow = m.filteredNotes
 	}
 	
-	// Add folders and notes
+	// Add folders and notes, indented by how deep they are
 	for _, note := range notesToShow {
+		indent := strings.Repeat("  ", note.depth)
 		if note.isFolder {
-			expanded := m.sidebarExpanded[note.title]
+			expanded := m.sidebarExpanded[note.path]
 			if expanded {
-				items = append(items, "▼ "+note.title)
+				items = append(items, indent+"▼ "+note.title)
 			} else {
-				items = append(items, "▶ "+note.title)
+				items = append(items, indent+"▶ "+note.title)
 			}
+		} else if note.todos > 0 {
+			// Completion of the note's todos
+			items = append(items, fmt.Sprintf("%s  %s [%d/%d]", indent, note.title, note.doneTodos, note.todos))
 		} else {
-			items = append(items, "  "+note.title)
+			items = append(items, indent+"  "+note.title)
 		}
 	}
 	
@@ -189,7 +193,7 @@ func (m *MainModel) renderMainContent(width, height int) string {
 	}
 	
 	// Note content
-	content := m.currentNote.Content
+	content := m.RenderLinks(m.currentNote.Content)
 	if content == "" {
 		content = "No content yet. Start typing..."
 	}
@@ -197,12 +201,12 @@ func (m *MainModel) renderMainContent(width, height int) string {
 	// Wrap content to fit width
 	lines := strings.Split(content, "\n")
 	for _, line := range lines {
-		if len(line) > width-4 {
+		if lipgloss.Width(line) > width-4 {
 			// Word wrap
 			words := strings.Fields(line)
 			currentLine := ""
 			for _, word := range words {
-				if len(currentLine)+len(word)+1 > width-4 {
+				if lipgloss.Width(currentLine)+lipgloss.Width(word)+1 > width-4 {
 					if currentLine != "" {
 						s.WriteString(currentLine + "\n")
 					}
@@ -235,6 +239,25 @@ func (m *MainModel) renderMainContent(width, height int) string {
 	if len(m.currentNote.Tags) > 0 {
 		s.WriteString(fmt.Sprintf("Tags: %s\n", strings.Join(m.currentNote.Tags, ", ")))
 	}
+	if m.currentNote.ID != "" {
+		s.WriteString(fmt.Sprintf("ID: %s (links as [[%s|...]] survive renames)\n", m.currentNote.ID, m.currentNote.ID))
+	}
+	
+	// Notes linking here; they can be selected after the note's own links
+	backlinks := m.noteBacklinks()
+	if len(backlinks) == 0 {
+		s.WriteString("\nNo backlinks\n")
+	} else {
+		s.WriteString(fmt.Sprintf("\nBacklinks (%d):\n", len(backlinks)))
+		first := len(m.currentNote.ExtractLinks())
+		for i, note := range backlinks {
+			line := "  ← " + note.Title
+			if first+i == m.linkCursor {
+				line = selectedLinkStyle.Render(line)
+			}
+			s.WriteString(line + "\n")
+		}
+	}
 	
 	return s.String()
 }
@@ -243,7 +266,9 @@ func (m *MainModel) renderStatusBar() string {
 	var left, right strings.Builder
 	
 	// Left side: current note info
-	if m.currentNote != nil {
+	if prompt := m.promptText(); prompt != "" {
+		left.WriteString(prompt)
+	} else if m.currentNote != nil {
 		left.WriteString(fmt.Sprintf("%s • %d notes", m.currentNote.Title, len(m.notes)))
 	} else {
 		left.WriteString(fmt.Sprintf("%d notes", len(m.notes)))
@@ -253,6 +278,7 @@ func (m *MainModel) renderStatusBar() string {
 	shortcuts := []string{
 		"↑/k,↓/j",
 		"enter: edit",
+		"[/]: links",
 		"n: new",
 		"s: sort",
 		"f: filter",
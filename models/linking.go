@@ -2,44 +2,40 @@ package models
 
 import (
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/markdown"
+	"github.com/ssh-notes/terminal-notes/store"
+	"github.com/ssh-notes/terminal-notes/utils"
 )
 
-var linkRegex = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
+var linkRegex = store.LinkPattern
 
-// ExtractLinks extracts all [[link]] references from note content
-func (n *Note) ExtractLinks() []string {
-	matches := linkRegex.FindAllStringSubmatch(n.Content, -1)
-	links := make([]string, 0, len(matches))
+// FindBacklinks finds all notes that link to this note, by ID or title
+func (m *MainModel) FindBacklinks(target *Note) []*Note {
+	backlinks := []*Note{}
 	
-	for _, match := range matches {
-		if len(match) > 1 {
-			links = append(links, strings.TrimSpace(match[1]))
-		}
+	// Use the metadata index so only the linking notes are read
+	metas, err := m.store.Metadata()
+	if err != nil {
+		return backlinks
 	}
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].Path < metas[j].Path
+	})
 	
-	return links
-}
-
-// FindBacklinks finds all notes that link to this note
-func (m *MainModel) FindBacklinks(noteTitle string) []*Note {
-	backlinks := []*Note{}
-	
-	for _, noteItem := range m.notes {
-		if noteItem.isFolder {
-			continue
-		}
-		
-		note, err := m.loadNoteFromFile(noteItem.path)
-		if err != nil {
-			continue
-		}
-		
-		links := note.ExtractLinks()
-		for _, link := range links {
-			if strings.EqualFold(link, noteTitle) {
-				backlinks = append(backlinks, note)
+	for _, meta := range metas {
+		for _, link := range meta.Links {
+			if target.MatchesLink(link) {
+				note, err := m.store.Get(meta.Path)
+				if err == nil {
+					backlinks = append(backlinks, note)
+				}
 				break
 			}
 		}
@@ -48,49 +44,394 @@ func (m *MainModel) FindBacklinks(noteTitle string) []*Note {
 	return backlinks
 }
 
-// RenderLinks renders [[links]] as clickable (highlighted) text
-func RenderLinks(content string) string {
+// noteBacklinks returns the notes linking to the current note. They are
+// looked up once per note until the notes are reloaded.
+func (m *MainModel) noteBacklinks() []*Note {
+	key := m.currentNote.Path + "\x00" + m.currentNote.Title
+	if m.backlinksFor != key {
+		m.backlinks = m.FindBacklinks(m.currentNote)
+		m.backlinksFor = key
+	}
+	return m.backlinks
+}
+
+// RenderLinks highlights the [[links]] in content, marking missing notes
+// and the link selected in the main view. Each word of a link is styled
+// on its own so the text can still be wrapped at spaces.
+func (m *MainModel) RenderLinks(content string) string {
+	style := wikiLinkStyle(Themes[m.currentTheme])
+	index := 0
 	return linkRegex.ReplaceAllStringFunc(content, func(match string) string {
-		linkText := linkRegex.FindStringSubmatch(match)
-		if len(linkText) > 1 {
-			// Highlight link text
-			return fmt.Sprintf("[[%s]]", linkText[1])
+		linkStyle := style
+		if target, _ := store.SplitLink(linkRegex.FindStringSubmatch(match)[1]); !m.linkExists(target) {
+			linkStyle = brokenLinkStyle
+		}
+		if index == m.linkCursor {
+			linkStyle = selectedLinkStyle.Copy().Inherit(linkStyle)
+		}
+		index++
+		
+		words := strings.Split(match, " ")
+		for i, word := range words {
+			if word != "" {
+				words[i] = linkStyle.Render(word)
+			}
 		}
-		return match
+		return strings.Join(words, " ")
 	})
 }
 
 // ResolveLink finds a note by title (fuzzy match)
 func (m *MainModel) ResolveLink(linkTitle string) *Note {
-	// Exact match first
-	for _, noteItem := range m.notes {
-		if noteItem.isFolder {
-			continue
+	meta, ok := m.linkTarget(linkTitle)
+	if !ok {
+		return nil
+	}
+	
+	note, err := m.store.Get(meta.Path)
+	if err != nil {
+		return nil
+	}
+	return note
+}
+
+// linkTarget finds the note a link target points to, by ID, title or
+// part of a title. Notes in folders count too.
+func (m *MainModel) linkTarget(linkTitle string) (store.NoteMeta, bool) {
+	meta, match := store.ResolveLink(m.linkMetas(), linkTitle)
+	return meta, match != store.LinkMissing
+}
+
+// linkMetas returns the notes links are resolved against. Rendering
+// resolves every link on every redraw, so the index is read once and kept
+// until the notes are reloaded or saved.
+func (m *MainModel) linkMetas() []store.NoteMeta {
+	if m.linkIndex == nil {
+		metas, err := m.store.Metadata()
+		if err != nil {
+			logger.Error("Failed to load note index: %v", err)
+			return nil
 		}
-		
-		if strings.EqualFold(noteItem.title, linkTitle) {
-			note, err := m.loadNoteFromFile(noteItem.path)
-			if err == nil {
-				return note
-			}
+		m.linkIndex = append([]store.NoteMeta{}, metas...)
+	}
+	return m.linkIndex
+}
+
+// linkExists reports whether a link leads to a note
+func (m *MainModel) linkExists(linkTitle string) bool {
+	_, ok := m.linkTarget(linkTitle)
+	return ok
+}
+
+// linkText returns what the preview shows for a link without an alias:
+// the title of the note for a link by ID, otherwise the link as written
+func (m *MainModel) linkText(target string) (string, bool) {
+	meta, ok := m.linkTarget(target)
+	if ok && meta.ID == target {
+		return meta.Title, true
+	}
+	return target, ok
+}
+
+// viewLinks returns the links that can be selected in the current view:
+// the ones the preview draws, or every link in the note's text
+func (m *MainModel) viewLinks() []string {
+	if m.currentNote == nil {
+		return nil
+	}
+	if m.currentView == "preview" {
+		// Counted in the text as shown, embedded notes included
+		return markdown.WikiLinks(m.previewMarkdown())
+	}
+	return m.currentNote.ExtractLinks()
+}
+
+// selectLink moves the link selection by delta, wrapping around. In the
+// main view the backlinks follow the note's own links.
+func (m *MainModel) selectLink(delta int) {
+	count := len(m.viewLinks())
+	if m.currentView == "main" && m.currentNote != nil {
+		count += len(m.noteBacklinks())
+	}
+	
+	switch {
+	case count == 0:
+		m.linkCursor = -1
+	case m.linkCursor < 0 && delta < 0:
+		m.linkCursor = count - 1
+	case m.linkCursor < 0:
+		m.linkCursor = 0
+	default:
+		m.linkCursor = ((m.linkCursor+delta)%count + count) % count
+	}
+	
+	if m.currentView == "preview" {
+		m.refreshPreview()
+	}
+}
+
+// followSelectedLink opens the note of the selected link or backlink
+func (m *MainModel) followSelectedLink() {
+	links := m.viewLinks()
+	switch {
+	case m.linkCursor < 0:
+		return
+	case m.linkCursor < len(links):
+		m.followLink(links[m.linkCursor])
+	case m.currentView == "main" && m.currentNote != nil:
+		backlinks := m.noteBacklinks()
+		if i := m.linkCursor - len(links); i < len(backlinks) {
+			m.pushHistory()
+			m.showNote(backlinks[i])
 		}
 	}
+}
+
+// followLinkAtCursor follows the link under the editor's cursor
+func (m *MainModel) followLinkAtCursor() {
+	row, col := m.editor.Cursor()
+	line := m.editor.Line(row)
+	for _, loc := range linkRegex.FindAllStringSubmatchIndex(line, -1) {
+		start := utf8.RuneCountInString(line[:loc[0]])
+		end := utf8.RuneCountInString(line[:loc[1]])
+		// Just past the closing brackets counts, as after typing a link
+		if col >= start && col <= end {
+			target, _ := store.SplitLink(line[loc[2]:loc[3]])
+			m.followLink(target)
+			return
+		}
+	}
+}
+
+// followLink opens the note a link points to in the current view,
+// remembering the note it came from. A link to a missing note offers to
+// create it.
+func (m *MainModel) followLink(linkTitle string) {
+	note := m.ResolveLink(linkTitle)
+	if note == nil {
+		m.linkPrompt = linkTitle
+		return
+	}
+	m.pushHistory()
+	m.showNote(note)
+}
+
+// pushHistory records the current note before moving to another, which
+// ends any run of going back
+func (m *MainModel) pushHistory() {
+	if m.currentNote != nil {
+		m.navBack = append(m.navBack, m.currentNote.Path)
+	}
+	m.navForward = nil
+}
+
+// navigate goes back to the previous note, or forward again, skipping
+// notes deleted since
+func (m *MainModel) navigate(back bool) {
+	from, to := &m.navBack, &m.navForward
+	if !back {
+		from, to = to, from
+	}
 	
-	// Fuzzy match
-	linkLower := strings.ToLower(linkTitle)
-	for _, noteItem := range m.notes {
-		if noteItem.isFolder {
+	for len(*from) > 0 {
+		path := (*from)[len(*from)-1]
+		*from = (*from)[:len(*from)-1]
+		note, err := m.store.Get(path)
+		if err != nil {
 			continue
 		}
-		
-		if strings.Contains(strings.ToLower(noteItem.title), linkLower) {
-			note, err := m.loadNoteFromFile(noteItem.path)
-			if err == nil {
-				return note
-			}
+		if m.currentNote != nil {
+			*to = append(*to, m.currentNote.Path)
 		}
+		m.showNote(note)
+		return
 	}
+}
+
+// showNote makes note the current one without leaving the current view
+func (m *MainModel) showNote(note *Note) {
+	m.saveEditorChanges()
+	m.currentNote = note
+	m.linkCursor = -1
 	
-	return nil
+	switch m.currentView {
+	case "editor":
+		m.loadEditor(note)
+	case "preview":
+		m.refreshPreview()
+		m.previewViewport.GotoTop()
+	default:
+		m.revealInSidebar(note.Path)
+	}
+}
+
+// saveEditorChanges saves the note being edited if it has unsaved changes
+func (m *MainModel) saveEditorChanges() {
+	if m.currentView == "editor" && m.currentNote != nil && m.editorText != m.currentNote.Content {
+		m.saveCurrentNote()
+		m.loadNotes()
+	}
 }
 
+// revealInSidebar moves the sidebar cursor to a note, if it is listed
+func (m *MainModel) revealInSidebar(path string) {
+	// Open the folders the note is in
+	if parent := parentFolder(path); parent != "" && !m.sidebarExpanded[parent] {
+		m.expandFolder(parent)
+	}
+	
+	notesToUse := m.notes
+	if m.showFiltered && len(m.filteredNotes) > 0 {
+		notesToUse = m.filteredNotes
+	}
+	
+	for i, item := range notesToUse {
+		if item.path == path {
+			m.sidebarCursor = i
+			return
+		}
+	}
+}
+
+// handleLinkPromptKey answers the offer to create a linked note that
+// doesn't exist yet
+func (m *MainModel) handleLinkPromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	title := m.linkPrompt
+	m.linkPrompt = ""
+	
+	switch msg.String() {
+	case "y", "Y", "enter":
+		if err := utils.ValidateTitle(title); err != nil {
+			return m, nil
+		}
+		m.saveEditorChanges()
+		m.pushHistory()
+		m.linkCursor = -1
+		m.createNote(title)
+	}
+	return m, nil
+}
+
+// renameCurrentNote retitles the current note and offers to update the
+// links that name it by its old title
+func (m *MainModel) renameCurrentNote(title string) {
+	if m.currentNote == nil {
+		return
+	}
+	oldTitle := m.currentNote.Title
+	
+	// The title is saved along with the editor's text, which must be this note's
+	if m.editorPath != m.currentNote.Path {
+		m.loadEditor(m.currentNote)
+	}
+	m.currentNote.Title = title
+	m.saveCurrentNote()
+	m.loadNotes()
+	
+	if saved, err := m.store.Get(m.currentNote.Path); err == nil && saved.Title != oldTitle {
+		m.offerLinkRename(oldTitle)
+	}
+}
+
+// offerLinkRename asks whether to rewrite the links to the current note's
+// old title. Nothing is offered if another note still has that title, as
+// the links now lead there.
+func (m *MainModel) offerLinkRename(oldTitle string) {
+	note := m.currentNote
+	if strings.EqualFold(oldTitle, note.Title) {
+		return
+	}
+	
+	metas, err := m.store.Metadata()
+	if err != nil {
+		return
+	}
+	for _, meta := range metas {
+		if meta.Path != note.Path && strings.EqualFold(meta.Title, oldTitle) {
+			return
+		}
+	}
+	
+	plan, err := store.PlanLinkRename(m.store, oldTitle, note.Title, "")
+	if err != nil {
+		logger.Error("Failed to find links to %q: %v", oldTitle, err)
+		return
+	}
+	if len(plan) == 0 {
+		return
+	}
+	m.renameFrom = oldTitle
+	m.renameTo = note.Title
+	m.renameID = note.ID
+	m.renamePlan = plan
+}
+
+// handleRenamePromptKey rewrites the links to a renamed note by its new
+// title or by its ID, or leaves them
+func (m *MainModel) handleRenamePromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	plan := m.renamePlan
+	m.renamePlan = nil
+	
+	switch msg.String() {
+	case "i", "I":
+		if m.renameID == "" {
+			return m, nil
+		}
+		var err error
+		plan, err = store.PlanLinkRename(m.store, m.renameFrom, m.renameTo, m.renameID)
+		if err != nil {
+			logger.Error("Failed to find links to %q: %v", m.renameFrom, err)
+			return m, nil
+		}
+	case "y", "Y", "enter":
+	default:
+		return m, nil
+	}
+	
+	// Each note's old text goes to its version history
+	if err := store.ApplyReplace(m.store, plan); err != nil {
+		logger.Error("Failed to update links to %q: %v", m.renameFrom, err)
+		return m, nil
+	}
+	for _, change := range plan {
+		if m.currentNote != nil && m.currentNote.Path == change.Path {
+			if note, err := m.store.Get(change.Path); err == nil {
+				m.currentNote = note
+				if m.currentView == "editor" {
+					m.loadEditor(note)
+				}
+			}
+		}
+	}
+	m.loadNotes()
+	return m, nil
+}
+
+// promptText is the question awaiting a key press, if any
+func (m *MainModel) promptText() string {
+	switch {
+	case m.renamePlan != nil:
+		notes := fmt.Sprintf("%d notes", len(m.renamePlan))
+		if len(m.renamePlan) == 1 {
+			notes = "1 note"
+		}
+		return fmt.Sprintf("Update the links to [[%s]] in %s? y: as [[%s]] | i: by ID | n: leave them",
+			m.renameFrom, notes, m.renameTo)
+	case m.linkPrompt != "":
+		return fmt.Sprintf("No note called %q. Create it? (y/n)", m.linkPrompt)
+	case m.folderDelete != "":
+		count := m.folderNoteCount(m.folderDelete)
+		notes := fmt.Sprintf("%d notes", count)
+		if count == 1 {
+			notes = "1 note"
+		}
+		return fmt.Sprintf("Delete folder %q and the %s in it? (y/n)", m.folderDelete, notes)
+	}
+	return ""
+}
+
+func (m *MainModel) renderPrompt() string {
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color(Themes[m.currentTheme].Accent)).
+		Render(m.promptText())
+}
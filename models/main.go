@@ -4,32 +4,68 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/editor"
+	"github.com/ssh-notes/terminal-notes/search"
+	"github.com/ssh-notes/terminal-notes/store"
 )
 
 type MainModel struct {
 	dataDir     string
 	username    string
-	currentView string // "main", "editor", "title_edit", "search", "tags"
+	store       store.NoteStore
+	currentView string // "main", "editor", "title_edit", "search", "palette", "tags"
 	
 	// Two-pane layout
 	sidebarCursor int
-	sidebarExpanded map[string]bool // Track expanded folders
+	sidebarExpanded map[string]bool // Track expanded folders by path
 	showSidebar   bool
 	
 	// Browser
 	browserList list.Model
-	notes       []NoteItem
+	notes       []NoteItem            // sidebar rows: the tree with collapsed folders left out
+	folderItems map[string][]NoteItem // contents of each folder, "" for the top level
 	
 	// Editor
-	editor      textinput.Model
+	editor      editor.Model
 	editorText  string
 	editorMode  string // "normal", "insert", "vim"
+	vim         *editor.Vim
+	editorPath  string                     // note whose text is in the editor
+	histories   map[string]*editor.History // undo history per note path
+	
+	// Find and replace in the editor
+	findActive    bool
+	findReplacing bool
+	findInput     textinput.Model
+	replaceInput  textinput.Model
+	findFocus     int // 0: find field, 1: replace field
+	findOptions   editor.FindOptions
+	findMatches   []editor.Range
+	findCurrent   int
+	findAnchor    editor.Pos // where the search starts from
+	findError     string
+	findStatus    string
+	
+	// Replace across all notes
+	globalFindInput textinput.Model
+	globalWithInput textinput.Model
+	replaceFocus    int // replaceFocusPattern, replaceFocusWith or replaceFocusNotes
+	replaceOptions  store.ReplaceOptions
+	replacePlan     []store.Replacement
+	replaceSelected []bool // whether each planned change will be applied
+	replaceCursor   int
+	replaceScroll   int // first diff line shown
+	replaceConfirm  bool
+	replaceError    string
+	replaceStatus   string
+	
 	currentNote *Note
 	editingTitleInEditor bool // Whether we're editing title in the editor view
 	
@@ -40,13 +76,74 @@ type MainModel struct {
 	// Preview
 	previewViewport viewport.Model
 	previewContent  string
+	bannerCache     map[string]map[bannerKey]string // rendered headings per note path
+	embedsText      string                          // the previewed note with its embeds expanded
+	embedsFor       string                          // path and text of the note in embedsText
+	codeLineNumbers bool                            // number the lines of code blocks
+	codeColors      codeColors                      // highlighting of the editor text
+	
+	// Wiki links
+	linkCursor   int                 // selected [[link]] in the preview or main view, -1 for none
+	navBack      []string            // paths of the notes visited before the current one
+	navForward   []string            // paths of the notes gone back from
+	linkPrompt   string              // missing note offered for creation
+	renamePlan   []store.Replacement // links to rewrite after a rename, awaiting an answer
+	renameFrom   string
+	renameTo     string
+	renameID     string
+	backlinks    []*Note             // notes linking to the note in backlinksFor
+	backlinksFor string
+	linkIndex    []store.NoteMeta    // notes links are resolved against, nil until read
+	
+	// Link health
+	linkReport   *store.LinkReport
+	healthCursor int
+	healthError  string
+	
+	// Note graph
+	graph         *store.Graph
+	graphRoot     string   // path of the note the tree is centered on
+	graphBack     []string // notes the tree was centered on before
+	graphDepth    int
+	graphClusters bool // list connected notes instead of the tree
+	graphRows     []graphRow
+	graphCursor   int
+	graphError    string
+	
+	// Folders
+	folderInput    textinput.Model
+	folderParent   string // folder the named folder is in
+	folderRenaming string // folder being renamed, empty for a new one
+	folderError    string
+	folderDelete   string // folder awaiting confirmation of its deletion
+	moveFrom       NoteItem
+	moveTargets    []string // folders to move to, "" for the top level
+	moveCursor     int
+	moveError      string
+	
+	// Tasks across notes
+	tasks      []taskItem
+	taskRows   []taskRow
+	taskCounts map[string][2]int // done and total todos of each note with open ones
+	taskCursor int
+	taskGroup  int // taskGroupNote, taskGroupTag or taskGroupAgenda
+	taskError  string
 	
 	// Search
 	searchInput textinput.Model
-	searchResults []NoteItem
+	searchResults []searchResult
+	searchCursor  int
+	searchError   *search.ParseError
 	searchQuery   string
 	searchMode    bool
 	
+	// Quick-jump palette
+	paletteInput    textinput.Model
+	paletteEntries  []paletteEntry
+	paletteMatches  []paletteMatch
+	paletteCursor   int
+	palettePreviews map[string]*Note
+	
 	// Tags
 	tagsInput textinput.Model
 	showTags  bool
@@ -73,15 +170,25 @@ type MainModel struct {
 	// Theme
 	currentTheme   string
 	
+	// Session timeouts
+	idleTimeout     time.Duration
+	sessionDeadline time.Time
+	lastActivity    time.Time
+	sessionWarning  string
+	timeoutReason   string
+	
 	width  int
 	height int
 }
 
 type NoteItem struct {
-	title    string
-	path     string
-	isFolder bool
-	tags     []string
+	title     string
+	path      string
+	isFolder  bool
+	tags      []string
+	todos     int
+	doneTodos int
+	depth     int // how many folders deep the item is
 }
 
 func (i NoteItem) FilterValue() string { return i.title }
@@ -102,6 +209,7 @@ func NewMainModel(username, dataDir string) *MainModel {
 	m := &MainModel{
 		username:      username,
 		dataDir:       dataDir,
+		store:         store.NewFileStore(dataDir),
 		currentView:   "main",
 		sidebarCursor: 0,
 		sidebarExpanded: make(map[string]bool),
@@ -112,13 +220,25 @@ func NewMainModel(username, dataDir string) *MainModel {
 		editingTitleInEditor: false,
 		sortMode:           SortByModified, // Default sort by modified date
 		currentTheme:       "default",
+		linkCursor:         -1,
+		graphDepth:         2,
 	}
 	
 	// Initialize editor
-	m.editor = textinput.New()
+	m.editor = editor.New()
 	m.editor.Placeholder = "Start typing..."
 	m.editor.Focus()
+	m.editor.Colorize = m.colorizeCode
 	m.editorMode = "insert" // Start in insert mode
+	m.vim = editor.NewVim()
+	m.histories = make(map[string]*editor.History)
+	m.bannerCache = make(map[string]map[bannerKey]string)
+	m.findInput = textinput.New()
+	m.replaceInput = textinput.New()
+	m.globalFindInput = textinput.New()
+	m.globalWithInput = textinput.New()
+	m.folderInput = textinput.New()
+	m.folderInput.Placeholder = "Folder name..."
 	
 	// Initialize search
 	m.searchInput = textinput.New()
@@ -152,7 +272,7 @@ func NewMainModel(username, dataDir string) *MainModel {
 
 func (m *MainModel) Init() tea.Cmd {
 	// Enter alt screen for proper TUI rendering
-	return tea.EnterAltScreen
+	return tea.Batch(tea.EnterAltScreen, m.sessionTick())
 }
 
 func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -164,16 +284,34 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		if m.width > 4 {
 			m.browserList.SetWidth(m.width - 4)
-			m.editor.Width = m.width - 4
 			m.previewViewport.Width = m.width - 4
 		}
 		if m.height > 6 {
 			m.browserList.SetHeight(m.height - 6)
 			m.previewViewport.Height = m.height - 4
 		}
+		if m.currentView == "preview" {
+			m.refreshPreview()
+		}
 		return m, nil
 		
+	case sessionTickMsg:
+		return m, m.handleSessionTick(time.Time(msg))
+		
 	case tea.KeyMsg:
+		m.lastActivity = time.Now()
+		m.sessionWarning = ""
+		
+		if m.renamePlan != nil {
+			return m.handleRenamePromptKey(msg)
+		}
+		if m.linkPrompt != "" {
+			return m.handleLinkPromptKey(msg)
+		}
+		if m.folderDelete != "" {
+			return m.handleFolderDeleteKey(msg)
+		}
+		
 		switch m.currentView {
 		case "main":
 			return m.handleMainKey(msg)
@@ -185,12 +323,26 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handlePreviewKey(msg)
 		case "search":
 			return m.handleSearchKey(msg)
+		case "palette":
+			return m.handlePaletteKey(msg)
 		case "tags":
 			return m.handleTagsKey(msg)
 		case "template_select":
 			return m.handleTemplateSelectKey(msg)
 		case "versions":
 			return m.handleVersionsKey(msg)
+		case "replace":
+			return m.handleGlobalReplaceKey(msg)
+		case "links":
+			return m.handleLinkHealthKey(msg)
+		case "graph":
+			return m.handleGraphKey(msg)
+		case "tasks":
+			return m.handleTasksKey(msg)
+		case "folder_name":
+			return m.handleFolderNameKey(msg)
+		case "move":
+			return m.handleMoveKey(msg)
 		}
 	}
 	
@@ -198,6 +350,10 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *MainModel) View() string {
+	return m.renderSessionWarning(m.renderView())
+}
+
+func (m *MainModel) renderView() string {
 	// Ensure we have minimum dimensions
 	if m.width == 0 {
 		m.width = 80
@@ -217,12 +373,26 @@ func (m *MainModel) View() string {
 		return m.renderPreview()
 	case "search":
 		return m.renderSearch()
+	case "palette":
+		return m.renderPalette()
 	case "tags":
 		return m.renderTags()
 	case "template_select":
 		return m.renderTemplateSelect()
 	case "versions":
 		return m.renderVersions()
+	case "replace":
+		return m.renderGlobalReplace()
+	case "links":
+		return m.renderLinkHealth()
+	case "graph":
+		return m.renderGraph()
+	case "tasks":
+		return m.renderTasks()
+	case "folder_name":
+		return m.renderFolderName()
+	case "move":
+		return m.renderMovePicker()
 	default:
 		return m.RenderTwoPane()
 	}
@@ -282,44 +452,27 @@ func (m *MainModel) handleBrowserKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *MainModel) handleEditorKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	if m.editorMode == "vim" {
-		return m.handleVimKey(msg)
-	}
-	
 	// Handle title editing in editor
 	if m.editingTitleInEditor {
 		switch msg.String() {
 		case "enter":
 			// Save title and switch to content editing
-			if m.currentNote != nil {
-				m.currentNote.Title = m.titleInput.Value()
-				m.saveCurrentNote()
-				m.loadNotes() // Refresh sidebar
-			}
+			m.renameCurrentNote(m.titleInput.Value())
 			m.editingTitleInEditor = false
 			m.editor.Focus()
-			return m, textinput.Blink
+			return m, nil
 		case "esc":
 			m.editingTitleInEditor = false
 			m.editor.Focus()
-			return m, textinput.Blink
+			return m, nil
 		}
 		var cmd tea.Cmd
 		m.titleInput, cmd = m.titleInput.Update(msg)
 		return m, cmd
 	}
 	
-	// Handle navigation/command keys
+	// Keys that work in every mode
 	switch msg.String() {
-	case "esc":
-		if m.editorMode == "insert" {
-			m.editorMode = "normal"
-			return m, nil
-		}
-		m.saveCurrentNote()
-		m.currentView = "main"
-		m.loadNotes()
-		return m, nil
 	case "ctrl+c":
 		m.saveCurrentNote()
 		m.currentView = "main"
@@ -332,6 +485,15 @@ func (m *MainModel) handleEditorKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "ctrl+p":
 		m.previewCurrentNote()
 		return m, nil
+	case "ctrl+]":
+		m.followLinkAtCursor()
+		return m, nil
+	case "ctrl+@": // Ctrl+Space
+		m.toggleTodoAtCursor()
+		return m, nil
+	case "ctrl+o":
+		m.navigate(true)
+		return m, nil
 	case "ctrl+t":
 		// Edit title from editor (only Ctrl+T, not just 't')
 		if m.currentNote != nil {
@@ -341,59 +503,91 @@ func (m *MainModel) handleEditorKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, textinput.Blink
 		}
 		return m, nil
+	}
+	
+	if m.findActive {
+		return m.handleFindKey(msg)
+	}
+	
+	if m.editorMode == "vim" {
+		return m.handleVimKey(msg)
+	}
+	
+	// Handle navigation/command keys
+	switch msg.String() {
+	case "ctrl+f":
+		return m.openFind(false)
+	case "ctrl+r":
+		return m.openFind(true)
+	case "esc":
+		if m.editorMode == "insert" {
+			m.editorMode = "normal"
+			return m, nil
+		}
+		m.saveCurrentNote()
+		m.currentView = "main"
+		m.loadNotes()
+		return m, nil
 	case "i":
 		if m.editorMode == "normal" {
 			m.editorMode = "insert"
 			m.editor.Focus()
-			return m, textinput.Blink
+			return m, nil
 		}
 	case "v":
 		if m.editorMode == "normal" {
 			m.editorMode = "vim"
+			m.vim.Reset(&m.editor)
 			return m, nil
 		}
 	}
 	
-	// Handle text input in insert mode
-	var cmd tea.Cmd
-	if m.editorMode == "insert" {
-		// Use the textinput component for proper input handling
-		m.editor, cmd = m.editor.Update(msg)
-		m.editorText = m.editor.Value()
+	// Typing only edits in insert mode; normal mode still moves the cursor
+	if m.editorMode != "insert" && (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace ||
+		msg.Type == tea.KeyEnter || msg.Type == tea.KeyTab || msg.Type == tea.KeyBackspace ||
+		msg.Type == tea.KeyDelete) {
+		return m, nil
 	}
+	
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	m.editorText = m.editor.Value()
 	return m, cmd
 }
 
+// handleVimKey passes keys to the vim keymap and carries out the ex
+// commands that reach outside the editor
 func (m *MainModel) handleVimKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "i":
-		m.editorMode = "insert"
-		m.editor.Focus()
-		return m, textinput.Blink
-	case "esc":
-		m.editorMode = "normal"
-		return m, nil
-	case "h", "left":
-		// Move cursor left
-		return m, nil
-	case "l", "right":
-		// Move cursor right
-		return m, nil
-	case "j", "down":
-		// Move cursor down
-		return m, nil
-	case "k", "up":
-		// Move cursor up
-		return m, nil
-	case "w":
-		// Save
+	action := m.vim.HandleKey(&m.editor, msg)
+	m.editorText = m.editor.Value()
+	
+	switch action {
+	case editor.VimWrite:
 		m.saveCurrentNote()
-		return m, nil
-	case "q":
+		m.loadNotes()
+		if m.currentNote != nil {
+			m.vim.Message = fmt.Sprintf("%q written", m.currentNote.Title)
+		}
+	case editor.VimQuit:
+		if m.currentNote != nil && m.editorText != m.currentNote.Content {
+			m.vim.Message = "E37: No write since last change (add ! to override)"
+			return m, nil
+		}
+		m.currentView = "main"
+		m.loadNotes()
+	case editor.VimWriteQuit:
 		m.saveCurrentNote()
 		m.currentView = "main"
 		m.loadNotes()
-		return m, nil
+	case editor.VimForceQuit:
+		// Forget the discarded edits so nothing saves them later
+		if m.currentNote != nil {
+			m.editorText = m.currentNote.Content
+		}
+		m.currentView = "main"
+		m.loadNotes()
+	case editor.VimDisable:
+		m.editorMode = "normal"
 	}
 	return m, nil
 }
@@ -406,10 +600,30 @@ func (m *MainModel) handlePreviewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "e":
 		if m.currentNote != nil {
 			m.currentView = "editor"
-			m.editor.SetValue(m.currentNote.Content)
-			m.editorText = m.currentNote.Content
+			m.loadEditor(m.currentNote)
 		}
 		return m, nil
+	case "n":
+		m.codeLineNumbers = !m.codeLineNumbers
+		m.refreshPreview()
+		return m, nil
+	
+	// Wiki links
+	case "]", "tab":
+		m.selectLink(1)
+		return m, nil
+	case "[", "shift+tab":
+		m.selectLink(-1)
+		return m, nil
+	case "enter", "o":
+		m.followSelectedLink()
+		return m, nil
+	case "ctrl+o", "alt+left":
+		m.navigate(true)
+		return m, nil
+	case "alt+right":
+		m.navigate(false)
+		return m, nil
 	}
 	
 	var cmd tea.Cmd
@@ -419,18 +633,36 @@ func (m *MainModel) handlePreviewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func (m *MainModel) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
-	case "esc", "q":
+	case "esc":
 		m.currentView = "main"
 		m.searchMode = false
 		return m, nil
+	case "up", "ctrl+p":
+		if m.searchCursor > 0 {
+			m.searchCursor--
+		}
+		return m, nil
+	case "down", "ctrl+n":
+		if m.searchCursor < len(m.searchResults)-1 {
+			m.searchCursor++
+		}
+		return m, nil
 	case "enter":
-		m.performSearch()
+		if m.searchCursor < len(m.searchResults) {
+			m.searchMode = false
+			m.openNote(m.searchResults[m.searchCursor].item.path)
+		}
 		return m, nil
 	}
 	
 	var cmd tea.Cmd
 	m.searchInput, cmd = m.searchInput.Update(msg)
-	m.searchQuery = m.searchInput.Value()
+	
+	// Search as the user types; the index makes this cheap
+	if m.searchInput.Value() != m.searchQuery {
+		m.searchQuery = m.searchInput.Value()
+		m.performSearch()
+	}
 	return m, cmd
 }
 
@@ -504,12 +736,7 @@ func (m *MainModel) renderEditor() string {
 		}
 	}
 	
-	if m.editorMode == "vim" {
-		mode := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render(fmt.Sprintf("-- %s MODE --", strings.ToUpper(m.editorMode)))
-		s.WriteString(mode + "\n")
-	} else if m.editorMode == "normal" {
+	if m.editorMode == "normal" {
 		mode := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("240")).
 			Render("-- NORMAL MODE -- Press 'i' to insert")
@@ -517,7 +744,7 @@ func (m *MainModel) renderEditor() string {
 	}
 	
 	// Show editor content (multi-line)
-	editorHeight := m.height - 12
+	editorHeight := m.height - 12 - m.findBarHeight()
 	if editorHeight < 1 {
 		editorHeight = 1
 	}
@@ -527,30 +754,76 @@ func (m *MainModel) renderEditor() string {
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color("62"))
 	
-	content := m.editorText
-	if content == "" {
-		content = m.editor.Placeholder
-	}
+	m.editor.SetSize(m.width-4, editorHeight)
+	s.WriteString(editorStyle.Render(m.editor.View()))
 	
-	// Split content into lines for display
-	lines := strings.Split(content, "\n")
-	if len(lines) > editorHeight {
-		lines = lines[:editorHeight]
+	if m.findActive {
+		s.WriteString("\n" + m.renderFindBar())
 	}
-	displayContent := strings.Join(lines, "\n")
 	
-	s.WriteString(editorStyle.Render(displayContent))
+	// Cursor position
+	row, col := m.editor.Cursor()
+	position := fmt.Sprintf("Ln %d/%d, Col %d", row+1, m.editor.LineCount(), col+1)
+	if m.editorMode == "vim" {
+		s.WriteString("\n" + m.renderVimStatus(position))
+	} else {
+		s.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render(position))
+	}
 	
+	helpText := "Ctrl+S: Save | Ctrl+T: Edit title | Ctrl+P: Preview | Ctrl+]: Follow link | Ctrl+O: Previous note | Esc: Back | i: Insert"
+	switch {
+	case m.promptText() != "":
+		helpText = m.renderPrompt()
+	case m.findActive:
+		helpText = m.findHelp()
+	case m.editorMode == "vim":
+		helpText = ":w Save | :q Quit | :set novim Leave vim mode | Ctrl+P: Preview | Ctrl+T: Edit title"
+	}
 	help := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240")).
 		PaddingTop(1).
-		Render("Ctrl+S: Save | Ctrl+T: Edit title | Ctrl+P: Preview | Esc: Back | i: Insert")
+		Render(helpText)
 	
 	s.WriteString("\n" + help)
 	
 	return s.String()
 }
 
+// renderVimStatus renders vim's status line: the command line while one
+// is being typed, otherwise the mode or last message, the pending keys and
+// the cursor position
+func (m *MainModel) renderVimStatus(position string) string {
+	theme := Themes[m.currentTheme]
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	
+	if m.vim.Mode == editor.VimCommandLine {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Foreground)).
+			Render(m.vim.Prompt + m.vim.CommandLine + "█")
+	}
+	
+	var left string
+	switch {
+	case m.vim.Message != "":
+		style := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Foreground))
+		if m.vim.MessageIsError() {
+			style = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+		}
+		left = style.Render(m.vim.Message)
+	case m.vim.Mode != editor.VimNormal:
+		left = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Accent)).
+			Render("-- " + m.vim.Mode.String() + " --")
+	}
+	
+	right := dim.Render(fmt.Sprintf("%-10s %s", m.vim.Pending(), position))
+	gap := m.width - 4 - lipgloss.Width(left) - lipgloss.Width(right)
+	if gap < 1 {
+		gap = 1
+	}
+	return left + strings.Repeat(" ", gap) + right
+}
+
 func (m *MainModel) renderPreview() string {
 	var s strings.Builder
 	
@@ -589,10 +862,14 @@ func (m *MainModel) renderPreview() string {
 	s.WriteString(m.previewViewport.View())
 	
 	// Help text
+	helpText := "↑/↓: Scroll | [/]: Select link | Enter: Follow | Alt+←/→: Previous/next note | n: Code line numbers | Esc/q: Back | e: Edit"
+	if m.promptText() != "" {
+		helpText = m.renderPrompt()
+	}
 	help := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240")).
 		PaddingTop(1).
-		Render("↑/↓: Scroll | Esc/q: Back | e: Edit")
+		Render(helpText)
 	
 	s.WriteString("\n" + help)
 	
@@ -608,25 +885,84 @@ func (m *MainModel) renderSearch() string {
 		Render("Search Notes")
 	
 	s.WriteString(title + "\n\n")
-	s.WriteString(m.searchInput.View() + "\n\n")
+	s.WriteString(m.searchInput.View() + "\n")
+	
+	if m.searchError != nil {
+		// Point at the offending part of the query under the input
+		errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+		offset := lipgloss.Width(m.searchInput.Prompt) + lipgloss.Width(m.searchQuery[:m.searchError.Pos])
+		s.WriteString(errStyle.Render(strings.Repeat(" ", offset)+"^") + "\n")
+		s.WriteString(errStyle.Render("✗ "+m.searchError.Msg) + "\n")
+		s.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render(`Filters: tag:work title:"standup" links:"Project X" has:todo created:>2026-01-01 updated:last7d`) + "\n")
+		s.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render(`Combine with AND, OR, NOT or -word, group with ( ), quote "exact phrases"`) + "\n")
+	}
+	s.WriteString("\n")
 	
 	if len(m.searchResults) > 0 {
 		s.WriteString(fmt.Sprintf("Found %d results:\n\n", len(m.searchResults)))
-		for _, result := range m.searchResults {
-			s.WriteString(fmt.Sprintf("  • %s\n", result.Title()))
+		
+		styles := m.getStyles()
+		theme := Themes[m.currentTheme]
+		highlight := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(theme.Accent)).
+			Bold(true)
+		snippetStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+		
+		// Each result takes three lines; keep the cursor in view
+		visible := (m.height - 10) / 3
+		if visible < 1 {
+			visible = 1
+		}
+		first := 0
+		if m.searchCursor >= visible {
+			first = m.searchCursor - visible + 1
+		}
+		
+		for i := first; i < len(m.searchResults) && i < first+visible; i++ {
+			result := m.searchResults[i]
+			if i == m.searchCursor {
+				s.WriteString(styles["selected"].Render("▶ "+result.item.Title()) + "\n")
+			} else {
+				s.WriteString("  " + result.item.Title() + "\n")
+			}
+			s.WriteString("    " + renderSnippet(result.snippet, result.spans, snippetStyle, highlight) + "\n\n")
 		}
+	} else if strings.TrimSpace(m.searchQuery) != "" && m.searchError == nil {
+		s.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Render("No matching notes") + "\n")
 	}
 	
 	help := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240")).
 		PaddingTop(1).
-		Render("Enter: Search | Esc: Back")
+		Render("↑/↓: Select | Enter: Open | Esc: Back")
 	
 	s.WriteString("\n" + help)
 	
 	return s.String()
 }
 
+// renderSnippet styles a search snippet, highlighting the matched spans
+func renderSnippet(snippet string, spans []search.Span, base, highlight lipgloss.Style) string {
+	var s strings.Builder
+	pos := 0
+	for _, span := range spans {
+		if span.Start < pos || span.End > len(snippet) {
+			continue
+		}
+		s.WriteString(base.Render(snippet[pos:span.Start]))
+		s.WriteString(highlight.Render(snippet[span.Start:span.End]))
+		pos = span.End
+	}
+	s.WriteString(base.Render(snippet[pos:]))
+	return s.String()
+}
+
 func (m *MainModel) renderTags() string {
 	var s strings.Builder
 	
@@ -32,21 +32,11 @@ func (m *MainModel) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	
 	// Expand/collapse folders
-	case "left":
-		if m.sidebarCursor < len(m.notes) {
-			note := m.notes[m.sidebarCursor]
-			if note.isFolder {
-				m.sidebarExpanded[note.title] = !m.sidebarExpanded[note.title]
-			}
-		}
+	case "left", "h":
+		m.collapseOrLeave()
 		return m, nil
 	case "l", "right":
-		if m.sidebarCursor < len(m.notes) {
-			note := m.notes[m.sidebarCursor]
-			if note.isFolder {
-				m.sidebarExpanded[note.title] = !m.sidebarExpanded[note.title]
-			}
-		}
+		m.expandOrEnter()
 		return m, nil
 	
 	// Edit note
@@ -58,12 +48,13 @@ func (m *MainModel) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		
 		if m.sidebarCursor < len(notesToUse) {
 			note := notesToUse[m.sidebarCursor]
-			if !note.isFolder {
-				loadedNote, err := m.loadNoteFromFile(note.path)
+			if note.isFolder {
+				m.toggleFolder(note.path)
+			} else {
+				loadedNote, err := m.store.Get(note.path)
 				if err == nil {
 					m.currentNote = loadedNote
-					m.editor.SetValue(loadedNote.Content)
-					m.editorText = loadedNote.Content
+					m.loadEditor(loadedNote)
 					m.currentView = "editor"
 				}
 			}
@@ -77,7 +68,7 @@ func (m *MainModel) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	
 	// New note from template
 	case "ctrl+n":
-		m.templates = m.LoadTemplates()
+		m.templates, _ = m.store.Templates()
 		if len(m.templates) > 0 {
 			m.currentView = "template_select"
 		} else {
@@ -87,12 +78,17 @@ func (m *MainModel) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	
 	// New folder
 	case "N":
-		// Create new folder - for now, just create a note with folder-like name
-		// In a full implementation, you'd create a directory
-		return m, nil
+		return m.openFolderName("")
+	
+	// Move the note or folder to another folder
+	case "m":
+		return m.openMovePicker()
 	
-	// Edit title
+	// Edit title, or rename the selected folder
 	case "t":
+		if item, ok := m.selectedItem(); ok && item.isFolder {
+			return m.openFolderName(item.path)
+		}
 		if m.currentNote != nil {
 			m.editingTitle = true
 			m.titleInput.SetValue(m.currentNote.Title)
@@ -105,7 +101,7 @@ func (m *MainModel) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Version history
 	case "ctrl+h":
 		if m.currentNote != nil {
-			versions, err := m.LoadVersions(m.currentNote.Path)
+			versions, err := m.store.Versions(m.currentNote.Path)
 			if err == nil {
 				m.versions = versions
 				m.selectedVersion = 0
@@ -114,6 +110,22 @@ func (m *MainModel) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 	
+	// Replace across all notes
+	case "R":
+		return m.openGlobalReplace()
+	
+	// Broken links and orphans
+	case "L":
+		return m.openLinkHealth()
+	
+	// Links around the note, and clusters of linked notes
+	case "G":
+		return m.openGraph()
+	
+	// Open todos across notes
+	case "T":
+		return m.openTasks()
+	
 	// Theme selector
 	case "ctrl+t":
 		m.cycleTheme()
@@ -121,9 +133,13 @@ func (m *MainModel) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	
 	// Archive/Delete
 	case "backspace", "d":
-		if m.sidebarCursor < len(m.notes) {
-			note := m.notes[m.sidebarCursor]
-			m.deleteNote(note.path)
+		if note, ok := m.selectedItem(); ok {
+			if note.isFolder {
+				// Folders take their notes with them, so ask first
+				m.folderDelete = note.path
+			} else {
+				m.deleteNote(note.path)
+			}
 		}
 		return m, nil
 	
@@ -158,6 +174,23 @@ func (m *MainModel) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.clearFilter()
 		return m, nil
 	
+	// Wiki links
+	case "]":
+		m.selectLink(1)
+		return m, nil
+	case "[":
+		m.selectLink(-1)
+		return m, nil
+	case "o":
+		m.followSelectedLink()
+		return m, nil
+	case "ctrl+o", "alt+left":
+		m.navigate(true)
+		return m, nil
+	case "alt+right":
+		m.navigate(false)
+		return m, nil
+	
 	// Quick actions
 	case "g":
 		return m.quickJump()
@@ -219,9 +252,10 @@ func (m *MainModel) selectNote() {
 	if m.sidebarCursor < len(notesToUse) {
 		note := notesToUse[m.sidebarCursor]
 		if !note.isFolder {
-			loadedNote, err := m.loadNoteFromFile(note.path)
+			loadedNote, err := m.store.Get(note.path)
 			if err == nil {
 				m.currentNote = loadedNote
+				m.linkCursor = -1
 			}
 		}
 	}
@@ -231,12 +265,8 @@ func (m *MainModel) selectNote() {
 func (m *MainModel) handleTitleEditKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "enter":
-		// Save title
-		if m.currentNote != nil {
-			m.currentNote.Title = m.titleInput.Value()
-			m.saveCurrentNote()
-			m.loadNotes() // Refresh sidebar to show updated title
-		}
+		// Save title, refreshing the sidebar, and offer to fix links to the old one
+		m.renameCurrentNote(m.titleInput.Value())
 		m.editingTitle = false
 		m.currentView = "main"
 		return m, nil
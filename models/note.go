@@ -1,63 +1,43 @@
 package models
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/ssh-notes/terminal-notes/editor"
+	"github.com/ssh-notes/terminal-notes/figlet"
 	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/markdown"
+	"github.com/ssh-notes/terminal-notes/search"
+	"github.com/ssh-notes/terminal-notes/store"
 	"github.com/ssh-notes/terminal-notes/utils"
 )
 
-type Note struct {
-	Title      string    `json:"title"`
-	Content    string    `json:"content"`
-	Tags       []string  `json:"tags"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	Path       string    `json:"path"`
-	Encrypted  bool      `json:"encrypted"`
-}
+// Persisted types live in the store package
+type (
+	Note     = store.Note
+	Version  = store.Version
+	Template = store.Template
+	TodoItem = store.TodoItem
+)
 
 func (m *MainModel) loadNotes() {
 	m.notes = []NoteItem{}
-	
-	// Ensure data directory exists
-	if err := os.MkdirAll(m.dataDir, 0700); err != nil {
-		return
-	}
-	
-	// Load folders and notes
-	entries, err := os.ReadDir(m.dataDir)
-	if err != nil {
+	m.folderItems = make(map[string][]NoteItem)
+	m.backlinksFor = "" // links may have changed
+	m.embedsFor = ""
+	m.linkIndex = nil
+	
+	// Load folders and notes, all the way down
+	if err := m.loadTree(); err != nil {
+		logger.Error("Failed to list notes: %v", err)
 		return
 	}
 	
-	for _, entry := range entries {
-		if entry.IsDir() {
-			m.notes = append(m.notes, NoteItem{
-				title:    entry.Name(),
-				path:     filepath.Join(m.dataDir, entry.Name()),
-				isFolder: true,
-			})
-		} else if strings.HasSuffix(entry.Name(), ".json") {
-			note, err := m.loadNoteFromFile(filepath.Join(m.dataDir, entry.Name()))
-			if err == nil {
-				m.notes = append(m.notes, NoteItem{
-					title: note.Title,
-					path:  filepath.Join(m.dataDir, entry.Name()),
-					tags:  note.Tags,
-				})
-			}
-		}
-	}
-	
-	// Apply sorting
+	// Apply sorting, which also lays out the tree
 	m.SortNotes(m.sortMode)
 	
 	// Apply filtering if active
@@ -74,51 +54,17 @@ func (m *MainModel) loadNotes() {
 	m.browserList.SetItems(items)
 }
 
-func (m *MainModel) loadNoteFromFile(path string) (*Note, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	
-	var note Note
-	if err := json.Unmarshal(data, &note); err != nil {
-		return nil, err
-	}
-	
-	// Decrypt if needed
-	if note.Encrypted {
-		decrypted, err := decryptNote(note.Content)
-		if err == nil {
-			note.Content = decrypted
-		}
-	}
-	
-	note.Path = path
-	return &note, nil
+func (m *MainModel) createNewNote() {
+	m.createNote("Untitled Note")
 }
 
-func (m *MainModel) createNewNote() {
-	// Generate filename from timestamp
-	filename := fmt.Sprintf("note_%d.json", time.Now().Unix())
-	
-	// Validate filename
-	if err := utils.ValidateFilename(filename); err != nil {
-		logger.Error("Invalid filename: %v", err)
-		return
-	}
-	
-	path := filepath.Join(m.dataDir, filename)
-	
-	// Sanitize path
-	safePath, err := utils.SanitizePath(path)
-	if err != nil {
-		logger.Error("Invalid path: %v", err)
-		return
-	}
-	path = safePath
+// createNote creates an empty note with the given title and opens it in
+// the editor
+func (m *MainModel) createNote(title string) {
+	path := m.newNotePath(m.currentFolder())
 	
 	note := &Note{
-		Title:     "Untitled Note",
+		Title:     title,
 		Content:   "",
 		Tags:      []string{},
 		CreatedAt: time.Now(),
@@ -127,28 +73,7 @@ func (m *MainModel) createNewNote() {
 		Encrypted: false,
 	}
 	
-	// Validate note data
-	if err := utils.ValidateTitle(note.Title); err != nil {
-		logger.Error("Invalid title: %v", err)
-		return
-	}
-	if err := utils.ValidateContent(note.Content); err != nil {
-		logger.Error("Invalid content: %v", err)
-		return
-	}
-	if err := utils.ValidateTags(note.Tags); err != nil {
-		logger.Error("Invalid tags: %v", err)
-		return
-	}
-	
-	// Save the note first using safe write
-	data, err := json.MarshalIndent(note, "", "  ")
-	if err != nil {
-		logger.Error("Failed to marshal note: %v", err)
-		return
-	}
-	
-	if err := utils.SafeWriteFile(path, data, 0600); err != nil {
+	if err := m.store.Put(note); err != nil {
 		logger.Error("Failed to save note: %v", err)
 		return
 	}
@@ -156,28 +81,69 @@ func (m *MainModel) createNewNote() {
 	logger.LogRequest(m.username, "create_note", nil)
 	
 	m.currentNote = note
-	m.editor.SetValue("")
-	m.editorText = ""
+	m.loadEditor(note)
 	m.editingTitleInEditor = false
 	m.currentView = "editor"
 	m.loadNotes() // Refresh sidebar
 }
 
+// newNotePath generates a filename in folder from the timestamp, numbered
+// if a note made this second already has it
+func (m *MainModel) newNotePath(folder string) string {
+	stamp := time.Now().Unix()
+	name := fmt.Sprintf("note_%d.json", stamp)
+	for i := 2; ; i++ {
+		notePath := name
+		if folder != "" {
+			notePath = folder + "/" + name
+		}
+		if _, err := m.store.Get(notePath); errors.Is(err, store.ErrNotFound) {
+			return notePath
+		}
+		name = fmt.Sprintf("note_%d_%d.json", stamp, i)
+	}
+}
+
 func (m *MainModel) openNote(path string) {
-	note, err := m.loadNoteFromFile(path)
+	note, err := m.store.Get(path)
 	if err != nil {
 		return
 	}
 	
 	m.currentNote = note
-	m.editor.SetValue(note.Content)
-	m.editorText = note.Content
+	m.loadEditor(note)
 	m.editingTitleInEditor = false
 	m.currentView = "editor"
 	// Don't change view - stay in main view to show in two-pane
 	// User can press 'e' or 'enter' to edit
 }
 
+// loadEditor puts a note's text in the editor. Each note keeps its own undo
+// history for the session, and reopening the note the editor already holds
+// keeps the cursor where it was, so toggling the preview loses nothing.
+func (m *MainModel) loadEditor(note *Note) {
+	if note.Path == m.editorPath && note.Content == m.editorText {
+		return
+	}
+	
+	m.vim.Reset(&m.editor)
+	m.closeFind()
+	if note.Path == m.editorPath {
+		// Changed behind the editor's back; keep that undoable too
+		m.editor.Replace(note.Content)
+	} else {
+		history, ok := m.histories[note.Path]
+		if !ok {
+			history = editor.NewHistory()
+			m.histories[note.Path] = history
+		}
+		m.editor.SetHistory(history)
+		m.editor.SetValue(note.Content)
+	}
+	m.editorText = note.Content
+	m.editorPath = note.Path
+}
+
 func (m *MainModel) saveCurrentNote() {
 	if m.currentNote == nil {
 		return
@@ -218,46 +184,38 @@ func (m *MainModel) saveCurrentNote() {
 		return
 	}
 	
-	// Create backup before saving
-	if err := utils.BackupFile(m.currentNote.Path); err != nil {
-		logger.Warn("Failed to create backup: %v", err)
-	}
-	
 	// Save version history before overwriting
-	if err := m.SaveVersion(m.currentNote); err != nil {
+	if err := m.store.SaveVersion(m.currentNote); err != nil {
 		logger.Warn("Failed to save version: %v", err)
 	}
 	
-	// Save to file using safe write
-	data, err := json.MarshalIndent(m.currentNote, "", "  ")
-	if err != nil {
-		logger.Error("Failed to marshal note: %v", err)
-		return
-	}
-	
-	if err := utils.SafeWriteFile(m.currentNote.Path, data, 0600); err != nil {
+	if err := m.store.Put(m.currentNote); err != nil {
 		logger.Error("Failed to save note: %v", err)
 		return
 	}
+	m.linkIndex = nil // its title and links may have changed
 	
 	logger.LogRequest(m.username, "save_note", nil)
 }
 
 func (m *MainModel) deleteNote(path string) {
-	os.Remove(path)
+	if err := m.store.Delete(path); err != nil {
+		logger.Error("Failed to delete note: %v", err)
+	}
 	m.loadNotes()
 }
 
 func (m *MainModel) previewNote(path string) {
-	note, err := m.loadNoteFromFile(path)
+	note, err := m.store.Get(path)
 	if err != nil {
 		return
 	}
 	
 	m.currentNote = note
-	m.previewContent = renderMarkdown(note.Content)
-	m.previewViewport.SetContent(m.previewContent)
+	m.linkCursor = -1
 	m.currentView = "preview"
+	m.embedsFor = "" // embedded notes may have changed since
+	m.refreshPreview()
 }
 
 func (m *MainModel) previewCurrentNote() {
@@ -268,63 +226,90 @@ func (m *MainModel) previewCurrentNote() {
 	// Update content from editor
 	m.currentNote.Content = m.editorText
 	
-	// Render markdown
-	m.previewContent = renderMarkdown(m.currentNote.Content)
-	
-	// Set viewport content and ensure proper sizing
-	m.previewViewport.SetContent(m.previewContent)
+	// Ensure proper sizing, then render markdown to fit
 	m.previewViewport.Width = m.width - 4
 	previewHeight := m.height - 6
 	if previewHeight < 1 {
 		previewHeight = 1
 	}
 	m.previewViewport.Height = previewHeight
+	m.linkCursor = -1
 	
 	// Switch to preview view
 	m.currentView = "preview"
+	m.embedsFor = "" // embedded notes may have changed since
+	m.refreshPreview()
+}
+
+// refreshPreview renders the current note into the preview viewport at its
+// width; it is called again when the width, theme or selected link changes
+func (m *MainModel) refreshPreview() {
+	if m.currentNote == nil {
+		return
+	}
+	var linkLine int
+	m.previewContent, linkLine = m.renderMarkdown(m.currentNote.Path, m.previewMarkdown(), m.previewViewport.Width, m.linkCursor)
+	m.previewViewport.SetContent(m.previewContent)
+	
+	// Scroll the selected link into view
+	if linkLine >= 0 {
+		if linkLine < m.previewViewport.YOffset {
+			m.previewViewport.SetYOffset(linkLine)
+		} else if linkLine >= m.previewViewport.YOffset+m.previewViewport.Height {
+			m.previewViewport.SetYOffset(linkLine - m.previewViewport.Height + 1)
+		}
+	}
+}
+
+// searchLimit caps the number of ranked results shown for a query
+const searchLimit = 50
+
+// searchResult is a ranked search hit with a highlighted snippet of its note
+type searchResult struct {
+	item    NoteItem
+	snippet string
+	spans   []search.Span
 }
 
 func (m *MainModel) performSearch() {
-	m.searchResults = []NoteItem{}
-	query := strings.ToLower(m.searchQuery)
+	m.searchResults = nil
+	m.searchCursor = 0
+	m.searchError = nil
 	
-	if query == "" {
+	if strings.TrimSpace(m.searchQuery) == "" {
 		return
 	}
 	
-	// Search through all notes
-	err := filepath.Walk(m.dataDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+	hits, err := m.store.Search(m.searchQuery, searchLimit)
+	if err != nil {
+		var parseErr *search.ParseError
+		if errors.As(err, &parseErr) {
+			m.searchError = parseErr
+			return
+		}
+		logger.Error("Search failed: %v", err)
+		return
+	}
+	
+	snippetWidth := m.width - 8
+	if snippetWidth < 20 {
+		snippetWidth = 20
+	}
+	
+	for _, hit := range hits {
+		result := searchResult{
+			item: NoteItem{
+				title: hit.Title,
+				path:  hit.Path,
+				tags:  hit.Tags,
+			},
 		}
 		
-		if !info.IsDir() && strings.HasSuffix(path, ".json") {
-			note, err := m.loadNoteFromFile(path)
-			if err != nil {
-				return nil
-			}
-			
-			// Search in title, content, and tags
-			content := strings.ToLower(note.Content)
-			title := strings.ToLower(note.Title)
-			tags := strings.ToLower(strings.Join(note.Tags, " "))
-			
-			if strings.Contains(title, query) ||
-				strings.Contains(content, query) ||
-				strings.Contains(tags, query) {
-				m.searchResults = append(m.searchResults, NoteItem{
-					title: note.Title,
-					path:  path,
-					tags:  note.Tags,
-				})
-			}
+		if note, err := m.store.Get(hit.Path); err == nil {
+			result.snippet, result.spans = search.Snippet(note.Content, hit.Words, snippetWidth)
 		}
 		
-		return nil
-	})
-	
-	if err != nil {
-		return
+		m.searchResults = append(m.searchResults, result)
 	}
 }
 
@@ -354,122 +339,63 @@ func (m *MainModel) showSettings() {
 	// TODO: Implement settings
 }
 
-// renderMarkdown provides an enhanced markdown renderer for terminal
-func renderMarkdown(md string) string {
-	lines := strings.Split(md, "\n")
-	var result strings.Builder
-	inCodeBlock := false
-	codeBlockLang := ""
-	
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		
-		// Handle code blocks
-		if strings.HasPrefix(trimmed, "```") {
-			if !inCodeBlock {
-				inCodeBlock = true
-				codeBlockLang = strings.TrimPrefix(trimmed, "```")
-				result.WriteString("\n" + strings.Repeat("─", 60) + "\n")
-				if codeBlockLang != "" {
-					result.WriteString("Code: " + codeBlockLang + "\n")
-					result.WriteString(strings.Repeat("─", 60) + "\n")
-				}
-			} else {
-				inCodeBlock = false
-				result.WriteString(strings.Repeat("─", 60) + "\n\n")
-			}
-			continue
-		}
-		
-		if inCodeBlock {
-			// In code block - preserve formatting
-			result.WriteString(line + "\n")
-			continue
-		}
-		
-		// Headings with figlet-style rendering
-		if strings.HasPrefix(line, "# ") {
-			// H1 - Use figlet if available, otherwise bold with underline
-			text := strings.TrimPrefix(line, "# ")
-			figletText := renderFiglet(text, "standard")
-			if figletText != "" {
-				result.WriteString("\n" + figletText + "\n")
-			} else {
-				// Fallback: bold with underline
-				result.WriteString("\n" + strings.ToUpper(text) + "\n")
-				result.WriteString(strings.Repeat("═", len(text)) + "\n")
-			}
-		} else if strings.HasPrefix(line, "## ") {
-			// H2 - Use smaller figlet font
-			text := strings.TrimPrefix(line, "## ")
-			figletText := renderFiglet(text, "small")
-			if figletText != "" {
-				result.WriteString("\n" + figletText + "\n")
-			} else {
-				// Fallback
-				result.WriteString("\n" + text + "\n")
-				result.WriteString(strings.Repeat("─", len(text)) + "\n")
-			}
-		} else if strings.HasPrefix(line, "### ") {
-			// H3 - Bold text
-			text := strings.TrimPrefix(line, "### ")
-			result.WriteString("\n" + strings.ToUpper(text) + "\n")
-		} else if strings.HasPrefix(line, "#### ") {
-			// H4
-			text := strings.TrimPrefix(line, "#### ")
-			result.WriteString("\n" + text + "\n")
-		} else if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
-			// List item
-			item := strings.TrimPrefix(strings.TrimPrefix(line, "- "), "* ")
-			// Check for checkbox
-			if strings.HasPrefix(item, "[ ]") {
-				result.WriteString("  ☐ " + strings.TrimPrefix(item, "[ ]") + "\n")
-			} else if strings.HasPrefix(item, "[x]") || strings.HasPrefix(item, "[X]") {
-				result.WriteString("  ☑ " + strings.TrimPrefix(strings.TrimPrefix(item, "[x]"), "[X]") + "\n")
-			} else {
-				result.WriteString("  • " + item + "\n")
-			}
-		} else if strings.HasPrefix(line, "> ") {
-			// Blockquote
-			quote := strings.TrimPrefix(line, "> ")
-			result.WriteString("  │ " + quote + "\n")
-		} else if strings.HasPrefix(line, "**") && strings.HasSuffix(line, "**") {
-			// Bold text
-			text := strings.Trim(strings.TrimPrefix(strings.TrimSuffix(line, "**"), "**"), " ")
-			result.WriteString(strings.ToUpper(text) + "\n")
-		} else if strings.HasPrefix(line, "*") && strings.HasSuffix(line, "*") && !strings.HasPrefix(line, "**") {
-			// Italic text
-			text := strings.Trim(strings.TrimPrefix(strings.TrimSuffix(line, "*"), "*"), " ")
-			result.WriteString(text + "\n")
-		} else if strings.HasPrefix(line, "`") && strings.HasSuffix(line, "`") {
-			// Inline code
-			code := strings.Trim(strings.TrimPrefix(strings.TrimSuffix(line, "`"), "`"), " ")
-			result.WriteString("[" + code + "]\n")
-		} else if trimmed == "" {
-			// Empty line
-			result.WriteString("\n")
-		} else if strings.HasPrefix(trimmed, "---") || strings.HasPrefix(trimmed, "***") {
-			// Horizontal rule
-			result.WriteString(strings.Repeat("─", 60) + "\n")
-		} else {
-			// Regular text
-			result.WriteString(line + "\n")
+// bannerFonts are the fonts tried, in order, for headings of each level.
+// A heading that fits none of them is drawn as styled text.
+var bannerFonts = map[int][]string{
+	1: {"standard", "small"},
+	2: {"small"},
+}
+
+// maxBannerLines is the most lines a banner heading may wrap onto
+const maxBannerLines = 2
+
+// bannerKey identifies a rendered heading in the banner cache
+type bannerKey struct {
+	level int
+	width int
+	text  string
+}
+
+// renderMarkdown renders a note for the preview at the given width, styled
+// with the current theme, and returns the line of the selected link too.
+// Banner headings are cached per note, so only headings that changed are
+// drawn again.
+func (m *MainModel) renderMarkdown(notePath, md string, width, link int) (string, int) {
+	cached := m.bannerCache[notePath]
+	used := make(map[bannerKey]string)
+	
+	style := markdownStyle(Themes[m.currentTheme])
+	style.CodeLineNumbers = m.codeLineNumbers
+	style.ResolveLink = m.linkText
+	style.Banner = func(level int, text string, width int) string {
+		key := bannerKey{level: level, width: width, text: text}
+		banner, ok := cached[key]
+		if !ok {
+			banner = renderBanner(level, text, width)
 		}
+		used[key] = banner
+		return banner
 	}
+	rendered, linkLine := markdown.RenderWithLink(md, width, style, link)
 	
-	return result.String()
+	// Drop headings the note no longer has
+	m.bannerCache[notePath] = used
+	return rendered, linkLine
 }
 
-// renderFiglet attempts to render text using figlet (if available)
-// Returns empty string if figlet is not available
-func renderFiglet(text, font string) string {
-	// Try to execute figlet command
-	cmd := exec.Command("figlet", "-f", font, "-w", "60", text)
-	output, err := cmd.Output()
-	if err != nil {
-		// Figlet not available, return empty to use fallback
-		return ""
+// renderBanner draws a heading in the first of its level's fonts that it
+// fits in, or returns "" if there is none
+func renderBanner(level int, text string, width int) string {
+	for _, name := range bannerFonts[level] {
+		font, err := figlet.Lookup(name)
+		if err != nil {
+			logger.Error("Failed to load banner font: %v", err)
+			continue
+		}
+		if banner, ok := font.Render(text, width, maxBannerLines); ok {
+			return banner
+		}
 	}
-	return string(output)
+	return ""
 }
 
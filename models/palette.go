@@ -0,0 +1,287 @@
+package models
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+	"github.com/ssh-notes/terminal-notes/logger"
+)
+
+// paletteRecencyBonus is the score bonus of a note edited just now; it
+// halves every paletteRecencyHalfLife
+const (
+	paletteRecencyBonus    = 20.0
+	paletteRecencyHalfLife = 7 * 24 * time.Hour
+)
+
+// paletteEntry is a note in the quick-jump palette. Label is what the
+// fuzzy matcher sees: the title, then the folder and tags.
+type paletteEntry struct {
+	path      string
+	title     string
+	label     string
+	titleLen  int
+	updatedAt time.Time
+}
+
+// paletteMatch is an entry matching the current pattern
+type paletteMatch struct {
+	entry   paletteEntry
+	matched []int // byte offsets of matched characters in entry.label
+	score   float64
+}
+
+type paletteSource []paletteEntry
+
+func (s paletteSource) String(i int) string { return s[i].label }
+func (s paletteSource) Len() int            { return len(s) }
+
+// quickJump opens the fuzzy quick-jump palette
+func (m *MainModel) quickJump() (tea.Model, tea.Cmd) {
+	m.paletteInput = textinput.New()
+	m.paletteInput.Placeholder = "Jump to note..."
+	m.paletteInput.Prompt = "❯ "
+	m.paletteInput.Focus()
+
+	m.paletteEntries = nil
+	m.palettePreviews = make(map[string]*Note)
+
+	metas, err := m.store.Metadata()
+	if err != nil {
+		logger.Error("Failed to load note index: %v", err)
+	}
+	for _, meta := range metas {
+		label := meta.Title
+		if folder := path.Dir(meta.Path); folder != "." {
+			label += "  " + folder + "/"
+		}
+		if len(meta.Tags) > 0 {
+			label += "  #" + strings.Join(meta.Tags, " #")
+		}
+		m.paletteEntries = append(m.paletteEntries, paletteEntry{
+			path:      meta.Path,
+			title:     meta.Title,
+			label:     label,
+			titleLen:  len(meta.Title),
+			updatedAt: meta.UpdatedAt,
+		})
+	}
+
+	m.updatePaletteMatches()
+	m.currentView = "palette"
+	return m, textinput.Blink
+}
+
+// recencyBonus favours recently edited notes among similar matches
+func recencyBonus(updatedAt time.Time) float64 {
+	age := time.Since(updatedAt)
+	if age < 0 {
+		age = 0
+	}
+	return paletteRecencyBonus * math.Pow(0.5, float64(age)/float64(paletteRecencyHalfLife))
+}
+
+// updatePaletteMatches re-ranks the palette for the current pattern
+func (m *MainModel) updatePaletteMatches() {
+	pattern := strings.TrimSpace(m.paletteInput.Value())
+	m.paletteMatches = m.paletteMatches[:0]
+	m.paletteCursor = 0
+
+	if pattern == "" {
+		for _, entry := range m.paletteEntries {
+			m.paletteMatches = append(m.paletteMatches, paletteMatch{
+				entry: entry,
+				score: recencyBonus(entry.updatedAt),
+			})
+		}
+	} else {
+		for _, match := range fuzzy.FindFrom(pattern, paletteSource(m.paletteEntries)) {
+			entry := m.paletteEntries[match.Index]
+			m.paletteMatches = append(m.paletteMatches, paletteMatch{
+				entry:   entry,
+				matched: match.MatchedIndexes,
+				score:   float64(match.Score) + recencyBonus(entry.updatedAt),
+			})
+		}
+	}
+
+	sort.SliceStable(m.paletteMatches, func(i, j int) bool {
+		a, b := m.paletteMatches[i], m.paletteMatches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.entry.updatedAt.After(b.entry.updatedAt)
+	})
+}
+
+func (m *MainModel) handlePaletteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.currentView = "main"
+		return m, nil
+	case "up", "ctrl+p", "ctrl+k":
+		if m.paletteCursor > 0 {
+			m.paletteCursor--
+		}
+		return m, nil
+	case "down", "ctrl+n", "ctrl+j":
+		if m.paletteCursor < len(m.paletteMatches)-1 {
+			m.paletteCursor++
+		}
+		return m, nil
+	case "enter":
+		if m.paletteCursor < len(m.paletteMatches) {
+			m.openNote(m.paletteMatches[m.paletteCursor].entry.path)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	before := m.paletteInput.Value()
+	m.paletteInput, cmd = m.paletteInput.Update(msg)
+	if m.paletteInput.Value() != before {
+		m.updatePaletteMatches()
+	}
+	return m, cmd
+}
+
+// palettePreview loads the highlighted note, caching it while the palette
+// is open
+func (m *MainModel) palettePreview(notePath string) *Note {
+	if note, ok := m.palettePreviews[notePath]; ok {
+		return note
+	}
+	note, err := m.store.Get(notePath)
+	if err != nil {
+		note = nil
+	}
+	m.palettePreviews[notePath] = note
+	return note
+}
+
+func (m *MainModel) renderPalette() string {
+	styles := m.getStyles()
+	theme := Themes[m.currentTheme]
+
+	listWidth := m.width * 2 / 5
+	if listWidth < 24 {
+		listWidth = 24
+	}
+	previewWidth := m.width - listWidth - 4
+	bodyHeight := m.height - 4
+	if bodyHeight < 3 {
+		bodyHeight = 3
+	}
+
+	// Match list
+	var list strings.Builder
+	list.WriteString(m.paletteInput.View() + "\n")
+	list.WriteString(lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render(strings.Repeat("─", listWidth-1)) + "\n")
+
+	visible := bodyHeight - 2
+	first := 0
+	if m.paletteCursor >= visible {
+		first = m.paletteCursor - visible + 1
+	}
+
+	highlight := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Accent)).Bold(true).Underline(true)
+	titleText := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Foreground))
+	detailText := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	for i := first; i < len(m.paletteMatches) && i < first+visible; i++ {
+		match := m.paletteMatches[i]
+		label := truncateToWidth(match.entry.label, listWidth-3)
+
+		var line strings.Builder
+		matched := make(map[int]bool, len(match.matched))
+		for _, idx := range match.matched {
+			matched[idx] = true
+		}
+		for idx, r := range label {
+			style := detailText
+			if idx < match.entry.titleLen {
+				style = titleText
+			}
+			if matched[idx] {
+				style = highlight
+			}
+			line.WriteString(style.Render(string(r)))
+		}
+
+		if i == m.paletteCursor {
+			list.WriteString(styles["selected"].Render("▶ ") + line.String() + "\n")
+		} else {
+			list.WriteString("  " + line.String() + "\n")
+		}
+	}
+
+	if len(m.paletteMatches) == 0 {
+		list.WriteString(detailText.Render("  No matching notes") + "\n")
+	}
+
+	// Preview of the highlighted note
+	var preview strings.Builder
+	if m.paletteCursor < len(m.paletteMatches) && previewWidth > 10 {
+		entry := m.paletteMatches[m.paletteCursor].entry
+		if note := m.palettePreview(entry.path); note != nil {
+			preview.WriteString(styles["title"].Render(note.Title) + "\n")
+			meta := entry.path
+			if len(note.Tags) > 0 {
+				meta += " • " + strings.Join(note.Tags, ", ")
+			}
+			if !note.UpdatedAt.IsZero() {
+				meta += " • " + note.UpdatedAt.Format("2006-01-02 15:04")
+			}
+			preview.WriteString(detailText.Render(truncateToWidth(meta, previewWidth-2)) + "\n\n")
+
+			body := lipgloss.NewStyle().Width(previewWidth - 2).Render(note.Content)
+			lines := strings.Split(body, "\n")
+			if limit := bodyHeight - 3; len(lines) > limit {
+				lines = lines[:limit]
+			}
+			preview.WriteString(strings.Join(lines, "\n"))
+		}
+	}
+
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.NewStyle().Width(listWidth).Height(bodyHeight).Render(list.String()),
+		styles["main"].Width(previewWidth).Height(bodyHeight).Render(preview.String()),
+	)
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render("Type to filter | ↑/↓: Select | Enter: Open | Esc: Back")
+
+	return body + "\n" + help
+}
+
+// truncateToWidth shortens s to at most width terminal cells
+func truncateToWidth(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := lipgloss.Width(string(r))
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return b.String() + "…"
+}
@@ -1,14 +1,12 @@
 package models
 
 import (
-	"encoding/json"
 	"fmt"
-	"path/filepath"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/bubbles/textinput"
-	"github.com/ssh-notes/terminal-notes/utils"
+	"github.com/ssh-notes/terminal-notes/logger"
 )
 
 // QuickActions handles quick action commands
@@ -37,23 +35,26 @@ func (m *MainModel) handleQuickAction(key string) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m *MainModel) quickJump() (tea.Model, tea.Cmd) {
-	// Show a quick input to jump to note by name
-	m.searchMode = true
-	m.currentView = "search"
-	m.searchInput.Focus()
-	return m, textinput.Blink
-}
-
 func (m *MainModel) showRecentNotes() (tea.Model, tea.Cmd) {
 	// Sort by modified date and show top 10
 	m.sortMode = SortByModified
 	m.SortNotes(m.sortMode)
 	
+	// Notes from every folder, listed flat
+	recent := []NoteItem{}
+	for _, note := range m.allItems("") {
+		if !note.isFolder {
+			note.depth = 0
+			recent = append(recent, note)
+		}
+	}
+	sortItems(recent, m.sortMode, m.noteMetadata())
+	
 	// Limit to 10 most recent
-	if len(m.notes) > 10 {
-		m.notes = m.notes[:10]
+	if len(recent) > 10 {
+		recent = recent[:10]
 	}
+	m.notes = recent
 	
 	return m, nil
 }
@@ -64,8 +65,7 @@ func (m *MainModel) duplicateNote() (tea.Model, tea.Cmd) {
 	}
 	
 	// Create a copy with new timestamp
-	newFilename := fmt.Sprintf("note_%d.json", time.Now().Unix())
-	newPath := filepath.Join(m.dataDir, newFilename)
+	newPath := m.newNotePath(parentFolder(m.currentNote.Path))
 	
 	duplicate := &Note{
 		Title:     m.currentNote.Title + " (Copy)",
@@ -78,15 +78,13 @@ func (m *MainModel) duplicateNote() (tea.Model, tea.Cmd) {
 	}
 	
 	// Save duplicate
-	data, err := json.MarshalIndent(duplicate, "", "  ")
-	if err == nil {
-		if err := utils.SafeWriteFile(newPath, data, 0600); err == nil {
-			m.loadNotes()
-			m.currentNote = duplicate
-			return m, nil
-		}
+	if err := m.store.Put(duplicate); err != nil {
+		logger.Error("Failed to duplicate note: %v", err)
+		return m, nil
 	}
 	
+	m.loadNotes()
+	m.currentNote = duplicate
 	return m, nil
 }
 
@@ -0,0 +1,376 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/store"
+)
+
+// Focus targets in the global replace view
+const (
+	replaceFocusPattern = iota
+	replaceFocusWith
+	replaceFocusNotes
+)
+
+// openGlobalReplace shows the replace-across-notes view. The last pattern
+// and replacement are kept between uses.
+func (m *MainModel) openGlobalReplace() (tea.Model, tea.Cmd) {
+	m.globalFindInput.Prompt = "Find:    "
+	m.globalFindInput.Placeholder = "text to replace in every note"
+	m.globalWithInput.Prompt = "Replace: "
+	m.globalWithInput.Placeholder = "replacement"
+
+	m.clearReplacePlan()
+	m.replaceStatus = ""
+	m.focusReplace(replaceFocusPattern)
+	m.currentView = "replace"
+	return m, textinput.Blink
+}
+
+// focusReplace moves the focus between the two fields and the note list
+func (m *MainModel) focusReplace(focus int) {
+	m.replaceFocus = focus
+	m.globalFindInput.Blur()
+	m.globalWithInput.Blur()
+	switch focus {
+	case replaceFocusPattern:
+		m.globalFindInput.Focus()
+	case replaceFocusWith:
+		m.globalWithInput.Focus()
+	}
+}
+
+// clearReplacePlan drops the preview, e.g. when the pattern changes
+func (m *MainModel) clearReplacePlan() {
+	m.replacePlan = nil
+	m.replaceSelected = nil
+	m.replaceCursor = 0
+	m.replaceScroll = 0
+	m.replaceConfirm = false
+	m.replaceError = ""
+}
+
+// planGlobalReplace works out the changes for the current fields, with
+// every note selected
+func (m *MainModel) planGlobalReplace() {
+	m.clearReplacePlan()
+	m.replaceStatus = ""
+	if m.globalFindInput.Value() == "" {
+		return
+	}
+
+	plan, err := store.PlanReplace(m.store, m.globalFindInput.Value(), m.globalWithInput.Value(), m.replaceOptions)
+	if err != nil {
+		m.replaceError = strings.TrimPrefix(err.Error(), "error parsing regexp: ")
+		return
+	}
+	m.replacePlan = plan
+	m.replaceSelected = make([]bool, len(plan))
+	for i := range m.replaceSelected {
+		m.replaceSelected[i] = true
+	}
+	if len(plan) == 0 {
+		m.replaceStatus = "No matches"
+		return
+	}
+	m.focusReplace(replaceFocusNotes)
+}
+
+// selectedReplacements returns the planned changes that are ticked
+func (m *MainModel) selectedReplacements() []store.Replacement {
+	var selected []store.Replacement
+	for i, change := range m.replacePlan {
+		if m.replaceSelected[i] {
+			selected = append(selected, change)
+		}
+	}
+	return selected
+}
+
+// applyGlobalReplace writes the ticked changes
+func (m *MainModel) applyGlobalReplace() {
+	selected := m.selectedReplacements()
+	m.replaceConfirm = false
+	if len(selected) == 0 {
+		return
+	}
+
+	if err := store.ApplyReplace(m.store, selected); err != nil {
+		logger.Error("Global replace failed: %v", err)
+		m.replaceError = err.Error()
+		return
+	}
+
+	for _, change := range selected {
+		if m.currentNote != nil && m.currentNote.Path == change.Path {
+			if note, err := m.store.Get(change.Path); err == nil {
+				m.currentNote = note
+			}
+		}
+	}
+	m.loadNotes()
+	m.clearReplacePlan()
+	m.replaceStatus = fmt.Sprintf("Replaced %s; the old text is in each note's version history",
+		store.Summarize(selected))
+	m.focusReplace(replaceFocusPattern)
+}
+
+func (m *MainModel) handleGlobalReplaceKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.replaceConfirm {
+		if msg.String() == "y" || msg.String() == "Y" {
+			m.applyGlobalReplace()
+		} else {
+			m.replaceConfirm = false
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+	case "esc":
+		if m.replaceFocus == replaceFocusNotes {
+			m.focusReplace(replaceFocusPattern)
+		} else {
+			m.currentView = "main"
+		}
+		return m, nil
+	case "tab", "shift+tab":
+		// The note list can only take the focus once there is a preview
+		targets := 2
+		if len(m.replacePlan) > 0 {
+			targets = 3
+		}
+		step := 1
+		if msg.String() == "shift+tab" {
+			step = targets - 1
+		}
+		m.focusReplace((m.replaceFocus + step) % targets)
+		return m, nil
+	case "alt+c":
+		m.replaceOptions.CaseSensitive = !m.replaceOptions.CaseSensitive
+		m.replanIfShown()
+		return m, nil
+	case "alt+r":
+		m.replaceOptions.Regex = !m.replaceOptions.Regex
+		m.replanIfShown()
+		return m, nil
+	case "alt+t":
+		m.replaceOptions.Titles = !m.replaceOptions.Titles
+		m.replanIfShown()
+		return m, nil
+	}
+
+	if m.replaceFocus == replaceFocusNotes {
+		return m.handleReplaceListKey(msg)
+	}
+
+	if msg.String() == "enter" {
+		m.planGlobalReplace()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	pattern, with := m.globalFindInput.Value(), m.globalWithInput.Value()
+	if m.replaceFocus == replaceFocusWith {
+		m.globalWithInput, cmd = m.globalWithInput.Update(msg)
+	} else {
+		m.globalFindInput, cmd = m.globalFindInput.Update(msg)
+	}
+	// A preview of different text would be misleading
+	if m.globalFindInput.Value() != pattern || m.globalWithInput.Value() != with {
+		m.clearReplacePlan()
+		m.replaceStatus = ""
+	}
+	return m, cmd
+}
+
+// replanIfShown refreshes the preview after an option changes
+func (m *MainModel) replanIfShown() {
+	if m.replacePlan != nil {
+		focus := m.replaceFocus
+		m.planGlobalReplace()
+		if len(m.replacePlan) > 0 {
+			m.focusReplace(focus)
+		}
+	}
+}
+
+// handleReplaceListKey handles the note list of the preview
+func (m *MainModel) handleReplaceListKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.replaceCursor > 0 {
+			m.replaceCursor--
+			m.replaceScroll = 0
+		}
+	case "down", "j":
+		if m.replaceCursor < len(m.replacePlan)-1 {
+			m.replaceCursor++
+			m.replaceScroll = 0
+		}
+	case "pgdown", "ctrl+d":
+		m.replaceScroll += m.replaceDiffHeight() / 2
+	case "pgup", "ctrl+u":
+		m.replaceScroll -= m.replaceDiffHeight() / 2
+		if m.replaceScroll < 0 {
+			m.replaceScroll = 0
+		}
+	case " ", "x":
+		if m.replaceCursor < len(m.replaceSelected) {
+			m.replaceSelected[m.replaceCursor] = !m.replaceSelected[m.replaceCursor]
+		}
+	case "a":
+		for i := range m.replaceSelected {
+			m.replaceSelected[i] = true
+		}
+	case "n":
+		for i := range m.replaceSelected {
+			m.replaceSelected[i] = false
+		}
+	case "enter":
+		m.replaceConfirm = len(m.selectedReplacements()) > 0
+	}
+	return m, nil
+}
+
+// replaceDiffHeight is the number of lines the diff preview has room for
+func (m *MainModel) replaceDiffHeight() int {
+	height := m.height - 9
+	if height < 3 {
+		height = 3
+	}
+	return height
+}
+
+func (m *MainModel) renderGlobalReplace() string {
+	styles := m.getStyles()
+	theme := Themes[m.currentTheme]
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	on := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Accent))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	toggle := func(label string, enabled bool) string {
+		if enabled {
+			return on.Render("[" + label + "]")
+		}
+		return dim.Render("[" + label + "]")
+	}
+
+	var s strings.Builder
+	s.WriteString(styles["title"].Render("Replace in All Notes") + "\n\n")
+	s.WriteString(m.globalFindInput.View() + "  " +
+		toggle("Aa", m.replaceOptions.CaseSensitive) + " " +
+		toggle(".*", m.replaceOptions.Regex) + " " +
+		toggle("Titles", m.replaceOptions.Titles) + "\n")
+	s.WriteString(m.globalWithInput.View() + "\n")
+
+	switch {
+	case m.replaceError != "":
+		s.WriteString(errStyle.Render(m.replaceError) + "\n")
+	case m.replaceStatus != "":
+		s.WriteString(dim.Render(m.replaceStatus) + "\n")
+	default:
+		s.WriteString("\n")
+	}
+
+	bodyHeight := m.replaceDiffHeight()
+	listWidth := m.width * 2 / 5
+	if listWidth < 24 {
+		listWidth = 24
+	}
+	diffWidth := m.width - listWidth - 4
+
+	// Notes with matches
+	var list strings.Builder
+	first := 0
+	if m.replaceCursor >= bodyHeight {
+		first = m.replaceCursor - bodyHeight + 1
+	}
+	for i := first; i < len(m.replacePlan) && i < first+bodyHeight; i++ {
+		change := m.replacePlan[i]
+		box := "[ ]"
+		if m.replaceSelected[i] {
+			box = "[x]"
+		}
+		line := truncateToWidth(fmt.Sprintf("%s %s (%d)", box, change.Title, change.Count), listWidth-3)
+		if i == m.replaceCursor && m.replaceFocus == replaceFocusNotes {
+			list.WriteString(styles["selected"].Render("▶ "+line) + "\n")
+		} else if i == m.replaceCursor {
+			list.WriteString("▶ " + line + "\n")
+		} else {
+			list.WriteString("  " + line + "\n")
+		}
+	}
+
+	// Diff of the highlighted note
+	var diff strings.Builder
+	if m.replaceCursor < len(m.replacePlan) && diffWidth > 10 {
+		diff.WriteString(m.renderReplaceDiff(m.replacePlan[m.replaceCursor], diffWidth-2, bodyHeight))
+	}
+
+	s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.NewStyle().Width(listWidth).Height(bodyHeight).Render(list.String()),
+		styles["main"].Width(diffWidth).Height(bodyHeight).Render(diff.String()),
+	) + "\n")
+
+	var help string
+	switch {
+	case m.replaceConfirm:
+		help = on.Render(fmt.Sprintf("Replace %s? (y/n)", store.Summarize(m.selectedReplacements())))
+	case m.replaceFocus == replaceFocusNotes:
+		help = dim.Render("↑/↓: Navigate | Space: Include/exclude | a/n: All/none | PgUp/PgDn: Scroll diff | Enter: Apply | Esc: Edit pattern")
+	default:
+		help = dim.Render("Enter: Preview | Tab: Switch field | Alt+C: Case | Alt+R: Regex | Alt+T: Titles | Esc: Back")
+	}
+	s.WriteString(help)
+	return s.String()
+}
+
+// renderReplaceDiff renders the changes to one note, starting at the
+// scroll offset
+func (m *MainModel) renderReplaceDiff(change store.Replacement, width, height int) string {
+	removed := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	added := lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var lines []string
+	lines = append(lines, dim.Render(truncateToWidth(change.Path, width)))
+	if change.NewTitle != change.Title {
+		lines = append(lines,
+			removed.Render(truncateToWidth("- title: "+change.Title, width)),
+			added.Render(truncateToWidth("+ title: "+change.NewTitle, width)))
+	}
+	if change.Before != change.After {
+		for _, line := range store.Diff(change.Before, change.After, 2) {
+			switch line.Kind {
+			case store.DiffGap:
+				lines = append(lines, dim.Render("  ⋯"))
+			case store.DiffRemoved:
+				lines = append(lines, removed.Render(truncateToWidth(fmt.Sprintf("-%4d %s", line.Line, line.Text), width)))
+			case store.DiffAdded:
+				lines = append(lines, added.Render(truncateToWidth(fmt.Sprintf("+%4d %s", line.Line, line.Text), width)))
+			default:
+				lines = append(lines, truncateToWidth(fmt.Sprintf(" %4d %s", line.Line, line.Text), width))
+			}
+		}
+	}
+
+	if m.replaceScroll > len(lines)-height {
+		m.replaceScroll = len(lines) - height
+	}
+	if m.replaceScroll < 0 {
+		m.replaceScroll = 0
+	}
+	lines = lines[m.replaceScroll:]
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/logger"
+)
+
+// sessionWarningWindow is how long before a timeout the countdown is shown
+const sessionWarningWindow = 60 * time.Second
+
+type sessionTickMsg time.Time
+
+// SetSessionLimits configures the idle timeout and the absolute session
+// limit. A zero duration disables that limit.
+func (m *MainModel) SetSessionLimits(idle, max time.Duration) {
+	now := time.Now()
+	m.idleTimeout = idle
+	m.lastActivity = now
+	if max > 0 {
+		m.sessionDeadline = now.Add(max)
+	}
+}
+
+// TimeoutReason returns why the session was ended by a timeout, or "" if it
+// wasn't
+func (m *MainModel) TimeoutReason() string {
+	return m.timeoutReason
+}
+
+func (m *MainModel) sessionTick() tea.Cmd {
+	if m.idleTimeout <= 0 && m.sessionDeadline.IsZero() {
+		return nil
+	}
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return sessionTickMsg(t)
+	})
+}
+
+// handleSessionTick updates the countdown and ends the session once the idle
+// or absolute limit has passed
+func (m *MainModel) handleSessionTick(now time.Time) tea.Cmd {
+	remaining, reason := m.sessionRemaining(now)
+	if reason == "" {
+		m.sessionWarning = ""
+		return m.sessionTick()
+	}
+
+	if remaining > 0 {
+		m.sessionWarning = fmt.Sprintf("%s: disconnecting in %ds", reason, int(remaining.Round(time.Second).Seconds()))
+		if reason == "Idle" {
+			m.sessionWarning += " - press any key to stay connected"
+		}
+		return m.sessionTick()
+	}
+
+	// Out of time: save any unsaved edits, whichever view is open (the
+	// preview or a dialog may be showing over them), and disconnect
+	if m.currentNote != nil && m.editorText != m.currentNote.Content {
+		m.saveCurrentNote()
+	}
+	m.timeoutReason = strings.ToLower(reason) + " timeout"
+	logger.Info("Session %s for user %s", m.timeoutReason, m.username)
+	return tea.Sequence(tea.ExitAltScreen, tea.Quit)
+}
+
+// sessionRemaining returns the time left before the nearest limit, and which
+// limit it is, if that limit is inside the warning window
+func (m *MainModel) sessionRemaining(now time.Time) (time.Duration, string) {
+	var remaining time.Duration
+	reason := ""
+
+	if m.idleTimeout > 0 {
+		idleLeft := m.idleTimeout - now.Sub(m.lastActivity)
+		if idleLeft <= sessionWarningWindow {
+			remaining, reason = idleLeft, "Idle"
+		}
+	}
+
+	if !m.sessionDeadline.IsZero() {
+		maxLeft := m.sessionDeadline.Sub(now)
+		if maxLeft <= sessionWarningWindow && (reason == "" || maxLeft < remaining) {
+			remaining, reason = maxLeft, "Session limit"
+		}
+	}
+
+	return remaining, reason
+}
+
+// renderSessionWarning overlays the countdown on the last line of a view
+func (m *MainModel) renderSessionWarning(view string) string {
+	if m.sessionWarning == "" {
+		return view
+	}
+
+	banner := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("230")).
+		Background(lipgloss.Color("160")).
+		Width(m.width).
+		Render("⚠ " + m.sessionWarning)
+
+	lines := strings.Split(view, "\n")
+	if len(lines) >= m.height && m.height > 0 {
+		lines = lines[:m.height-1]
+	}
+	return strings.Join(append(lines, banner), "\n")
+}
@@ -4,6 +4,9 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/store"
 )
 
 type SortMode string
@@ -16,44 +19,54 @@ const (
 	SortByModified   SortMode = "modified"
 )
 
+// noteMetadata returns the store's metadata index keyed by note path
+func (m *MainModel) noteMetadata() map[string]store.NoteMeta {
+	metas, err := m.store.Metadata()
+	if err != nil {
+		logger.Error("Failed to load note index: %v", err)
+	}
+	
+	byPath := make(map[string]store.NoteMeta, len(metas))
+	for _, meta := range metas {
+		byPath[meta.Path] = meta
+	}
+	return byPath
+}
+
+// SortNotes sorts the contents of each folder, folders first, and lays
+// the tree out in the sidebar
 func (m *MainModel) SortNotes(mode SortMode) {
-	switch mode {
-	case SortByDateNewest:
-		sort.Slice(m.notes, func(i, j int) bool {
-			noteI, _ := m.loadNoteFromFile(m.notes[i].path)
-			noteJ, _ := m.loadNoteFromFile(m.notes[j].path)
-			if noteI == nil || noteJ == nil {
-				return false
-			}
-			return noteI.CreatedAt.After(noteJ.CreatedAt)
-		})
-	case SortByDateOldest:
-		sort.Slice(m.notes, func(i, j int) bool {
-			noteI, _ := m.loadNoteFromFile(m.notes[i].path)
-			noteJ, _ := m.loadNoteFromFile(m.notes[j].path)
-			if noteI == nil || noteJ == nil {
-				return false
-			}
-			return noteI.CreatedAt.Before(noteJ.CreatedAt)
-		})
-	case SortByTitleAsc:
-		sort.Slice(m.notes, func(i, j int) bool {
-			return strings.ToLower(m.notes[i].title) < strings.ToLower(m.notes[j].title)
-		})
-	case SortByTitleDesc:
-		sort.Slice(m.notes, func(i, j int) bool {
-			return strings.ToLower(m.notes[i].title) > strings.ToLower(m.notes[j].title)
-		})
-	case SortByModified:
-		sort.Slice(m.notes, func(i, j int) bool {
-			noteI, _ := m.loadNoteFromFile(m.notes[i].path)
-			noteJ, _ := m.loadNoteFromFile(m.notes[j].path)
-			if noteI == nil || noteJ == nil {
-				return false
-			}
-			return noteI.UpdatedAt.After(noteJ.UpdatedAt)
-		})
+	metas := m.noteMetadata()
+	for _, items := range m.folderItems {
+		sortItems(items, mode, metas)
 	}
+	m.notes = m.visibleItems("")
+}
+
+func sortItems(items []NoteItem, mode SortMode, metas map[string]store.NoteMeta) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.isFolder != b.isFolder {
+			return a.isFolder
+		}
+		if a.isFolder {
+			return strings.ToLower(a.title) < strings.ToLower(b.title)
+		}
+		
+		switch mode {
+		case SortByDateNewest:
+			return metas[a.path].CreatedAt.After(metas[b.path].CreatedAt)
+		case SortByDateOldest:
+			return metas[a.path].CreatedAt.Before(metas[b.path].CreatedAt)
+		case SortByTitleAsc:
+			return strings.ToLower(a.title) < strings.ToLower(b.title)
+		case SortByTitleDesc:
+			return strings.ToLower(a.title) > strings.ToLower(b.title)
+		case SortByModified:
+			return metas[a.path].UpdatedAt.After(metas[b.path].UpdatedAt)
+		}
+		return false
+	})
 }
 
 func (m *MainModel) FilterNotesByTag(tag string) []NoteItem {
@@ -61,18 +74,15 @@ func (m *MainModel) FilterNotesByTag(tag string) []NoteItem {
 		return m.notes
 	}
 	
+	metas := m.noteMetadata()
 	filtered := []NoteItem{}
-	for _, note := range m.notes {
+	for _, note := range m.allItems("") {
 		if note.isFolder {
 			continue
 		}
+		note.depth = 0 // filtered notes are listed flat
 		
-		loadedNote, err := m.loadNoteFromFile(note.path)
-		if err != nil {
-			continue
-		}
-		
-		for _, noteTag := range loadedNote.Tags {
+		for _, noteTag := range metas[note.path].Tags {
 			if strings.EqualFold(noteTag, tag) {
 				filtered = append(filtered, note)
 				break
@@ -84,23 +94,24 @@ func (m *MainModel) FilterNotesByTag(tag string) []NoteItem {
 }
 
 func (m *MainModel) FilterNotesByDateRange(start, end time.Time) []NoteItem {
+	metas := m.noteMetadata()
 	filtered := []NoteItem{}
-	for _, note := range m.notes {
+	for _, note := range m.allItems("") {
 		if note.isFolder {
 			continue
 		}
+		note.depth = 0 // filtered notes are listed flat
 		
-		loadedNote, err := m.loadNoteFromFile(note.path)
-		if err != nil {
+		meta, ok := metas[note.path]
+		if !ok {
 			continue
 		}
 		
-		if (loadedNote.CreatedAt.After(start) || loadedNote.CreatedAt.Equal(start)) &&
-			(loadedNote.CreatedAt.Before(end) || loadedNote.CreatedAt.Equal(end)) {
+		if (meta.CreatedAt.After(start) || meta.CreatedAt.Equal(start)) &&
+			(meta.CreatedAt.Before(end) || meta.CreatedAt.Equal(end)) {
 			filtered = append(filtered, note)
 		}
 	}
 	
 	return filtered
 }
-
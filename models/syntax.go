@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ssh-notes/terminal-notes/editor"
+	"github.com/ssh-notes/terminal-notes/highlight"
+	"github.com/ssh-notes/terminal-notes/markdown"
+)
+
+// codeColors is the highlighting of the editor text it was worked out for
+type codeColors struct {
+	valid  bool
+	text   string
+	theme  string
+	colors [][]editor.Colored
+}
+
+// colorizeCode highlights the fenced code blocks of the note in the editor
+// the same way the preview does. The result is reused until the text or
+// the theme changes.
+func (m *MainModel) colorizeCode(text string) [][]editor.Colored {
+	if m.codeColors.valid && m.codeColors.text == text && m.codeColors.theme == m.currentTheme {
+		return m.codeColors.colors
+	}
+	
+	palette := syntaxPalette(Themes[m.currentTheme])
+	var colors [][]editor.Colored
+	for _, block := range markdown.CodeBlocks(text) {
+		code := make([]string, len(block.Lines))
+		for i, line := range block.Lines {
+			code[i] = line.Text
+		}
+		tokens, ok := highlight.Lex(block.Language, strings.Join(code, "\n"))
+		if !ok {
+			continue
+		}
+		
+		for i, line := range block.Lines {
+			if i >= len(tokens) {
+				break
+			}
+			for len(colors) <= line.Row {
+				colors = append(colors, nil)
+			}
+			col := line.Col
+			for _, token := range tokens[i] {
+				n := utf8.RuneCountInString(token.Text)
+				if token.Class != highlight.Plain {
+					colors[line.Row] = append(colors[line.Row], editor.Colored{
+						From:  col,
+						To:    col + n,
+						Style: palette[token.Class],
+					})
+				}
+				col += n
+			}
+		}
+	}
+	
+	m.codeColors = codeColors{valid: true, text: text, theme: m.currentTheme, colors: colors}
+	return colors
+}
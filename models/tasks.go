@@ -0,0 +1,412 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/store"
+)
+
+// taskItem is a todo in the tasks view. Only open todos are gathered, but
+// those ticked off while the view is open stay until it is reloaded, so a
+// slip can be undone.
+type taskItem struct {
+	path  string
+	title string // of the note
+	tags  []string
+	todo  store.TodoItem
+}
+
+// taskRow is a line of the tasks view: a group heading or a task
+type taskRow struct {
+	heading string
+	task    int // index into tasks
+}
+
+// Ways of grouping the tasks view, in the order Tab goes through them
+const (
+	taskGroupNote = iota
+	taskGroupTag
+	taskGroupAgenda
+	taskGroups
+)
+
+// priorityRank orders tasks with higher priorities first
+var priorityRank = map[string]int{
+	store.PriorityHigh:   0,
+	store.PriorityMedium: 1,
+	"":                   2,
+	store.PriorityLow:    3,
+}
+
+// openTasks gathers the open todos of every note
+func (m *MainModel) openTasks() (tea.Model, tea.Cmd) {
+	m.loadTasks()
+	m.currentView = "tasks"
+	return m, nil
+}
+
+func (m *MainModel) loadTasks() {
+	m.tasks = nil
+	m.taskCounts = make(map[string][2]int)
+	m.taskError = ""
+	err := m.store.Walk(func(note *Note) error {
+		done, total := note.CountTodos()
+		if done == total {
+			return nil
+		}
+		m.taskCounts[note.Path] = [2]int{done, total}
+		for _, todo := range note.ExtractTodos() {
+			if !todo.Completed {
+				m.tasks = append(m.tasks, taskItem{path: note.Path, title: note.Title, tags: note.Tags, todo: todo})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to gather tasks: %v", err)
+		m.taskError = err.Error()
+	}
+	m.sortTasks()
+	m.buildTaskRows()
+}
+
+// sortTasks puts the tasks in note order, and in each note by line
+func (m *MainModel) sortTasks() {
+	sort.SliceStable(m.tasks, func(i, j int) bool {
+		a, b := m.tasks[i], m.tasks[j]
+		if !strings.EqualFold(a.title, b.title) {
+			return strings.ToLower(a.title) < strings.ToLower(b.title)
+		}
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return a.todo.LineNum < b.todo.LineNum
+	})
+}
+
+// buildTaskRows groups the tasks by note; by tag, with untagged notes
+// last; or by when they are due
+func (m *MainModel) buildTaskRows() {
+	m.taskRows = nil
+	switch m.taskGroup {
+	case taskGroupNote:
+		for i, task := range m.tasks {
+			if i == 0 || task.path != m.tasks[i-1].path {
+				counts := m.taskCounts[task.path]
+				m.taskRows = append(m.taskRows, taskRow{heading: fmt.Sprintf("%s (%d/%d done)", task.title, counts[0], counts[1])})
+			}
+			m.taskRows = append(m.taskRows, taskRow{task: i})
+		}
+	case taskGroupTag:
+		// A note with several tags has its tasks under each
+		byTag := make(map[string][]int)
+		for i, task := range m.tasks {
+			if len(task.tags) == 0 {
+				byTag[""] = append(byTag[""], i)
+			}
+			for _, tag := range task.tags {
+				byTag[tag] = append(byTag[tag], i)
+			}
+		}
+		tags := make([]string, 0, len(byTag))
+		for tag := range byTag {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		sort.Strings(tags)
+		if len(byTag[""]) > 0 {
+			tags = append(tags, "")
+		}
+
+		for _, tag := range tags {
+			heading := "#" + tag
+			if tag == "" {
+				heading = "Untagged"
+			}
+			m.taskRows = append(m.taskRows, taskRow{heading: fmt.Sprintf("%s (%d)", heading, len(byTag[tag]))})
+			for _, i := range byTag[tag] {
+				m.taskRows = append(m.taskRows, taskRow{task: i})
+			}
+		}
+	case taskGroupAgenda:
+		m.buildAgendaRows(time.Now())
+	}
+
+	if m.taskCursor >= len(m.taskRows) {
+		m.taskCursor = len(m.taskRows) - 1
+	}
+	if len(m.taskRows) == 0 {
+		m.taskCursor = 0
+		return
+	}
+	if m.taskCursor < 0 || m.taskRows[m.taskCursor].heading != "" {
+		m.moveTaskCursor(1)
+	}
+}
+
+// buildAgendaRows lists the tasks that are overdue, due today and due in
+// the rest of the coming week, soonest and most important first. Tasks
+// without a due date are left out.
+func (m *MainModel) buildAgendaRows(now time.Time) {
+	sections := []struct {
+		heading string
+		in      func(todo store.TodoItem) bool
+	}{
+		{"Overdue", func(todo store.TodoItem) bool { return todo.Overdue(now) }},
+		{"Today", func(todo store.TodoItem) bool { return todo.DueWithin(now, 0) }},
+		{"This week", func(todo store.TodoItem) bool { return todo.DueWithin(now, 6) && !todo.DueWithin(now, 0) }},
+	}
+
+	for _, section := range sections {
+		var tasks []int
+		for i, task := range m.tasks {
+			if section.in(task.todo) {
+				tasks = append(tasks, i)
+			}
+		}
+		if len(tasks) == 0 {
+			continue
+		}
+
+		sort.SliceStable(tasks, func(i, j int) bool {
+			a, b := m.tasks[tasks[i]].todo, m.tasks[tasks[j]].todo
+			if !a.Due.Equal(b.Due) {
+				return a.Due.Before(b.Due)
+			}
+			return priorityRank[a.Priority] < priorityRank[b.Priority]
+		})
+		m.taskRows = append(m.taskRows, taskRow{heading: fmt.Sprintf("%s (%d)", section.heading, len(tasks))})
+		for _, i := range tasks {
+			m.taskRows = append(m.taskRows, taskRow{task: i})
+		}
+	}
+}
+
+// moveTaskCursor selects the next task in the given direction, skipping
+// headings
+func (m *MainModel) moveTaskCursor(step int) {
+	for i := m.taskCursor + step; i >= 0 && i < len(m.taskRows); i += step {
+		if m.taskRows[i].heading == "" {
+			m.taskCursor = i
+			return
+		}
+	}
+	if m.taskCursor < 0 {
+		m.taskCursor = 0
+	}
+}
+
+// selectedTask returns the selected task, if any
+func (m *MainModel) selectedTask() *taskItem {
+	if m.taskCursor < 0 || m.taskCursor >= len(m.taskRows) || m.taskRows[m.taskCursor].heading != "" {
+		return nil
+	}
+	return &m.tasks[m.taskRows[m.taskCursor].task]
+}
+
+// toggleTask ticks a task off, or back on, and saves its note
+func (m *MainModel) toggleTask(task *taskItem) {
+	note, err := m.store.Get(task.path)
+	if err != nil {
+		m.taskError = err.Error()
+		return
+	}
+
+	// The note may have been edited since the tasks were gathered
+	todos := note.ExtractTodos()
+	current := -1
+	for i, todo := range todos {
+		if todo.LineNum == task.todo.LineNum && todo.Text == task.todo.Text {
+			current = i
+		}
+	}
+	if current < 0 || todos[current].Completed != task.todo.Completed {
+		m.taskError = fmt.Sprintf("%q has changed; press r to reload", note.Title)
+		return
+	}
+
+	if err := m.store.SaveVersion(note); err != nil {
+		logger.Warn("Failed to save version: %v", err)
+	}
+	line := task.todo.LineNum
+	note.ToggleTodo(line)
+	note.UpdatedAt = time.Now()
+	if err := m.store.Put(note); err != nil {
+		logger.Error("Failed to save note: %v", err)
+		m.taskError = err.Error()
+		return
+	}
+	m.taskError = ""
+
+	task.todo.Completed = !task.todo.Completed
+	counts := m.taskCounts[task.path]
+	if task.todo.Completed {
+		counts[0]++
+	} else {
+		counts[0]--
+	}
+
+	// A recurring todo comes round again on the line below, and goes away
+	// again when it is unticked
+	switch after := note.ExtractTodos(); {
+	case len(after) > len(todos):
+		for i := range m.tasks {
+			if m.tasks[i].path == note.Path && m.tasks[i].todo.LineNum > line {
+				m.tasks[i].todo.LineNum++
+			}
+		}
+		m.tasks = append(m.tasks, taskItem{path: note.Path, title: note.Title, tags: note.Tags, todo: after[current+1]})
+		m.sortTasks()
+		counts[1]++
+	case len(after) < len(todos):
+		tasks := m.tasks[:0]
+		for _, t := range m.tasks {
+			if t.path == note.Path && t.todo.LineNum == line+1 {
+				continue
+			}
+			if t.path == note.Path && t.todo.LineNum > line+1 {
+				t.todo.LineNum--
+			}
+			tasks = append(tasks, t)
+		}
+		m.tasks = tasks
+		counts[1]--
+	}
+	m.taskCounts[note.Path] = counts
+	m.buildTaskRows()
+
+	if m.currentNote != nil && m.currentNote.Path == note.Path {
+		m.currentNote = note
+	}
+	if m.editorPath == note.Path {
+		m.loadEditor(note)
+	}
+	m.loadNotes()
+}
+
+// toggleTodoAtCursor ticks the todo on the editor's current line off, or
+// back on, as one undoable change
+func (m *MainModel) toggleTodoAtCursor() {
+	row, _ := m.editor.Cursor()
+	note := &Note{Content: m.editor.Value()}
+	if note.ToggleTodo(row) {
+		m.editor.Replace(note.Content)
+		m.editorText = m.editor.Value()
+	}
+}
+
+func (m *MainModel) handleTasksKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Sequence(tea.ExitAltScreen, tea.Quit)
+	case "esc", "q":
+		m.currentView = "main"
+	case "up", "k":
+		m.moveTaskCursor(-1)
+	case "down", "j":
+		m.moveTaskCursor(1)
+	case " ", "x", "ctrl+@":
+		if task := m.selectedTask(); task != nil {
+			m.toggleTask(task)
+		}
+	case "tab":
+		m.taskGroup = (m.taskGroup + 1) % taskGroups
+		m.taskCursor = 0
+		m.buildTaskRows()
+	case "r":
+		m.loadTasks()
+	case "enter", "o":
+		if task := m.selectedTask(); task != nil {
+			m.openNote(task.path)
+			if m.currentView == "editor" {
+				m.editor.SetCursor(task.todo.LineNum, 0)
+			}
+			m.revealInSidebar(task.path)
+		}
+	}
+	return m, nil
+}
+
+func (m *MainModel) renderTasks() string {
+	styles := m.getStyles()
+	theme := Themes[m.currentTheme]
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	heading := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Accent))
+	done := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Strikethrough(true)
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	overdue := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	open := 0
+	for _, task := range m.tasks {
+		if !task.todo.Completed {
+			open++
+		}
+	}
+	grouping := []string{"by note", "by tag", "agenda"}[m.taskGroup]
+	now := time.Now()
+
+	var s strings.Builder
+	s.WriteString(styles["title"].Render("Tasks") + "\n")
+	s.WriteString(dim.Render(fmt.Sprintf("%d open, %s", open, grouping)) + "\n\n")
+
+	switch {
+	case m.taskError != "":
+		s.WriteString(errStyle.Render(m.taskError) + "\n")
+	case len(m.tasks) == 0:
+		s.WriteString(dim.Render("No open tasks. Add one to a note with \"- [ ] ...\"") + "\n")
+	case len(m.taskRows) == 0:
+		s.WriteString(dim.Render("Nothing due this week. Give a task a date with due:2026-11-01") + "\n")
+	}
+
+	height := m.height - 6
+	if height < 3 {
+		height = 3
+	}
+	first := 0
+	if m.taskCursor >= height {
+		first = m.taskCursor - height + 1
+	}
+	for i := first; i < len(m.taskRows) && i < first+height; i++ {
+		row := m.taskRows[i]
+		if row.heading != "" {
+			s.WriteString(heading.Render(truncateToWidth(row.heading, m.width-2)) + "\n")
+			continue
+		}
+
+		task := m.tasks[row.task]
+		box := "[ ] "
+		if task.todo.Completed {
+			box = "[x] "
+		}
+		line := box + task.todo.Text
+		if m.taskGroup != taskGroupNote {
+			line += "  (" + task.title + ")"
+		}
+		if m.taskGroup == taskGroupAgenda {
+			line = task.todo.Due.Format("Mon Jan _2") + "  " + line
+		}
+		line = truncateToWidth(line, m.width-4)
+
+		switch {
+		case i == m.taskCursor:
+			s.WriteString(styles["selected"].Render("▶ "+line) + "\n")
+		case task.todo.Completed:
+			s.WriteString("  " + done.Render(line) + "\n")
+		case task.todo.Overdue(now):
+			s.WriteString("  " + overdue.Render(line) + "\n")
+		default:
+			s.WriteString("  " + line + "\n")
+		}
+	}
+
+	s.WriteString("\n" + dim.Render("↑/↓: Navigate | Space: Done/undone | Enter: Open note | Tab: By note/tag/agenda | r: Reload | Esc: Back"))
+	return s.String()
+}
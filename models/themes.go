@@ -2,6 +2,8 @@ package models
 
 import (
 	"github.com/charmbracelet/lipgloss"
+	"github.com/ssh-notes/terminal-notes/highlight"
+	"github.com/ssh-notes/terminal-notes/markdown"
 )
 
 type Theme struct {
@@ -15,6 +17,14 @@ type Theme struct {
 	Selected    string
 	Title       string
 	StatusBar   string
+	
+	// Syntax highlighting of code
+	Keyword     string
+	String      string
+	Number      string
+	Comment     string
+	Function    string
+	Type        string
 }
 
 var Themes = map[string]Theme{
@@ -29,6 +39,12 @@ var Themes = map[string]Theme{
 		Selected:   "205",
 		Title:      "62",
 		StatusBar:  "236",
+		Keyword:    "205",
+		String:     "114",
+		Number:     "215",
+		Comment:    "244",
+		Function:   "81",
+		Type:       "141",
 	},
 	"dark": {
 		Name:       "Dark",
@@ -41,6 +57,12 @@ var Themes = map[string]Theme{
 		Selected:   "51",
 		Title:      "33",
 		StatusBar:  "0",
+		Keyword:    "33",
+		String:     "78",
+		Number:     "214",
+		Comment:    "242",
+		Function:   "51",
+		Type:       "75",
 	},
 	"light": {
 		Name:       "Light",
@@ -53,6 +75,12 @@ var Themes = map[string]Theme{
 		Selected:   "205",
 		Title:      "62",
 		StatusBar:  "252",
+		Keyword:    "126",
+		String:     "28",
+		Number:     "130",
+		Comment:    "245",
+		Function:   "25",
+		Type:       "90",
 	},
 	"monokai": {
 		Name:       "Monokai",
@@ -65,6 +93,12 @@ var Themes = map[string]Theme{
 		Selected:   "197",
 		Title:      "141",
 		StatusBar:  "235",
+		Keyword:    "197",
+		String:     "186",
+		Number:     "141",
+		Comment:    "242",
+		Function:   "148",
+		Type:       "81",
 	},
 	"nord": {
 		Name:       "Nord",
@@ -77,6 +111,12 @@ var Themes = map[string]Theme{
 		Selected:   "103",
 		Title:      "109",
 		StatusBar:  "236",
+		Keyword:    "110",
+		String:     "150",
+		Number:     "139",
+		Comment:    "243",
+		Function:   "116",
+		Type:       "109",
 	},
 }
 
@@ -125,3 +165,70 @@ func (m *MainModel) getStyles() map[string]lipgloss.Style {
 	return GetThemeStyles(m.currentTheme)
 }
 
+// markdownStyle styles rendered markdown in a theme's colours
+func markdownStyle(theme Theme) markdown.Style {
+	color := func(c string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(c))
+	}
+	
+	return markdown.Style{
+		Text: color(theme.Foreground),
+		Headings: [6]lipgloss.Style{
+			color(theme.Title).Bold(true),
+			color(theme.Primary).Bold(true),
+			color(theme.Accent).Bold(true),
+			color(theme.Foreground).Bold(true),
+			color(theme.Foreground).Bold(true).Italic(true),
+			color(theme.Secondary).Bold(true),
+		},
+		Bold:         lipgloss.NewStyle().Bold(true),
+		Italic:       lipgloss.NewStyle().Italic(true),
+		Strike:       lipgloss.NewStyle().Strikethrough(true),
+		Code:         color(theme.Accent),
+		Link:         color(theme.Primary).Underline(true),
+		LinkURL:      color(theme.Secondary),
+		Quote:        color(theme.Secondary).Italic(true),
+		Bullet:       color(theme.Accent),
+		CodeBlock:    color(theme.Foreground),
+		CodeLabel:    color(theme.Secondary).Italic(true),
+		Rule:         color(theme.Border),
+		TableBorder:  color(theme.Border),
+		TableHeader:  color(theme.Title).Bold(true),
+		HTML:         color(theme.Secondary),
+		WikiLink:     wikiLinkStyle(theme),
+		BrokenLink:   brokenLinkStyle,
+		SelectedLink: selectedLinkStyle,
+		Syntax:       syntaxPalette(theme),
+		LineNumber:   color(theme.Secondary),
+	}
+}
+
+// Styles of [[links]] to other notes
+var (
+	brokenLinkStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	selectedLinkStyle = lipgloss.NewStyle().Reverse(true)
+)
+
+func wikiLinkStyle(theme Theme) lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Accent)).Underline(true)
+}
+
+// syntaxPalette colours highlighted code in a theme's colours
+func syntaxPalette(theme Theme) *highlight.Palette {
+	color := func(c string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(c))
+	}
+	
+	var palette highlight.Palette
+	palette[highlight.Plain] = color(theme.Foreground)
+	palette[highlight.Keyword] = color(theme.Keyword).Bold(true)
+	palette[highlight.Type] = color(theme.Type)
+	palette[highlight.Function] = color(theme.Function)
+	palette[highlight.Builtin] = color(theme.Type).Italic(true)
+	palette[highlight.Variable] = color(theme.Accent)
+	palette[highlight.String] = color(theme.String)
+	palette[highlight.Number] = color(theme.Number)
+	palette[highlight.Comment] = color(theme.Comment).Italic(true)
+	palette[highlight.Operator] = color(theme.Keyword)
+	return &palette
+}
@@ -0,0 +1,303 @@
+// Package search implements the full-text index behind note search: an
+// inverted index of stemmed terms, ranked with BM25 and persisted to disk
+// so it only needs updating when notes change.
+package search
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ssh-notes/terminal-notes/utils"
+)
+
+// formatVersion is bumped whenever tokenizing or the file layout changes, so
+// stale index files are rebuilt instead of misread
+const formatVersion = 1
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+
+	// prefixWeight scales the score of terms that only match as a prefix
+	prefixWeight = 0.6
+)
+
+// Field is a piece of a document to index. Weight multiplies each term
+// occurrence, so title words can count for more than body words.
+type Field struct {
+	Text   string
+	Weight int
+}
+
+// document is the indexed form of one document. Stamp is an opaque value
+// supplied by the caller to tell whether the document changed since.
+type document struct {
+	Stamp  string
+	Length int
+	Terms  map[string]int
+}
+
+// indexFile is the on-disk format of an Index
+type indexFile struct {
+	Version int
+	Docs    map[string]*document
+}
+
+// Index is an inverted index of documents keyed by ID. It is safe for
+// concurrent use.
+type Index struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	dirty  bool
+
+	docs     map[string]*document
+	postings map[string]map[string]int // term -> doc ID -> frequency
+	terms    []string                  // sorted, for prefix lookups; nil when stale
+	totalLen int
+}
+
+// New returns an index persisted at path. The file is read on first use.
+func New(path string) *Index {
+	return &Index{
+		path:     path,
+		docs:     make(map[string]*document),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// load reads the index file once; a missing, corrupt or outdated file just
+// leaves the index empty so callers re-add their documents
+func (ix *Index) load() {
+	if ix.loaded {
+		return
+	}
+	ix.loaded = true
+
+	data, err := os.ReadFile(ix.path)
+	if err != nil {
+		return
+	}
+
+	var file indexFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&file); err != nil {
+		return
+	}
+	if file.Version != formatVersion || file.Docs == nil {
+		return
+	}
+
+	for id, doc := range file.Docs {
+		ix.addDocument(id, doc)
+	}
+}
+
+// Save writes the index to disk if it changed since the last save
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if !ix.dirty {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(indexFile{Version: formatVersion, Docs: ix.docs}); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0700); err != nil {
+		return err
+	}
+	if err := utils.SafeWriteFile(ix.path, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	ix.dirty = false
+	return nil
+}
+
+// Stamp returns the stamp a document was indexed with, or "" if it isn't
+// indexed
+func (ix *Index) Stamp(id string) string {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.load()
+	if doc, ok := ix.docs[id]; ok {
+		return doc.Stamp
+	}
+	return ""
+}
+
+// IDs returns the ID of every indexed document
+func (ix *Index) IDs() []string {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.load()
+	ids := make([]string, 0, len(ix.docs))
+	for id := range ix.docs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Add indexes a document, replacing any previous version of it
+func (ix *Index) Add(id, stamp string, fields ...Field) {
+	doc := &document{Stamp: stamp, Terms: make(map[string]int)}
+	for _, field := range fields {
+		weight := field.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		for _, token := range Tokenize(field.Text) {
+			doc.Terms[token.Term] += weight
+			doc.Length += weight
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.load()
+	ix.removeDocument(id)
+	ix.addDocument(id, doc)
+	ix.dirty = true
+}
+
+// Remove drops a document from the index
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.load()
+	if _, ok := ix.docs[id]; ok {
+		ix.removeDocument(id)
+		ix.dirty = true
+	}
+}
+
+// Rename re-keys a document without re-indexing it
+func (ix *Index) Rename(from, to string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.load()
+	doc, ok := ix.docs[from]
+	if !ok {
+		return
+	}
+	ix.removeDocument(from)
+	ix.removeDocument(to)
+	ix.addDocument(to, doc)
+	ix.dirty = true
+}
+
+func (ix *Index) addDocument(id string, doc *document) {
+	if doc.Terms == nil {
+		doc.Terms = make(map[string]int)
+	}
+
+	ix.docs[id] = doc
+	ix.totalLen += doc.Length
+	for term, freq := range doc.Terms {
+		posting, ok := ix.postings[term]
+		if !ok {
+			posting = make(map[string]int)
+			ix.postings[term] = posting
+			ix.terms = nil
+		}
+		posting[id] = freq
+	}
+}
+
+func (ix *Index) removeDocument(id string) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+
+	delete(ix.docs, id)
+	ix.totalLen -= doc.Length
+	for term := range doc.Terms {
+		posting := ix.postings[term]
+		delete(posting, id)
+		if len(posting) == 0 {
+			delete(ix.postings, term)
+			ix.terms = nil
+		}
+	}
+}
+
+// sortedTerms returns every indexed term in order, rebuilding the list after
+// terms were added or removed
+func (ix *Index) sortedTerms() []string {
+	if ix.terms == nil {
+		ix.terms = make([]string, 0, len(ix.postings))
+		for term := range ix.postings {
+			ix.terms = append(ix.terms, term)
+		}
+		sort.Strings(ix.terms)
+	}
+	return ix.terms
+}
+
+// termScores adds the BM25 score of term for each document containing it
+// to scores, keeping the best score where a document already has one
+func (ix *Index) termScores(term string, weight float64, scores map[string]float64) {
+	posting := ix.postings[term]
+	if len(posting) == 0 {
+		return
+	}
+
+	n := float64(len(ix.docs))
+	df := float64(len(posting))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+	avgLen := float64(ix.totalLen) / n
+
+	for id, freq := range posting {
+		tf := float64(freq)
+		norm := 1 - b + b*float64(ix.docs[id].Length)/avgLen
+		score := weight * idf * tf * (k1 + 1) / (tf + k1*norm)
+		if score > scores[id] {
+			scores[id] = score
+		}
+	}
+}
+
+// Match scores every document containing word. Documents with the word's
+// stem score fully; with prefix set, documents with a longer word starting
+// with it ("prog" for "programming") score too, at a discount.
+func (ix *Index) Match(word string, prefix bool) map[string]float64 {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.load()
+	scores := make(map[string]float64)
+
+	word = strings.ToLower(word)
+	stem := Stem(word)
+	ix.termScores(stem, 1, scores)
+
+	if prefix && len(word) >= 2 {
+		terms := ix.sortedTerms()
+		for _, p := range []string{word, stem} {
+			i := sort.SearchStrings(terms, p)
+			for ; i < len(terms) && strings.HasPrefix(terms[i], p); i++ {
+				if terms[i] != stem {
+					ix.termScores(terms[i], prefixWeight, scores)
+				}
+			}
+		}
+	}
+
+	return scores
+}
@@ -0,0 +1,521 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Node is a parsed search query
+type Node interface {
+	node()
+}
+
+// TermNode matches notes containing a word. Prefix is set on a trailing
+// word that may still be being typed.
+type TermNode struct {
+	Word   string
+	Prefix bool
+}
+
+// PhraseNode matches notes containing Words next to each other
+type PhraseNode struct {
+	Text  string
+	Words []string
+}
+
+// FieldNode filters notes on a field: tag, title, links or has. Value is
+// lowercased.
+type FieldNode struct {
+	Field string
+	Value string
+}
+
+// DateNode filters notes on their created or updated time, From inclusive
+// and To exclusive; a zero bound is open
+type DateNode struct {
+	Field string
+	From  time.Time
+	To    time.Time
+}
+
+// AndNode matches notes matching every child
+type AndNode struct {
+	Children []Node
+}
+
+// OrNode matches notes matching any child
+type OrNode struct {
+	Children []Node
+}
+
+// NotNode matches notes that don't match Child
+type NotNode struct {
+	Child Node
+}
+
+func (TermNode) node()   {}
+func (PhraseNode) node() {}
+func (FieldNode) node()  {}
+func (DateNode) node()   {}
+func (AndNode) node()    {}
+func (OrNode) node()     {}
+func (NotNode) node()    {}
+
+// ParseError is a syntax error in a query. Pos is the byte offset of the
+// offending input.
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s (at column %d)", e.Msg, e.Pos+1)
+}
+
+// Fields lists the filters understood by Parse
+var Fields = []string{"tag", "title", "links", "has", "created", "updated"}
+
+// hasValues lists the values accepted by has:
+var hasValues = []string{"todo", "done", "tag", "link"}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPhrase
+	tokField
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokEOF
+)
+
+type queryToken struct {
+	kind  tokenKind
+	text  string // word, phrase text or field value
+	field string
+	pos   int
+	end   int
+}
+
+// lexQuery splits a query into tokens
+func lexQuery(input string) ([]queryToken, error) {
+	var tokens []queryToken
+
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokLParen, pos: i, end: i + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokRParen, pos: i, end: i + 1})
+			i++
+		case c == '-' && i+1 < len(input) && input[i+1] != ' ':
+			tokens = append(tokens, queryToken{kind: tokNot, pos: i, end: i + 1})
+			i++
+		case c == '"':
+			text, end, err := lexQuoted(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: tokPhrase, text: text, pos: i, end: end})
+			i = end
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n()\"", rune(input[i])) {
+				i++
+			}
+			word := input[start:i]
+
+			switch word {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokAnd, pos: start, end: i})
+				continue
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokOr, pos: start, end: i})
+				continue
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokNot, pos: start, end: i})
+				continue
+			}
+
+			field, value, isField := strings.Cut(word, ":")
+			if !isField || !isFieldName(field) || strings.HasPrefix(value, "//") {
+				tokens = append(tokens, queryToken{kind: tokWord, text: word, pos: start, end: i})
+				continue
+			}
+
+			field = strings.ToLower(field)
+			if !knownField(field) {
+				return nil, &ParseError{Pos: start, Msg: fmt.Sprintf("unknown filter %q (use %s)", field+":", strings.Join(Fields, ":, ")+":")}
+			}
+
+			// field:"quoted value"
+			if value == "" && i < len(input) && input[i] == '"' {
+				text, end, err := lexQuoted(input, i)
+				if err != nil {
+					return nil, err
+				}
+				value = text
+				i = end
+			}
+			if strings.TrimSpace(value) == "" {
+				return nil, &ParseError{Pos: start, Msg: fmt.Sprintf("%s: needs a value", field)}
+			}
+			tokens = append(tokens, queryToken{kind: tokField, field: field, text: value, pos: start, end: i})
+		}
+	}
+
+	tokens = append(tokens, queryToken{kind: tokEOF, pos: len(input), end: len(input)})
+	return tokens, nil
+}
+
+// lexQuoted reads a double-quoted string starting at input[start]
+func lexQuoted(input string, start int) (string, int, error) {
+	end := strings.IndexByte(input[start+1:], '"')
+	if end < 0 {
+		return "", 0, &ParseError{Pos: start, Msg: "unterminated quote"}
+	}
+	end += start + 1
+	return input[start+1 : end], end + 1, nil
+}
+
+func isFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func knownField(field string) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+type parser struct {
+	input  string
+	tokens []queryToken
+	pos    int
+	now    time.Time
+}
+
+// Parse parses a search query. Words are ANDed together unless joined by
+// OR; NOT or a leading - negates, and parentheses group. Relative dates
+// such as updated:last7d are resolved against now. An empty query parses
+// to nil.
+func Parse(input string, now time.Time) (Node, error) {
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{input: input, tokens: tokens, now: now}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := p.peek(); tok.kind {
+	case tokEOF:
+	case tokRParen:
+		return nil, &ParseError{Pos: tok.pos, Msg: "unmatched )"}
+	default:
+		return nil, &ParseError{Pos: tok.pos, Msg: "unexpected input"}
+	}
+	return node, nil
+}
+
+func (p *parser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []Node{left}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+
+	if len(children) == 1 {
+		return left, nil
+	}
+	return OrNode{Children: children}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var children []Node
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case tokEOF, tokRParen, tokOr:
+			if len(children) == 0 {
+				return nil, p.missingTerm(tok)
+			}
+			if len(children) == 1 {
+				return children[0], nil
+			}
+			return AndNode{Children: children}, nil
+		case tokAnd:
+			if len(children) == 0 {
+				return nil, &ParseError{Pos: tok.pos, Msg: "AND needs a term on both sides"}
+			}
+			p.next()
+			if next := p.peek(); next.kind == tokEOF || next.kind == tokRParen || next.kind == tokOr || next.kind == tokAnd {
+				return nil, &ParseError{Pos: tok.pos, Msg: "AND needs a term on both sides"}
+			}
+			continue
+		}
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if node != nil {
+			children = append(children, node)
+		}
+	}
+}
+
+// missingTerm reports an operator or group with nothing in it
+func (p *parser) missingTerm(tok queryToken) error {
+	switch {
+	case tok.kind == tokOr || (p.pos > 0 && p.tokens[p.pos-1].kind == tokOr):
+		return &ParseError{Pos: tok.pos, Msg: "OR needs a term on both sides"}
+	case tok.kind == tokRParen:
+		return &ParseError{Pos: tok.pos, Msg: "empty group"}
+	}
+	return &ParseError{Pos: tok.pos, Msg: "expected a search term"}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNot:
+		switch p.peek().kind {
+		case tokEOF, tokRParen, tokOr, tokAnd:
+			return nil, &ParseError{Pos: tok.pos, Msg: "NOT needs a term after it"}
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			return nil, &ParseError{Pos: tok.pos, Msg: "NOT needs a term after it"}
+		}
+		return NotNode{Child: child}, nil
+
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, &ParseError{Pos: tok.pos, Msg: "missing )"}
+		}
+		p.next()
+		return node, nil
+
+	case tokPhrase:
+		var words []string
+		for _, t := range Tokenize(tok.text) {
+			words = append(words, t.Word)
+		}
+		if len(words) == 0 {
+			return nil, &ParseError{Pos: tok.pos, Msg: "empty phrase"}
+		}
+		if len(words) == 1 {
+			return TermNode{Word: words[0]}, nil
+		}
+		return PhraseNode{Text: tok.text, Words: words}, nil
+
+	case tokField:
+		return p.parseField(tok)
+
+	case tokWord:
+		// A word like "foo-bar" holds several index words, all required
+		tokens := Tokenize(tok.text)
+		var terms []Node
+		for i, t := range tokens {
+			terms = append(terms, TermNode{
+				Word:   t.Word,
+				Prefix: i == len(tokens)-1 && tok.end == len(p.input),
+			})
+		}
+		switch len(terms) {
+		case 0:
+			return nil, nil
+		case 1:
+			return terms[0], nil
+		}
+		return AndNode{Children: terms}, nil
+	}
+
+	return nil, &ParseError{Pos: tok.pos, Msg: "expected a search term"}
+}
+
+func (p *parser) parseField(tok queryToken) (Node, error) {
+	value := strings.TrimSpace(tok.text)
+
+	switch tok.field {
+	case "created", "updated":
+		from, to, err := parseDateRange(value, p.now)
+		if err != nil {
+			return nil, &ParseError{Pos: tok.pos, Msg: fmt.Sprintf("%s: %v", tok.field, err)}
+		}
+		return DateNode{Field: tok.field, From: from, To: to}, nil
+
+	case "has":
+		value = strings.TrimSuffix(strings.ToLower(value), "s")
+		for _, v := range hasValues {
+			if v == value {
+				return FieldNode{Field: "has", Value: value}, nil
+			}
+		}
+		return nil, &ParseError{Pos: tok.pos, Msg: fmt.Sprintf("has: expects one of %s", strings.Join(hasValues, ", "))}
+	}
+
+	return FieldNode{Field: tok.field, Value: strings.ToLower(value)}, nil
+}
+
+// dateLayouts are the absolute date formats accepted in date filters
+var dateLayouts = []string{"2006-01-02", "2006-01-02T15:04", time.RFC3339}
+
+// parseDateRange parses a date filter value: a date with an optional
+// comparison (>2026-01-01, <=2026-03-31, 2026-02-14), today, yesterday, or
+// a relative window such as last7d, last12h, last2w, last3m or last1y
+func parseDateRange(value string, now time.Time) (from, to time.Time, err error) {
+	startOfDay := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+
+	switch strings.ToLower(value) {
+	case "today":
+		return startOfDay(now), time.Time{}, nil
+	case "yesterday":
+		today := startOfDay(now)
+		return today.AddDate(0, 0, -1), today, nil
+	}
+
+	if rest, ok := strings.CutPrefix(strings.ToLower(value), "last"); ok && len(rest) >= 2 {
+		n, err := strconv.Atoi(rest[:len(rest)-1])
+		if err != nil || n <= 0 {
+			return from, to, fmt.Errorf("invalid window %q", value)
+		}
+		switch rest[len(rest)-1] {
+		case 'h':
+			return now.Add(-time.Duration(n) * time.Hour), time.Time{}, nil
+		case 'd':
+			return now.AddDate(0, 0, -n), time.Time{}, nil
+		case 'w':
+			return now.AddDate(0, 0, -7*n), time.Time{}, nil
+		case 'm':
+			return now.AddDate(0, -n, 0), time.Time{}, nil
+		case 'y':
+			return now.AddDate(-n, 0, 0), time.Time{}, nil
+		}
+		return from, to, fmt.Errorf("invalid window %q (use h, d, w, m or y)", value)
+	}
+
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, candidate) {
+			op = candidate
+			value = value[len(candidate):]
+			break
+		}
+	}
+
+	var date time.Time
+	var dayOnly bool
+	for i, layout := range dateLayouts {
+		if date, err = time.ParseInLocation(layout, value, now.Location()); err == nil {
+			dayOnly = i == 0
+			break
+		}
+	}
+	if err != nil {
+		return from, to, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", value)
+	}
+
+	// A bare day covers the whole day; a time is a single instant
+	end := date.Add(time.Minute)
+	if dayOnly {
+		end = date.AddDate(0, 0, 1)
+	}
+
+	switch op {
+	case ">":
+		return end, time.Time{}, nil
+	case ">=":
+		return date, time.Time{}, nil
+	case "<":
+		return time.Time{}, date, nil
+	case "<=":
+		return time.Time{}, end, nil
+	}
+	return date, end, nil
+}
+
+// Words returns the words and phrase words of node that aren't negated,
+// for highlighting matches
+func Words(node Node) []string {
+	var words []string
+	var walk func(n Node)
+	walk = func(n Node) {
+		switch n := n.(type) {
+		case TermNode:
+			words = append(words, n.Word)
+		case PhraseNode:
+			words = append(words, n.Words...)
+		case AndNode:
+			for _, child := range n.Children {
+				walk(child)
+			}
+		case OrNode:
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+	return words
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Span is a highlighted byte range of a snippet
+type Span struct {
+	Start int
+	End   int
+}
+
+const ellipsis = "…"
+
+// Snippet returns up to width bytes of text around the first word matching
+// one of words, flattened to a single line, along with the spans of every
+// matching word inside it. Words match the way Search matches them: by
+// stem, or by prefix. If nothing matches, the start of text is returned.
+func Snippet(text string, words []string, width int) (string, []Span) {
+	text = strings.Join(strings.Fields(text), " ")
+	if width <= 0 || text == "" {
+		return "", nil
+	}
+
+	var stems, prefixes []string
+	for _, word := range words {
+		for _, token := range Tokenize(word) {
+			stems = append(stems, token.Term)
+			prefixes = append(prefixes, token.Word)
+		}
+	}
+
+	matches := func(token Token) bool {
+		for i := range stems {
+			if token.Term == stems[i] || strings.HasPrefix(token.Word, prefixes[i]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var hits []Token
+	for _, token := range Tokenize(text) {
+		if matches(token) {
+			hits = append(hits, token)
+		}
+	}
+
+	// Centre the window a third of the way in on the first hit
+	start := 0
+	if len(hits) > 0 && len(text) > width {
+		start = hits[0].Start - width/3
+		if start > len(text)-width {
+			start = len(text) - width
+		}
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + width
+	if end > len(text) {
+		end = len(text)
+	}
+
+	// Don't cut runes, and prefer not to cut words
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	if start > 0 {
+		if space := strings.IndexByte(text[start:end], ' '); space >= 0 && space < width/4 {
+			start += space + 1
+		}
+	}
+	if end < len(text) {
+		if space := strings.LastIndexByte(text[start:end], ' '); space > 0 && end-start-space < width/4 {
+			end = start + space
+		}
+	}
+
+	snippet := text[start:end]
+	offset := -start
+	if start > 0 {
+		snippet = ellipsis + snippet
+		offset += len(ellipsis)
+	}
+	if end < len(text) {
+		snippet += ellipsis
+	}
+
+	var spans []Span
+	for _, hit := range hits {
+		if hit.Start >= start && hit.End <= end {
+			spans = append(spans, Span{Start: hit.Start + offset, End: hit.End + offset})
+		}
+	}
+
+	return snippet, spans
+}
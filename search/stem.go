@@ -0,0 +1,235 @@
+package search
+
+// Stem reduces an English word to its stem with the Porter (1980)
+// algorithm, so "running", "runs" and "run" index to the same term.
+// Words that aren't plain lowercase ASCII are returned unchanged.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	b := []byte(word)
+	b = step1a(b)
+	b = step1b(b)
+	b = step1c(b)
+	b = step2(b)
+	b = step3(b)
+	b = step4(b)
+	b = step5(b)
+	return string(b)
+}
+
+// isConsonant reports whether b[i] is a consonant in Porter's sense: y is a
+// consonant at the start of a word or after a vowel
+func isConsonant(b []byte, i int) bool {
+	switch b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(b, i-1)
+	}
+	return true
+}
+
+// measure counts the VC sequences in b, Porter's m
+func measure(b []byte) int {
+	m := 0
+	i := 0
+	n := len(b)
+	for i < n && isConsonant(b, i) {
+		i++
+	}
+	for i < n {
+		for i < n && !isConsonant(b, i) {
+			i++
+		}
+		if i >= n {
+			break
+		}
+		for i < n && isConsonant(b, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+func hasVowel(b []byte) bool {
+	for i := range b {
+		if !isConsonant(b, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(b []byte) bool {
+	n := len(b)
+	return n >= 2 && b[n-1] == b[n-2] && isConsonant(b, n-1)
+}
+
+// endsCVC reports whether b ends consonant-vowel-consonant, where the last
+// consonant is not w, x or y
+func endsCVC(b []byte) bool {
+	n := len(b)
+	if n < 3 || !isConsonant(b, n-3) || isConsonant(b, n-2) || !isConsonant(b, n-1) {
+		return false
+	}
+	switch b[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func hasSuffix(b []byte, suffix string) bool {
+	return len(b) >= len(suffix) && string(b[len(b)-len(suffix):]) == suffix
+}
+
+func replaceSuffix(b []byte, suffix, replacement string) []byte {
+	return append(b[:len(b)-len(suffix)], replacement...)
+}
+
+func step1a(b []byte) []byte {
+	switch {
+	case hasSuffix(b, "sses"):
+		return replaceSuffix(b, "sses", "ss")
+	case hasSuffix(b, "ies"):
+		return replaceSuffix(b, "ies", "i")
+	case hasSuffix(b, "ss"):
+		return b
+	case hasSuffix(b, "s"):
+		return b[:len(b)-1]
+	}
+	return b
+}
+
+func step1b(b []byte) []byte {
+	if hasSuffix(b, "eed") {
+		if measure(b[:len(b)-3]) > 0 {
+			return b[:len(b)-1]
+		}
+		return b
+	}
+
+	stripped := false
+	for _, suffix := range []string{"ed", "ing"} {
+		if hasSuffix(b, suffix) && hasVowel(b[:len(b)-len(suffix)]) {
+			b = b[:len(b)-len(suffix)]
+			stripped = true
+			break
+		}
+	}
+	if !stripped {
+		return b
+	}
+
+	switch {
+	case hasSuffix(b, "at"), hasSuffix(b, "bl"), hasSuffix(b, "iz"):
+		return append(b, 'e')
+	case endsDoubleConsonant(b):
+		switch b[len(b)-1] {
+		case 'l', 's', 'z':
+			return b
+		}
+		return b[:len(b)-1]
+	case measure(b) == 1 && endsCVC(b):
+		return append(b, 'e')
+	}
+	return b
+}
+
+func step1c(b []byte) []byte {
+	if hasSuffix(b, "y") && hasVowel(b[:len(b)-1]) {
+		b[len(b)-1] = 'i'
+	}
+	return b
+}
+
+// applyLongest replaces the longest matching suffix when the remaining stem
+// has a measure above minMeasure
+func applyLongest(b []byte, rules [][2]string, minMeasure int) []byte {
+	best := -1
+	for i, rule := range rules {
+		if hasSuffix(b, rule[0]) && (best < 0 || len(rule[0]) > len(rules[best][0])) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return b
+	}
+
+	suffix, replacement := rules[best][0], rules[best][1]
+	if measure(b[:len(b)-len(suffix)]) > minMeasure {
+		return replaceSuffix(b, suffix, replacement)
+	}
+	return b
+}
+
+var step2Rules = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+func step2(b []byte) []byte {
+	return applyLongest(b, step2Rules, 0)
+}
+
+var step3Rules = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func step3(b []byte) []byte {
+	return applyLongest(b, step3Rules, 0)
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func step4(b []byte) []byte {
+	best := ""
+	for _, suffix := range step4Suffixes {
+		if hasSuffix(b, suffix) && len(suffix) > len(best) {
+			best = suffix
+		}
+	}
+	if best == "" {
+		return b
+	}
+
+	stem := b[:len(b)-len(best)]
+	if measure(stem) <= 1 {
+		return b
+	}
+	if best == "ion" && (len(stem) == 0 || (stem[len(stem)-1] != 's' && stem[len(stem)-1] != 't')) {
+		return b
+	}
+	return stem
+}
+
+func step5(b []byte) []byte {
+	if hasSuffix(b, "e") {
+		stem := b[:len(b)-1]
+		m := measure(stem)
+		if m > 1 || (m == 1 && !endsCVC(stem)) {
+			b = stem
+		}
+	}
+
+	if measure(b) > 1 && endsDoubleConsonant(b) && b[len(b)-1] == 'l' {
+		b = b[:len(b)-1]
+	}
+	return b
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a single word of a text. Word is the lowercased word as written,
+// Term is its stem, and Start/End are byte offsets into the text.
+type Token struct {
+	Word  string
+	Term  string
+	Start int
+	End   int
+}
+
+// isWordRune reports whether r belongs to a word. Apostrophes are kept
+// inside words so "don't" stays one token.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// Tokenize splits text into lowercased, stemmed words
+func Tokenize(text string) []Token {
+	var tokens []Token
+
+	start := -1
+	for i := 0; i <= len(text); {
+		r, size := utf8.RuneError, 0
+		if i < len(text) {
+			r, size = utf8.DecodeRuneInString(text[i:])
+		}
+
+		inWord := i < len(text) && (isWordRune(r) ||
+			(r == '\'' && start >= 0 && i+size < len(text) && isWordRune(nextRune(text[i+size:]))))
+
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			word := strings.ToLower(strings.ReplaceAll(text[start:i], "'", ""))
+			tokens = append(tokens, Token{
+				Word:  word,
+				Term:  Stem(word),
+				Start: start,
+				End:   i,
+			})
+			start = -1
+		}
+
+		if i == len(text) {
+			break
+		}
+		i += size
+	}
+
+	return tokens
+}
+
+func nextRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
@@ -0,0 +1,139 @@
+package store
+
+import "strings"
+
+// DiffKind says whether a diff line is kept, removed or added
+type DiffKind int
+
+const (
+	DiffContext DiffKind = iota
+	DiffRemoved
+	DiffAdded
+	DiffGap // stands for unchanged lines left out between hunks
+)
+
+// DiffLine is one line of a diff. Line is the line number in the old text
+// for context and removed lines, and in the new text for added lines.
+type DiffLine struct {
+	Kind DiffKind
+	Line int
+	Text string
+}
+
+// maxDiffCells caps the work of the line matching; past it, the changed
+// region is shown as a plain removal and addition
+const maxDiffCells = 4_000_000
+
+// Diff compares two texts line by line and returns the changed lines with
+// context lines of unchanged text around each change
+func Diff(before, after string, context int) []DiffLine {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// Unchanged lines at the start and end need no matching
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var all []DiffLine
+	for i := 0; i < prefix; i++ {
+		all = append(all, DiffLine{Kind: DiffContext, Line: i + 1, Text: a[i]})
+	}
+	all = append(all, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for i := len(a) - suffix; i < len(a); i++ {
+		all = append(all, DiffLine{Kind: DiffContext, Line: i + 1, Text: a[i]})
+	}
+
+	return trimContext(all, context)
+}
+
+// diffMiddle diffs the changed region using the longest common subsequence
+// of lines; offset is the number of lines before the region
+func diffMiddle(a, b []string, offset int) []DiffLine {
+	var out []DiffLine
+	if len(a)*len(b) > maxDiffCells {
+		for i, line := range a {
+			out = append(out, DiffLine{Kind: DiffRemoved, Line: offset + i + 1, Text: line})
+		}
+		for j, line := range b {
+			out = append(out, DiffLine{Kind: DiffAdded, Line: offset + j + 1, Text: line})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, DiffLine{Kind: DiffContext, Line: offset + i + 1, Text: a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, DiffLine{Kind: DiffRemoved, Line: offset + i + 1, Text: a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{Kind: DiffAdded, Line: offset + j + 1, Text: b[j]})
+			j++
+		}
+	}
+	return out
+}
+
+// trimContext keeps only the unchanged lines within context lines of a
+// change, marking each run left out with a DiffGap
+func trimContext(all []DiffLine, context int) []DiffLine {
+	keep := make([]bool, len(all))
+	for i, line := range all {
+		if line.Kind == DiffContext {
+			continue
+		}
+		for k := i - context; k <= i+context; k++ {
+			if k >= 0 && k < len(all) {
+				keep[k] = true
+			}
+		}
+	}
+	// A gap marker is no shorter than the single line it would stand for
+	for i := 1; i < len(all)-1; i++ {
+		if !keep[i] && keep[i-1] && keep[i+1] {
+			keep[i] = true
+		}
+	}
+
+	var out []DiffLine
+	skipped := false
+	for i, line := range all {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped && len(out) > 0 {
+			out = append(out, DiffLine{Kind: DiffGap})
+		}
+		skipped = false
+		out = append(out, line)
+	}
+	return out
+}
@@ -0,0 +1,222 @@
+package store
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Export exports all notes in s to a directory or archive
+func Export(s NoteStore, format, outputPath string) error {
+	switch format {
+	case "markdown", "md":
+		return exportToMarkdown(s, outputPath)
+	case "json":
+		return exportToJSON(s, outputPath)
+	case "tar":
+		return exportToTar(s, outputPath)
+	case "zip":
+		return exportToZip(s, outputPath)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func exportToMarkdown(s NoteStore, outputPath string) error {
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return err
+	}
+
+	return s.Walk(func(note *Note) error {
+		// Create markdown file
+		mdPath := strings.TrimSuffix(note.Path, ".json") + ".md"
+		mdPath = filepath.Join(outputPath, filepath.FromSlash(mdPath))
+
+		// Create directory if needed
+		if err := os.MkdirAll(filepath.Dir(mdPath), 0755); err != nil {
+			return err
+		}
+
+		// Write markdown
+		content := fmt.Sprintf("# %s\n\n", note.Title)
+		if len(note.Tags) > 0 {
+			content += fmt.Sprintf("Tags: %s\n\n", strings.Join(note.Tags, ", "))
+		}
+		content += fmt.Sprintf("Created: %s\nUpdated: %s\n\n",
+			note.CreatedAt.Format(time.RFC3339),
+			note.UpdatedAt.Format(time.RFC3339))
+		content += note.Content
+
+		return os.WriteFile(mdPath, []byte(content), 0644)
+	})
+}
+
+func exportToJSON(s NoteStore, outputPath string) error {
+	var notes []*Note
+
+	err := s.Walk(func(note *Note) error {
+		notes = append(notes, note)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+func exportToTar(s NoteStore, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var tw *tar.Writer
+	if strings.HasSuffix(outputPath, ".gz") {
+		gzw := gzip.NewWriter(file)
+		defer gzw.Close()
+		tw = tar.NewWriter(gzw)
+	} else {
+		tw = tar.NewWriter(file)
+	}
+	defer tw.Close()
+
+	return s.Walk(func(note *Note) error {
+		data, err := json.Marshal(note)
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Name:    note.Path,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: note.UpdatedAt,
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		_, err = tw.Write(data)
+		return err
+	})
+}
+
+func exportToZip(s NoteStore, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	defer zw.Close()
+
+	return s.Walk(func(note *Note) error {
+		f, err := zw.Create(note.Path)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(note)
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write(data)
+		return err
+	})
+}
+
+// Import imports notes from a directory or archive into s
+func Import(s NoteStore, format, inputPath string) error {
+	switch format {
+	case "markdown", "md":
+		return importFromMarkdown(s, inputPath)
+	case "json":
+		return importFromJSON(s, inputPath)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func importFromMarkdown(s NoteStore, inputPath string) error {
+	return filepath.Walk(inputPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if !info.IsDir() && strings.HasSuffix(path, ".md") {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+
+			// Parse markdown to extract title and content
+			lines := strings.Split(string(content), "\n")
+			title := info.Name()
+			noteContent := string(content)
+
+			if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+				title = strings.TrimPrefix(lines[0], "# ")
+				noteContent = strings.Join(lines[1:], "\n")
+			}
+
+			// Create note
+			note := &Note{
+				Title:     strings.TrimSuffix(title, ".md"),
+				Content:   noteContent,
+				Tags:      []string{},
+				CreatedAt: info.ModTime(),
+				UpdatedAt: time.Now(),
+				Path:      strings.TrimSuffix(info.Name(), ".md") + ".json",
+				Encrypted: false,
+			}
+
+			if err := s.Put(note); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func importFromJSON(s NoteStore, inputPath string) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	var notes []*Note
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return err
+	}
+
+	for _, note := range notes {
+		// Generate new path
+		note.Path = fmt.Sprintf("imported_%d.json", time.Now().UnixNano())
+		note.UpdatedAt = time.Now()
+
+		// Save note
+		if err := s.Put(note); err != nil {
+			continue
+		}
+	}
+
+	return nil
+}
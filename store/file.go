@@ -0,0 +1,489 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/utils"
+)
+
+const (
+	versionsDir  = ".versions"
+	templatesDir = ".templates"
+)
+
+// FileStore keeps each note as a JSON file under root, with folders as
+// directories, versions in root/.versions and templates in root/.templates
+type FileStore struct {
+	root string
+	idx  *metaIndex
+}
+
+func NewFileStore(root string) *FileStore {
+	return &FileStore{root: root, idx: indexFor(root)}
+}
+
+// Root returns the directory the store writes to
+func (s *FileStore) Root() string {
+	return s.root
+}
+
+// resolve maps a store path to a file path, refusing anything that would
+// escape the root
+func (s *FileStore) resolve(p string) (string, error) {
+	if strings.Contains(p, "\\") || path.IsAbs(p) {
+		return "", fmt.Errorf("invalid path: %q", p)
+	}
+	cleaned := path.Clean(p)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid path: %q", p)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// isHidden reports whether a directory entry is internal to the store
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+func (s *FileStore) List(folder string) ([]Entry, error) {
+	dir, err := s.resolve(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.root, 0700); err != nil {
+		return nil, err
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	metas := s.metasByPath()
+	entries := []Entry{}
+	for _, entry := range dirEntries {
+		// Skip hidden entries (.versions, .templates, .ssh)
+		if isHidden(entry.Name()) {
+			continue
+		}
+
+		if e, ok := newEntry(path.Join(folder, entry.Name()), entry.IsDir(), metas); ok {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *FileStore) ListAll(folder string) ([]Entry, error) {
+	dir, err := s.resolve(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.root, 0700); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	metas := s.metasByPath()
+	entries := []Entry{}
+	err = filepath.WalkDir(dir, func(filePath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == dir {
+			return nil
+		}
+		if isHidden(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+		if e, ok := newEntry(filepath.ToSlash(rel), d.IsDir(), metas); ok {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// metasByPath returns the indexed metadata keyed by note path
+func (s *FileStore) metasByPath() map[string]NoteMeta {
+	metas := make(map[string]NoteMeta)
+	for _, meta := range s.idx.all(s) {
+		metas[meta.Path] = meta
+	}
+	return metas
+}
+
+// newEntry describes the folder or note at entryPath. Files that aren't
+// indexed notes are left out.
+func newEntry(entryPath string, isDir bool, metas map[string]NoteMeta) (Entry, bool) {
+	if isDir {
+		return Entry{
+			Path:     entryPath,
+			Title:    path.Base(entryPath),
+			IsFolder: true,
+		}, true
+	}
+
+	meta, ok := metas[entryPath]
+	if !ok {
+		return Entry{}, false
+	}
+	return Entry{
+		Path:      entryPath,
+		Title:     meta.Title,
+		Tags:      meta.Tags,
+		Todos:     meta.Todos,
+		DoneTodos: meta.DoneTodos,
+	}, true
+}
+
+func (s *FileStore) Walk(fn func(note *Note) error) error {
+	return filepath.Walk(s.root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if filePath != s.root && isHidden(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(filePath, ".json") || isHidden(info.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return nil
+		}
+
+		note, err := s.Get(filepath.ToSlash(rel))
+		if err != nil {
+			return nil
+		}
+		return fn(note)
+	})
+}
+
+func (s *FileStore) Get(p string) (*Note, error) {
+	filePath, err := s.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var note Note
+	if err := json.Unmarshal(data, &note); err != nil {
+		return nil, err
+	}
+
+	// Decrypt if needed
+	if note.Encrypted {
+		decrypted, err := decryptNote(note.Content)
+		if err == nil {
+			note.Content = decrypted
+		}
+	}
+
+	note.Path = p
+	return &note, nil
+}
+
+func (s *FileStore) Put(note *Note) error {
+	if note == nil {
+		return fmt.Errorf("note is nil")
+	}
+
+	filePath, err := s.resolve(note.Path)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(filePath, ".json") {
+		return fmt.Errorf("invalid note path: %q", note.Path)
+	}
+
+	if err := utils.ValidateTitle(note.Title); err != nil {
+		return err
+	}
+	if err := utils.ValidateContent(note.Content); err != nil {
+		return err
+	}
+	if err := utils.ValidateTags(note.Tags); err != nil {
+		return err
+	}
+	s.idx.claimID(note)
+
+	// Encrypt a copy so the caller keeps the plaintext
+	onDisk := *note
+	if note.Encrypted && len(encryptionKey) > 0 {
+		encrypted, err := encryptNote(note.Content)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt note: %w", err)
+		}
+		onDisk.Content = encrypted
+	}
+
+	data, err := json.MarshalIndent(&onDisk, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal note: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return err
+	}
+
+	// Create backup before saving
+	if err := utils.BackupFile(filePath); err != nil {
+		logger.Warn("Failed to create backup: %v", err)
+	}
+
+	if err := utils.SafeWriteFile(filePath, data, 0600); err != nil {
+		return err
+	}
+
+	s.idx.put(note, filePath)
+	return nil
+}
+
+func (s *FileStore) Delete(p string) error {
+	filePath, err := s.resolve(p)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if filePath == filepath.Clean(s.root) {
+		return fmt.Errorf("cannot delete the root folder")
+	}
+
+	remove := os.Remove
+	if info.IsDir() {
+		remove = os.RemoveAll
+	}
+	if err := remove(filePath); err != nil {
+		return err
+	}
+
+	s.idx.remove(path.Clean(p))
+	return nil
+}
+
+func (s *FileStore) CreateFolder(p string) error {
+	dirPath, err := s.resolve(p)
+	if err != nil {
+		return err
+	}
+	if err := utils.ValidateFolderName(path.Base(p)); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dirPath); err == nil {
+		return fmt.Errorf("%s already exists", p)
+	}
+	return os.MkdirAll(dirPath, 0700)
+}
+
+func (s *FileStore) Move(from, to string) error {
+	fromPath, err := s.resolve(from)
+	if err != nil {
+		return err
+	}
+	toPath, err := s.resolve(to)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(fromPath); os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if _, err := os.Stat(toPath); err == nil {
+		return fmt.Errorf("%s already exists", to)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toPath), 0700); err != nil {
+		return err
+	}
+	if err := os.Rename(fromPath, toPath); err != nil {
+		return err
+	}
+
+	s.idx.move(path.Clean(from), path.Clean(to))
+	if err := s.moveVersions(from, to); err != nil {
+		logger.Warn("Failed to move versions of %s: %v", from, err)
+	}
+	return nil
+}
+
+func (s *FileStore) Metadata() ([]NoteMeta, error) {
+	return s.idx.all(s), nil
+}
+
+// versionPrefix is the file name prefix of a note's versions in .versions.
+// It is built from the whole path, escaped, so notes with the same file
+// name in different folders keep separate histories.
+func versionPrefix(notePath string) string {
+	return url.PathEscape(path.Clean(notePath)) + "_"
+}
+
+// moveVersions renames the versions of the note, or of every note in the
+// folder, that moved from one path to another
+func (s *FileStore) moveVersions(from, to string) error {
+	dir := filepath.Join(s.root, versionsDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	renames := [][2]string{
+		{versionPrefix(from), versionPrefix(to)},
+		{url.PathEscape(path.Clean(from) + "/"), url.PathEscape(path.Clean(to) + "/")},
+	}
+	for _, entry := range entries {
+		for _, rename := range renames {
+			if rest, ok := strings.CutPrefix(entry.Name(), rename[0]); ok {
+				if err := os.Rename(filepath.Join(dir, entry.Name()), filepath.Join(dir, rename[1]+rest)); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) Versions(notePath string) ([]Version, error) {
+	dir := filepath.Join(s.root, versionsDir)
+	prefix := versionPrefix(notePath)
+
+	versions := []Version{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return versions, nil
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), prefix) &&
+			strings.HasSuffix(entry.Name(), ".json") {
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+
+			var version Version
+			if err := json.Unmarshal(data, &version); err == nil {
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	// Sort by creation date (newest first)
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].CreatedAt.After(versions[j].CreatedAt)
+	})
+
+	return versions, nil
+}
+
+func (s *FileStore) SaveVersion(note *Note) error {
+	if note == nil {
+		return fmt.Errorf("note is nil")
+	}
+
+	dir := filepath.Join(s.root, versionsDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	versionID := fmt.Sprintf("%d", time.Now().Unix())
+	versionFile := filepath.Join(dir, fmt.Sprintf("%s%s.json", versionPrefix(note.Path), versionID))
+
+	version := Version{
+		ID:        versionID,
+		Content:   note.Content,
+		Title:     note.Title,
+		CreatedAt: time.Now(),
+	}
+
+	data, err := json.MarshalIndent(version, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(versionFile, data, 0600)
+}
+
+func (s *FileStore) Templates() ([]Template, error) {
+	dir := filepath.Join(s.root, templatesDir)
+	templates := []Template{}
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := createDefaultTemplates(dir); err != nil {
+			return templates, err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return templates, err
+	}
+
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".json") {
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+
+			var template Template
+			if err := json.Unmarshal(data, &template); err == nil {
+				templates = append(templates, template)
+			}
+		}
+	}
+
+	return templates, nil
+}
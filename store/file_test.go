@@ -0,0 +1,293 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// newTestStore returns a store on a fresh directory, so it never shares
+// an in-memory index with another test
+func newTestStore(t *testing.T) *FileStore {
+	t.Helper()
+	return NewFileStore(t.TempDir())
+}
+
+func putNote(t *testing.T, s *FileStore, notePath, title, content string) *Note {
+	t.Helper()
+	now := time.Now()
+	note := &Note{Path: notePath, Title: title, Content: content, CreatedAt: now, UpdatedAt: now}
+	if err := s.Put(note); err != nil {
+		t.Fatalf("Put(%s): %v", notePath, err)
+	}
+	return note
+}
+
+// metaPaths returns the sorted paths of every indexed note
+func metaPaths(t *testing.T, s *FileStore) []string {
+	t.Helper()
+	metas, err := s.Metadata()
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	var paths []string
+	for _, meta := range metas {
+		paths = append(paths, meta.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPutGetDelete(t *testing.T) {
+	s := newTestStore(t)
+	put := putNote(t, s, "work/plan.json", "Plan", "- [ ] ship it\n[[Other]]")
+	put.Tags = []string{"work"}
+	if err := s.Put(put); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, err := s.Get("work/plan.json")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Title != "Plan" || got.Content != put.Content || got.Path != "work/plan.json" {
+		t.Errorf("Get = %+v, want the note that was put", got)
+	}
+	if got.ID == "" || got.ID != put.ID {
+		t.Errorf("Get ID = %q, want the ID given on Put (%q)", got.ID, put.ID)
+	}
+	if len(got.Tags) != 1 || got.Tags[0] != "work" {
+		t.Errorf("Get tags = %v, want [work]", got.Tags)
+	}
+
+	if err := s.Delete("work/plan.json"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get("work/plan.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete("work/plan.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+	if paths := metaPaths(t, s); len(paths) != 0 {
+		t.Errorf("index after Delete = %v, want empty", paths)
+	}
+}
+
+func TestPutRejectsInvalidNotes(t *testing.T) {
+	s := newTestStore(t)
+	tests := []struct {
+		name string
+		note *Note
+	}{
+		{"nil", nil},
+		{"not json", &Note{Path: "plan.txt", Title: "Plan"}},
+		{"empty title", &Note{Path: "plan.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Put(tt.note); err == nil {
+				t.Error("Put succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDeleteFolder(t *testing.T) {
+	s := newTestStore(t)
+	putNote(t, s, "work/a.json", "A", "a")
+	putNote(t, s, "work/deep/b.json", "B", "b")
+	putNote(t, s, "home.json", "Home", "home")
+
+	if err := s.Delete("work"); err != nil {
+		t.Fatalf("Delete folder: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Root(), "work")); !os.IsNotExist(err) {
+		t.Errorf("folder still on disk: %v", err)
+	}
+	if paths := metaPaths(t, s); !equalStrings(paths, []string{"home.json"}) {
+		t.Errorf("index after Delete = %v, want [home.json]", paths)
+	}
+
+	for _, root := range []string{"", "."} {
+		if err := s.Delete(root); err == nil {
+			t.Errorf("Delete(%q) succeeded, want the root to be refused", root)
+		}
+	}
+}
+
+func TestPathEscapesAreRejected(t *testing.T) {
+	s := newTestStore(t)
+	putNote(t, s, "a.json", "A", "a")
+
+	// Something outside the root that an escape would reach
+	outside := filepath.Join(filepath.Dir(s.Root()), "outside.json")
+	if err := os.WriteFile(outside, []byte(`{"title":"Outside"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(outside)
+
+	for _, p := range []string{"../outside.json", "a/../../outside.json", "/etc/passwd", "..", `..\outside.json`} {
+		t.Run(p, func(t *testing.T) {
+			if _, err := s.Get(p); err == nil {
+				t.Error("Get succeeded")
+			}
+			if err := s.Put(&Note{Path: p, Title: "Escape"}); err == nil {
+				t.Error("Put succeeded")
+			}
+			if err := s.Delete(p); err == nil {
+				t.Error("Delete succeeded")
+			}
+			if err := s.Move("a.json", p); err == nil {
+				t.Error("Move to it succeeded")
+			}
+			if err := s.Move(p, "b.json"); err == nil {
+				t.Error("Move from it succeeded")
+			}
+			if err := s.CreateFolder(p); err == nil {
+				t.Error("CreateFolder succeeded")
+			}
+			if _, err := s.List(p); err == nil {
+				t.Error("List succeeded")
+			}
+		})
+	}
+
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside the root was touched: %v", err)
+	}
+	if _, err := s.Get("a.json"); err != nil {
+		t.Errorf("note inside the root was touched: %v", err)
+	}
+}
+
+func TestMoveNote(t *testing.T) {
+	s := newTestStore(t)
+	note := putNote(t, s, "a.json", "A", "a")
+	putNote(t, s, "b.json", "B", "b")
+	if err := s.SaveVersion(note); err != nil {
+		t.Fatalf("SaveVersion: %v", err)
+	}
+
+	if err := s.Move("a.json", "b.json"); err == nil {
+		t.Error("Move onto an existing note succeeded")
+	}
+	if err := s.Move("missing.json", "c.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Move of a missing note: err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Move("a.json", "archive/a.json"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	moved, err := s.Get("archive/a.json")
+	if err != nil {
+		t.Fatalf("Get after Move: %v", err)
+	}
+	if moved.ID != note.ID {
+		t.Errorf("ID after Move = %q, want %q", moved.ID, note.ID)
+	}
+	if paths := metaPaths(t, s); !equalStrings(paths, []string{"archive/a.json", "b.json"}) {
+		t.Errorf("index after Move = %v", paths)
+	}
+
+	if versions, _ := s.Versions("a.json"); len(versions) != 0 {
+		t.Errorf("old path still has %d versions", len(versions))
+	}
+	if versions, _ := s.Versions("archive/a.json"); len(versions) != 1 || versions[0].Content != "a" {
+		t.Errorf("versions at the new path = %+v, want the one saved before the move", versions)
+	}
+}
+
+func TestMoveFolder(t *testing.T) {
+	s := newTestStore(t)
+	a := putNote(t, s, "work/a.json", "A", "a")
+	b := putNote(t, s, "work/deep/b.json", "B", "b")
+	putNote(t, s, "workshop.json", "Workshop", "w")
+	for _, note := range []*Note{a, b} {
+		if err := s.SaveVersion(note); err != nil {
+			t.Fatalf("SaveVersion: %v", err)
+		}
+	}
+
+	if err := s.Move("work", "old/work"); err != nil {
+		t.Fatalf("Move folder: %v", err)
+	}
+
+	want := []string{"old/work/a.json", "old/work/deep/b.json", "workshop.json"}
+	if paths := metaPaths(t, s); !equalStrings(paths, want) {
+		t.Errorf("index after Move = %v, want %v", paths, want)
+	}
+	for _, p := range []string{"old/work/a.json", "old/work/deep/b.json"} {
+		if versions, _ := s.Versions(p); len(versions) != 1 {
+			t.Errorf("%s has %d versions, want 1", p, len(versions))
+		}
+	}
+	for _, p := range []string{"work/a.json", "work/deep/b.json"} {
+		if versions, _ := s.Versions(p); len(versions) != 0 {
+			t.Errorf("old path %s still has %d versions", p, len(versions))
+		}
+	}
+}
+
+func TestVersionsNewestFirst(t *testing.T) {
+	s := newTestStore(t)
+	putNote(t, s, "a.json", "A", "current")
+	putNote(t, s, "other/a.json", "Other", "other")
+
+	// Version IDs are unix seconds, so write them directly rather than
+	// waiting between saves
+	dir := filepath.Join(s.Root(), versionsDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, offset := range []int{2, 0, 1} {
+		created := base.Add(time.Duration(offset) * time.Hour)
+		version := Version{ID: fmt.Sprint(created.Unix()), Content: fmt.Sprint(offset), Title: "A", CreatedAt: created}
+		data, err := json.Marshal(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := versionPrefix("a.json") + version.ID + ".json"
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other, _ := s.Get("other/a.json")
+	if err := s.SaveVersion(other); err != nil {
+		t.Fatalf("SaveVersion: %v", err)
+	}
+
+	versions, err := s.Versions("a.json")
+	if err != nil {
+		t.Fatalf("Versions: %v", err)
+	}
+	var got []string
+	for _, version := range versions {
+		got = append(got, version.Content)
+	}
+	if !equalStrings(got, []string{"2", "1", "0"}) {
+		t.Errorf("Versions = %v, want newest first [2 1 0] without the other folder's note", got)
+	}
+
+	if versions, _ := s.Versions("missing.json"); versions == nil || len(versions) != 0 {
+		t.Errorf("Versions of a note without history = %v, want an empty list", versions)
+	}
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"sort"
+	"strings"
+)
+
+// Graph is the notes of a store and the links between them, by path
+type Graph struct {
+	Notes map[string]NoteMeta
+	Out   map[string][]string // notes each note links to, by title
+	In    map[string][]string // notes linking to each note, by title
+}
+
+// BuildGraph resolves the links of every note the way following them
+// would. Links a note makes to itself and links to no note are left out.
+func BuildGraph(metas []NoteMeta) *Graph {
+	g := &Graph{
+		Notes: make(map[string]NoteMeta, len(metas)),
+		Out:   make(map[string][]string),
+		In:    make(map[string][]string),
+	}
+	for _, meta := range metas {
+		g.Notes[meta.Path] = meta
+	}
+
+	// Notes tend to link to the same few notes, so resolve each target once
+	resolved := make(map[string]string)
+	for _, meta := range metas {
+		seen := make(map[string]bool)
+		for _, link := range meta.Links {
+			target, ok := resolved[link]
+			if !ok {
+				if to, match := ResolveLink(metas, link); match != LinkMissing {
+					target = to.Path
+				}
+				resolved[link] = target
+			}
+			if target == "" || target == meta.Path || seen[target] {
+				continue
+			}
+			seen[target] = true
+			g.Out[meta.Path] = append(g.Out[meta.Path], target)
+			g.In[target] = append(g.In[target], meta.Path)
+		}
+	}
+
+	for _, paths := range g.Out {
+		g.SortByTitle(paths)
+	}
+	for _, paths := range g.In {
+		g.SortByTitle(paths)
+	}
+	return g
+}
+
+// SortByTitle sorts paths by the titles of their notes, then by path
+func (g *Graph) SortByTitle(paths []string) {
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := strings.ToLower(g.Notes[paths[i]].Title), strings.ToLower(g.Notes[paths[j]].Title)
+		if a != b {
+			return a < b
+		}
+		return paths[i] < paths[j]
+	})
+}
+
+// Neighbors returns the notes linked to or from path, by title
+func (g *Graph) Neighbors(path string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, links := range [][]string{g.Out[path], g.In[path]} {
+		for _, next := range links {
+			if !seen[next] {
+				seen[next] = true
+				paths = append(paths, next)
+			}
+		}
+	}
+	g.SortByTitle(paths)
+	return paths
+}
+
+// Clusters returns the groups of notes connected by links in either
+// direction, largest first. Each group is sorted by title, and a note
+// without links is a group of its own.
+func (g *Graph) Clusters() [][]string {
+	paths := make([]string, 0, len(g.Notes))
+	for path := range g.Notes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	seen := make(map[string]bool)
+	var clusters [][]string
+	for _, start := range paths {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		cluster := []string{start}
+		for i := 0; i < len(cluster); i++ {
+			path := cluster[i]
+			for _, next := range g.Neighbors(path) {
+				if !seen[next] {
+					seen[next] = true
+					cluster = append(cluster, next)
+				}
+			}
+		}
+		g.SortByTitle(cluster)
+		clusters = append(clusters, cluster)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i]) > len(clusters[j])
+	})
+	return clusters
+}
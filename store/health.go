@@ -0,0 +1,157 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// LinkMatch is how a link target was matched to a note, best first
+type LinkMatch int
+
+const (
+	LinkByID    LinkMatch = iota
+	LinkByTitle           // the note's title, ignoring case
+	LinkFuzzy             // a title containing the target, quite possibly the wrong note
+	LinkMissing
+)
+
+// ResolveLink finds the note a link target names among metas: the note
+// with that ID or title, or failing that, one whose title contains it.
+// Among equally good matches the first by path wins. A target naming a
+// section, as in [[Note#Heading]], leads to the note.
+func ResolveLink(metas []NoteMeta, target string) (NoteMeta, LinkMatch) {
+	best, match := resolveLink(metas, target)
+	if note, _, ok := strings.Cut(target, "#"); ok && match > LinkByTitle {
+		if meta, m := resolveLink(metas, note); m < match {
+			return meta, m
+		}
+	}
+	return best, match
+}
+
+func resolveLink(metas []NoteMeta, target string) (NoteMeta, LinkMatch) {
+	target = strings.TrimSpace(target)
+	best, match := NoteMeta{}, LinkMissing
+	if target == "" {
+		return best, match
+	}
+
+	lower := strings.ToLower(target)
+	for _, meta := range metas {
+		var m LinkMatch
+		switch {
+		case meta.ID != "" && meta.ID == target:
+			m = LinkByID
+		case strings.EqualFold(meta.Title, target):
+			m = LinkByTitle
+		case strings.Contains(strings.ToLower(meta.Title), lower):
+			m = LinkFuzzy
+		default:
+			continue
+		}
+		if m < match || (m == match && meta.Path < best.Path) {
+			best, match = meta, m
+		}
+	}
+	return best, match
+}
+
+// BadLink is a link that leads to no note, or only to one whose title
+// happens to contain it
+type BadLink struct {
+	Path   string // the note with the link
+	Title  string
+	Line   int // from 0
+	Column int // in runes, from 0
+	Link   string
+	Match  LinkMatch
+	Target NoteMeta // the note a fuzzy link leads to
+}
+
+// LinkReport is the health of the links between notes
+type LinkReport struct {
+	Broken  []BadLink
+	Fuzzy   []BadLink
+	Orphans []NoteMeta // notes with no links to or from other notes
+}
+
+// Problems is the number of entries in the report
+func (r *LinkReport) Problems() int {
+	return len(r.Broken) + len(r.Fuzzy) + len(r.Orphans)
+}
+
+// CheckLinks resolves every link in every note, collecting those that
+// lead nowhere or only fuzzily somewhere, and the notes left unlinked.
+// The links of encrypted notes aren't indexed, so like the graph and
+// backlinks it skips them, and doesn't call encrypted notes orphans.
+func CheckLinks(s NoteStore) (*LinkReport, error) {
+	metas, err := s.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LinkReport{}
+	linked := make(map[string]bool)
+	err = s.Walk(func(note *Note) error {
+		if note.Encrypted {
+			return nil
+		}
+		for i, line := range strings.Split(note.Content, "\n") {
+			for _, loc := range LinkPattern.FindAllStringSubmatchIndex(line, -1) {
+				target, _ := SplitLink(line[loc[2]:loc[3]])
+				meta, match := ResolveLink(metas, target)
+				bad := BadLink{
+					Path:   note.Path,
+					Title:  note.Title,
+					Line:   i,
+					Column: utf8.RuneCountInString(line[:loc[0]]),
+					Link:   target,
+					Match:  match,
+				}
+
+				switch match {
+				case LinkMissing:
+					report.Broken = append(report.Broken, bad)
+					continue
+				case LinkFuzzy:
+					bad.Target = meta
+					report.Fuzzy = append(report.Fuzzy, bad)
+				}
+				if meta.Path != note.Path {
+					linked[note.Path] = true
+					linked[meta.Path] = true
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, meta := range metas {
+		if !linked[meta.Path] && !meta.Encrypted {
+			report.Orphans = append(report.Orphans, meta)
+		}
+	}
+
+	sortBadLinks(report.Broken)
+	sortBadLinks(report.Fuzzy)
+	sort.Slice(report.Orphans, func(i, j int) bool {
+		return report.Orphans[i].Path < report.Orphans[j].Path
+	})
+	return report, nil
+}
+
+func sortBadLinks(links []BadLink) {
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Path != links[j].Path {
+			return links[i].Path < links[j].Path
+		}
+		if links[i].Line != links[j].Line {
+			return links[i].Line < links[j].Line
+		}
+		return links[i].Column < links[j].Column
+	})
+}
@@ -0,0 +1,329 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/search"
+	"github.com/ssh-notes/terminal-notes/utils"
+)
+
+const indexFile = ".index.json"
+
+// indexVersion is bumped whenever NoteMeta gains fields, so older sidecar
+// files are rebuilt rather than read with the new fields left empty
+const indexVersion = 4
+
+// indexData is the on-disk format of the metadata index
+type indexData struct {
+	Version int                 `json:"version"`
+	Notes   map[string]NoteMeta `json:"notes"`
+}
+
+// NoteMeta is the indexed metadata of a note, enough to sort, filter and
+// resolve links without reading the note itself
+type NoteMeta struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	Title     string    `json:"title"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Size      int       `json:"size"` // content length in bytes
+	Links     []string  `json:"links"`
+	Todos     int       `json:"todos"`
+	DoneTodos int       `json:"done_todos"`
+	Encrypted bool      `json:"encrypted"` // Links and todos are left out
+
+	// File state when the entry was built, to spot changes made elsewhere
+	FileSize    int64     `json:"file_size"`
+	FileModTime time.Time `json:"file_mod_time"`
+}
+
+// metaIndex is the in-memory metadata index of one store root, persisted
+// to root/.index.json, along with the full-text index in root/.search.gob.
+// It is shared by every FileStore on the same root so concurrent sessions
+// of a user see each other's changes.
+type metaIndex struct {
+	mu     sync.Mutex
+	root   string
+	loaded bool
+	notes  map[string]NoteMeta
+	text   *search.Index
+}
+
+var (
+	indexesMu sync.Mutex
+	indexes   = make(map[string]*metaIndex)
+)
+
+// indexFor returns the shared index for root
+func indexFor(root string) *metaIndex {
+	key, err := filepath.Abs(root)
+	if err != nil {
+		key = root
+	}
+
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+
+	idx, ok := indexes[key]
+	if !ok {
+		idx = &metaIndex{
+			root:  root,
+			notes: make(map[string]NoteMeta),
+			text:  search.New(filepath.Join(root, searchIndexFile)),
+		}
+		indexes[key] = idx
+	}
+	return idx
+}
+
+// newNoteMeta builds the index entry for note as stored in file info
+func newNoteMeta(note *Note, info os.FileInfo) NoteMeta {
+	meta := NoteMeta{
+		ID:        note.ID,
+		Path:      note.Path,
+		Title:     note.Title,
+		Tags:      note.Tags,
+		CreatedAt: note.CreatedAt,
+		UpdatedAt: note.UpdatedAt,
+		Size:      len(note.Content),
+		Encrypted: note.Encrypted,
+	}
+
+	// Don't copy links or todos out of encrypted notes into the plaintext
+	// sidecar. Whatever goes by links (backlinks, the graph, links: search,
+	// the link check) leaves out the links of encrypted notes.
+	if !note.Encrypted {
+		meta.Links = note.ExtractLinks()
+		meta.DoneTodos, meta.Todos = note.CountTodos()
+	}
+
+	if info != nil {
+		meta.FileSize = info.Size()
+		meta.FileModTime = info.ModTime()
+	}
+	return meta
+}
+
+// load reads the sidecar file once; a missing or corrupt file just means
+// the next sync rebuilds everything
+func (idx *metaIndex) load() {
+	if idx.loaded {
+		return
+	}
+	idx.loaded = true
+
+	data, err := os.ReadFile(filepath.Join(idx.root, indexFile))
+	if err != nil {
+		return
+	}
+
+	var index indexData
+	if err := json.Unmarshal(data, &index); err != nil {
+		logger.Warn("Ignoring corrupt note index in %s: %v", idx.root, err)
+		return
+	}
+	if index.Version != indexVersion || index.Notes == nil {
+		return
+	}
+	idx.notes = index.Notes
+}
+
+// save writes the sidecar file
+func (idx *metaIndex) save() {
+	data, err := json.Marshal(indexData{Version: indexVersion, Notes: idx.notes})
+	if err != nil {
+		logger.Error("Failed to marshal note index: %v", err)
+		return
+	}
+
+	if err := os.MkdirAll(idx.root, 0700); err != nil {
+		logger.Error("Failed to save note index: %v", err)
+		return
+	}
+	if err := utils.SafeWriteFile(filepath.Join(idx.root, indexFile), data, 0600); err != nil {
+		logger.Error("Failed to save note index: %v", err)
+	}
+}
+
+// sync reconciles the index with the files on disk. Only notes whose size
+// or modification time changed since they were indexed are re-read.
+func (idx *metaIndex) sync(s *FileStore) {
+	idx.load()
+
+	seen := make(map[string]bool, len(idx.notes))
+	changed := false
+
+	filepath.Walk(idx.root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if filePath != idx.root && isHidden(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(filePath, ".json") || isHidden(info.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(idx.root, filePath)
+		if err != nil {
+			return nil
+		}
+		notePath := filepath.ToSlash(rel)
+		seen[notePath] = true
+
+		meta, ok := idx.notes[notePath]
+		if ok && meta.FileSize == info.Size() && meta.FileModTime.Equal(info.ModTime()) {
+			return nil
+		}
+
+		note, err := s.Get(notePath)
+		if err != nil {
+			return nil
+		}
+		idx.notes[notePath] = newNoteMeta(note, info)
+		changed = true
+		return nil
+	})
+
+	for notePath := range idx.notes {
+		if !seen[notePath] {
+			delete(idx.notes, notePath)
+			changed = true
+		}
+	}
+
+	if changed {
+		idx.save()
+	}
+}
+
+// all returns a snapshot of every indexed note
+func (idx *metaIndex) all(s *FileStore) []NoteMeta {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.sync(s)
+
+	metas := make([]NoteMeta, 0, len(idx.notes))
+	for _, meta := range idx.notes {
+		metas = append(metas, meta)
+	}
+	return metas
+}
+
+// put records a note that was just written to filePath
+func (idx *metaIndex) put(note *Note, filePath string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.load()
+	info, err := os.Stat(filePath)
+	if err != nil {
+		info = nil
+	}
+	meta := newNoteMeta(note, info)
+	idx.notes[note.Path] = meta
+	idx.save()
+
+	idx.text.Add(note.Path, meta.stamp(), noteFields(note)...)
+	idx.saveText()
+}
+
+// claimID gives note an ID that no other note has. A note without one
+// keeps the ID already indexed for its path, as when an old version is
+// restored, and a copy of another note gets a new one.
+func (idx *metaIndex) claimID(note *Note) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.load()
+	if note.ID == "" {
+		note.ID = idx.notes[note.Path].ID
+	}
+	for note.ID == "" || idx.idTaken(note.ID, note.Path) {
+		note.ID = newNoteID()
+	}
+}
+
+// idTaken reports whether a note other than the one at notePath has id
+func (idx *metaIndex) idTaken(id, notePath string) bool {
+	for p, meta := range idx.notes {
+		if meta.ID == id && p != notePath {
+			return true
+		}
+	}
+	return false
+}
+
+// newNoteID returns a random note ID, short enough to type in a link
+func newNoteID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// remove drops a note, or every note under a folder
+func (idx *metaIndex) remove(notePath string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.load()
+	prefix := strings.TrimSuffix(notePath, "/") + "/"
+	for p := range idx.notes {
+		if p == notePath || strings.HasPrefix(p, prefix) {
+			delete(idx.notes, p)
+		}
+	}
+	idx.save()
+
+	for _, p := range idx.text.IDs() {
+		if p == notePath || strings.HasPrefix(p, prefix) {
+			idx.text.Remove(p)
+		}
+	}
+	idx.saveText()
+}
+
+// move re-keys a note, or every note under a folder
+func (idx *metaIndex) move(from, to string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.load()
+	prefix := strings.TrimSuffix(from, "/") + "/"
+	for p, meta := range idx.notes {
+		var newPath string
+		switch {
+		case p == from:
+			newPath = to
+		case strings.HasPrefix(p, prefix):
+			newPath = strings.TrimSuffix(to, "/") + "/" + strings.TrimPrefix(p, prefix)
+		default:
+			continue
+		}
+		delete(idx.notes, p)
+		meta.Path = newPath
+		idx.notes[newPath] = meta
+		idx.text.Rename(p, newPath)
+	}
+	idx.save()
+	idx.saveText()
+}
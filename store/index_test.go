@@ -0,0 +1,144 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// forgetIndex drops the shared in-memory index of root, as if the server
+// had restarted
+func forgetIndex(t *testing.T, root string) {
+	t.Helper()
+	key, err := filepath.Abs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexesMu.Lock()
+	delete(indexes, key)
+	indexesMu.Unlock()
+}
+
+func metaFor(t *testing.T, s *FileStore, notePath string) (NoteMeta, bool) {
+	t.Helper()
+	metas, err := s.Metadata()
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	for _, meta := range metas {
+		if meta.Path == notePath {
+			return meta, true
+		}
+	}
+	return NoteMeta{}, false
+}
+
+func TestIndexSyncsOutsideEdits(t *testing.T) {
+	s := newTestStore(t)
+	putNote(t, s, "a.json", "A", "- [ ] one")
+	putNote(t, s, "b.json", "B", "b")
+	if _, ok := metaFor(t, s, "a.json"); !ok {
+		t.Fatal("a.json missing from the index")
+	}
+
+	// Rewrite a.json behind the store's back, with a new mtime so the
+	// change is seen even if the size happened to match
+	edited := `{"id":"a","title":"Edited","content":"- [x] one\n- [ ] two\n[[B]]"}`
+	filePath := filepath.Join(s.Root(), "a.json")
+	if err := os.WriteFile(filePath, []byte(edited), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add one note and remove another the same way
+	if err := os.WriteFile(filepath.Join(s.Root(), "c.json"), []byte(`{"title":"C"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(s.Root(), "b.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, ok := metaFor(t, s, "a.json")
+	if !ok {
+		t.Fatal("a.json missing from the index after the edit")
+	}
+	if meta.Title != "Edited" || meta.Todos != 2 || meta.DoneTodos != 1 {
+		t.Errorf("meta = title %q, %d/%d todos, want Edited, 1/2", meta.Title, meta.DoneTodos, meta.Todos)
+	}
+	if len(meta.Links) != 1 || meta.Links[0] != "B" {
+		t.Errorf("links = %v, want [B]", meta.Links)
+	}
+	if paths := metaPaths(t, s); !equalStrings(paths, []string{"a.json", "c.json"}) {
+		t.Errorf("index = %v, want [a.json c.json]", paths)
+	}
+}
+
+func TestIndexRebuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		index func(path string) error
+	}{
+		{"missing", os.Remove},
+		{"corrupt", func(path string) error { return os.WriteFile(path, []byte("{not json"), 0600) }},
+		{"old version", func(path string) error {
+			return os.WriteFile(path, []byte(`{"version":1,"notes":{"gone.json":{"path":"gone.json"}}}`), 0600)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			s := NewFileStore(root)
+			putNote(t, s, "a.json", "A", "a")
+			putNote(t, s, "folder/b.json", "B", "b")
+
+			forgetIndex(t, root)
+			if err := tt.index(filepath.Join(root, indexFile)); err != nil {
+				t.Fatal(err)
+			}
+
+			s = NewFileStore(root)
+			if paths := metaPaths(t, s); !equalStrings(paths, []string{"a.json", "folder/b.json"}) {
+				t.Errorf("rebuilt index = %v", paths)
+			}
+			if _, err := os.Stat(filepath.Join(root, indexFile)); err != nil {
+				t.Errorf("rebuilt index was not saved: %v", err)
+			}
+		})
+	}
+}
+
+func TestIndexSurvivesRestart(t *testing.T) {
+	root := t.TempDir()
+	s := NewFileStore(root)
+	note := putNote(t, s, "a.json", "A", "a")
+
+	forgetIndex(t, root)
+	s = NewFileStore(root)
+	meta, ok := metaFor(t, s, "a.json")
+	if !ok || meta.ID != note.ID {
+		t.Errorf("meta after restart = %+v, want ID %q", meta, note.ID)
+	}
+}
+
+func TestStoresOnOneRootShareTheIndex(t *testing.T) {
+	root := t.TempDir()
+	first := NewFileStore(root)
+	second := NewFileStore(root)
+
+	putNote(t, first, "a.json", "A", "a")
+	if _, ok := metaFor(t, second, "a.json"); !ok {
+		t.Error("second store does not see a note put through the first")
+	}
+
+	if err := second.Move("a.json", "b.json"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if paths := metaPaths(t, first); !equalStrings(paths, []string{"b.json"}) {
+		t.Errorf("first store's index = %v, want [b.json]", paths)
+	}
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LinkPattern matches [[wiki links]] in note content
+var LinkPattern = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
+
+// SplitLink splits the inside of a [[link]] into the note it points to,
+// by ID or title, and the text to show for it, given after a "|"
+func SplitLink(link string) (target, alias string) {
+	target, alias, _ = strings.Cut(link, "|")
+	return strings.TrimSpace(target), strings.TrimSpace(alias)
+}
+
+// ExtractLinks extracts all [[link]] references from note content
+func (n *Note) ExtractLinks() []string {
+	matches := LinkPattern.FindAllStringSubmatch(n.Content, -1)
+	links := make([]string, 0, len(matches))
+	
+	for _, match := range matches {
+		if len(match) > 1 {
+			target, _ := SplitLink(match[1])
+			links = append(links, target)
+		}
+	}
+	
+	return links
+}
+
+// MatchesLink reports whether a link target names this note, by its ID
+// or its title, or a section of it as in [[Note#Heading]]
+func (n *Note) MatchesLink(target string) bool {
+	if (n.ID != "" && target == n.ID) || strings.EqualFold(target, n.Title) {
+		return true
+	}
+	note, _, ok := strings.Cut(target, "#")
+	note = strings.TrimSpace(note)
+	return ok && ((n.ID != "" && note == n.ID) || strings.EqualFold(note, n.Title))
+}
+
+// PlanLinkRename plans rewriting the links that name a note by oldTitle
+// so they name newTitle, keeping their aliases and any #Heading. Given the
+// note's id, links are rewritten to [[id|alias]] instead, aliased by the
+// new title if they had no alias, so they survive later renames. Only the
+// notes the index says link to oldTitle are read, along with encrypted
+// notes, whose links aren't indexed. Apply the plan with ApplyReplace.
+func PlanLinkRename(s NoteStore, oldTitle, newTitle, id string) ([]Replacement, error) {
+	metas, err := s.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	
+	var plan []Replacement
+	for _, meta := range metas {
+		if !meta.Encrypted && !linksTo(meta.Links, oldTitle) {
+			continue
+		}
+		note, err := s.Get(meta.Path)
+		if err != nil {
+			return nil, err
+		}
+		
+		count := 0
+		after := LinkPattern.ReplaceAllStringFunc(note.Content, func(link string) string {
+			target, alias := SplitLink(LinkPattern.FindStringSubmatch(link)[1])
+			section, ok := linkSection(target, oldTitle)
+			if !ok {
+				return link
+			}
+			count++
+			
+			switch {
+			case id != "" && alias == "":
+				return "[[" + id + section + "|" + newTitle + section + "]]"
+			case id != "":
+				return "[[" + id + section + "|" + alias + "]]"
+			case alias != "":
+				return "[[" + newTitle + section + "|" + alias + "]]"
+			}
+			return "[[" + newTitle + section + "]]"
+		})
+		if count == 0 {
+			continue
+		}
+		
+		plan = append(plan, Replacement{
+			Path:     note.Path,
+			Title:    note.Title,
+			NewTitle: note.Title,
+			Before:   note.Content,
+			After:    after,
+			Count:    count,
+		})
+	}
+	
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Path < plan[j].Path })
+	return plan, nil
+}
+
+// linksTo reports whether any of the link targets names title
+func linksTo(targets []string, title string) bool {
+	for _, target := range targets {
+		if _, ok := linkSection(target, title); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// linkSection reports whether a link target names the note titled title,
+// returning the "#Heading" it goes on with, if any
+func linkSection(target, title string) (string, bool) {
+	title = strings.TrimSpace(title)
+	if strings.EqualFold(target, title) {
+		return "", true
+	}
+	note, heading, ok := strings.Cut(target, "#")
+	if ok && strings.EqualFold(strings.TrimSpace(note), title) {
+		return "#" + heading, true
+	}
+	return "", false
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/utils"
+)
+
+// ReplaceOptions controls how a global replace matches
+type ReplaceOptions struct {
+	CaseSensitive bool
+	Regex         bool // the pattern is a Go regular expression; $1 in the replacement refers to its groups
+	Titles        bool // replace in titles as well as content
+}
+
+// Replacement is the planned change to one note
+type Replacement struct {
+	Path     string
+	Title    string
+	NewTitle string
+	Before   string
+	After    string
+	Count    int // number of matches in the title and content
+}
+
+// compileReplace turns a pattern into a regular expression
+func compileReplace(pattern string, opts ReplaceOptions) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	expr := pattern
+	if !opts.Regex {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if !opts.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// replaceIn replaces every non-empty match in text, returning the new text
+// and the number of matches
+func replaceIn(re *regexp.Regexp, text, replacement string, opts ReplaceOptions) (string, int) {
+	var b strings.Builder
+	count, prev := 0, 0
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		if loc[1] == loc[0] {
+			continue
+		}
+		b.WriteString(text[prev:loc[0]])
+		if opts.Regex {
+			b.Write(re.ExpandString(nil, replacement, text, loc))
+		} else {
+			b.WriteString(replacement)
+		}
+		prev = loc[1]
+		count++
+	}
+	if count == 0 {
+		return text, 0
+	}
+	b.WriteString(text[prev:])
+	return b.String(), count
+}
+
+// Summarize describes a plan as, e.g., "3 matches in 2 notes"
+func Summarize(plan []Replacement) string {
+	matches := 0
+	for _, change := range plan {
+		matches += change.Count
+	}
+	return plural(matches, "match", "matches") + " in " + plural(len(plan), "note", "notes")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// PlanReplace works out what replacing pattern in every note would change,
+// without writing anything. Notes without a match are left out.
+func PlanReplace(s NoteStore, pattern, replacement string, opts ReplaceOptions) ([]Replacement, error) {
+	re, err := compileReplace(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []Replacement
+	err = s.Walk(func(note *Note) error {
+		after, count := replaceIn(re, note.Content, replacement, opts)
+		newTitle := note.Title
+		if opts.Titles {
+			var titleCount int
+			newTitle, titleCount = replaceIn(re, note.Title, replacement, opts)
+			count += titleCount
+		}
+		if count == 0 {
+			return nil
+		}
+
+		plan = append(plan, Replacement{
+			Path:     note.Path,
+			Title:    note.Title,
+			NewTitle: newTitle,
+			Before:   note.Content,
+			After:    after,
+			Count:    count,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Path < plan[j].Path })
+	return plan, nil
+}
+
+// ApplyReplace writes planned replacements. Every note is checked first, so
+// nothing is written if any note changed since it was planned or would
+// become invalid. Each note's previous state is saved as a version before it
+// is overwritten, and if a write fails the notes already written are put
+// back.
+func ApplyReplace(s NoteStore, plan []Replacement) error {
+	originals := make([]*Note, len(plan))
+	for i, change := range plan {
+		note, err := s.Get(change.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", change.Path, err)
+		}
+		if note.Content != change.Before || note.Title != change.Title {
+			return fmt.Errorf("%s changed since the preview; run the replace again", change.Path)
+		}
+		if err := utils.ValidateTitle(change.NewTitle); err != nil {
+			return fmt.Errorf("%s: %w", change.Path, err)
+		}
+		if err := utils.ValidateContent(change.After); err != nil {
+			return fmt.Errorf("%s: %w", change.Path, err)
+		}
+		originals[i] = note
+	}
+
+	now := time.Now()
+	for i, change := range plan {
+		original := originals[i]
+		if err := s.SaveVersion(original); err != nil {
+			logger.Warn("Failed to save version of %s: %v", change.Path, err)
+		}
+
+		updated := *original
+		updated.Title = change.NewTitle
+		updated.Content = change.After
+		updated.UpdatedAt = now
+		if err := s.Put(&updated); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if rollbackErr := s.Put(originals[j]); rollbackErr != nil {
+					logger.Error("Failed to roll back %s: %v", plan[j].Path, rollbackErr)
+				}
+			}
+			return fmt.Errorf("%s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
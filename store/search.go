@@ -0,0 +1,314 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ssh-notes/terminal-notes/logger"
+	"github.com/ssh-notes/terminal-notes/search"
+)
+
+const searchIndexFile = ".search.gob"
+
+// SearchHit is a note matching a search, with the query words to highlight
+// in it
+type SearchHit struct {
+	Path  string
+	Title string
+	Tags  []string
+	Score float64
+	Words []string
+}
+
+// stamp identifies the file state a full-text entry was built from
+func (meta NoteMeta) stamp() string {
+	return fmt.Sprintf("%d/%d", meta.FileSize, meta.FileModTime.UnixNano())
+}
+
+// noteFields is what the full-text index sees of a note. Encrypted notes
+// only have their title and tags indexed, like their links.
+func noteFields(note *Note) []search.Field {
+	fields := []search.Field{
+		{Text: note.Title, Weight: 3},
+		{Text: strings.Join(note.Tags, " "), Weight: 2},
+	}
+	if !note.Encrypted {
+		fields = append(fields, search.Field{Text: note.Content, Weight: 1})
+	}
+	return fields
+}
+
+// syncText brings the full-text index in line with the metadata index,
+// re-reading only notes whose file changed since they were indexed. The
+// caller holds idx.mu and has just synced.
+func (idx *metaIndex) syncText(s *FileStore) {
+	for notePath, meta := range idx.notes {
+		if idx.text.Stamp(notePath) == meta.stamp() {
+			continue
+		}
+		note, err := s.Get(notePath)
+		if err != nil {
+			continue
+		}
+		idx.text.Add(notePath, meta.stamp(), noteFields(note)...)
+	}
+
+	for _, id := range idx.text.IDs() {
+		if _, ok := idx.notes[id]; !ok {
+			idx.text.Remove(id)
+		}
+	}
+
+	idx.saveText()
+}
+
+// saveText writes the full-text index if it changed
+func (idx *metaIndex) saveText() {
+	if err := idx.text.Save(); err != nil {
+		logger.Error("Failed to save search index: %v", err)
+	}
+}
+
+// Search returns the notes matching query, best first. See search.Parse
+// for the query syntax; a malformed query returns a *search.ParseError.
+func (s *FileStore) Search(query string, limit int) ([]SearchHit, error) {
+	node, err := search.Parse(query, time.Now())
+	if err != nil || node == nil {
+		return nil, err
+	}
+
+	s.idx.mu.Lock()
+	defer s.idx.mu.Unlock()
+
+	s.idx.sync(s)
+	s.idx.syncText(s)
+
+	e := &evaluator{s: s, idx: s.idx, notes: make(map[string]*Note)}
+	scores := e.eval(node)
+
+	words := search.Words(node)
+	hits := make([]SearchHit, 0, len(scores))
+	for notePath, score := range scores {
+		meta := s.idx.notes[notePath]
+		hits = append(hits, SearchHit{
+			Path:  meta.Path,
+			Title: meta.Title,
+			Tags:  meta.Tags,
+			Score: score,
+			Words: words,
+		})
+	}
+
+	// Best match first; pure filter queries list the latest notes first
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		ui, uj := s.idx.notes[hits[i].Path].UpdatedAt, s.idx.notes[hits[j].Path].UpdatedAt
+		if !ui.Equal(uj) {
+			return ui.After(uj)
+		}
+		return hits[i].Path < hits[j].Path
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// evaluator runs a parsed query against the indexes, with idx.mu held.
+// Each node evaluates to the matching note paths and their scores; filters
+// match with a score of 0.
+type evaluator struct {
+	s     *FileStore
+	idx   *metaIndex
+	notes map[string]*Note // notes loaded to check phrases
+}
+
+func (e *evaluator) eval(node search.Node) map[string]float64 {
+	switch n := node.(type) {
+	case search.TermNode:
+		return e.known(e.idx.text.Match(n.Word, n.Prefix))
+
+	case search.PhraseNode:
+		return e.phrase(n)
+
+	case search.FieldNode:
+		// A link by ID counts as a link to the title of the note with it
+		var ids map[string]bool
+		if n.Field == "links" {
+			ids = make(map[string]bool)
+			for _, meta := range e.idx.notes {
+				if meta.ID != "" && strings.EqualFold(meta.Title, n.Value) {
+					ids[meta.ID] = true
+				}
+			}
+		}
+		return e.filter(func(meta NoteMeta) bool {
+			return matchField(meta, n, ids)
+		})
+
+	case search.DateNode:
+		return e.filter(func(meta NoteMeta) bool {
+			t := meta.CreatedAt
+			if n.Field == "updated" {
+				t = meta.UpdatedAt
+			}
+			return (n.From.IsZero() || !t.Before(n.From)) && (n.To.IsZero() || t.Before(n.To))
+		})
+
+	case search.AndNode:
+		var result map[string]float64
+		for _, child := range n.Children {
+			scores := e.eval(child)
+			if result == nil {
+				result = scores
+				continue
+			}
+			for notePath := range result {
+				if score, ok := scores[notePath]; ok {
+					result[notePath] += score
+				} else {
+					delete(result, notePath)
+				}
+			}
+		}
+		return result
+
+	case search.OrNode:
+		result := make(map[string]float64)
+		for _, child := range n.Children {
+			for notePath, score := range e.eval(child) {
+				result[notePath] += score
+			}
+		}
+		return result
+
+	case search.NotNode:
+		excluded := e.eval(n.Child)
+		return e.filter(func(meta NoteMeta) bool {
+			_, ok := excluded[meta.Path]
+			return !ok
+		})
+	}
+
+	return map[string]float64{}
+}
+
+// known drops full-text matches for notes that no longer exist
+func (e *evaluator) known(scores map[string]float64) map[string]float64 {
+	for notePath := range scores {
+		if _, ok := e.idx.notes[notePath]; !ok {
+			delete(scores, notePath)
+		}
+	}
+	return scores
+}
+
+// filter returns every note whose metadata satisfies match
+func (e *evaluator) filter(match func(meta NoteMeta) bool) map[string]float64 {
+	result := make(map[string]float64)
+	for notePath, meta := range e.idx.notes {
+		if match(meta) {
+			result[notePath] = 0
+		}
+	}
+	return result
+}
+
+// phrase narrows the notes containing every word of a phrase down to those
+// where the words appear in order
+func (e *evaluator) phrase(n search.PhraseNode) map[string]float64 {
+	var result map[string]float64
+	for _, word := range n.Words {
+		scores := e.known(e.idx.text.Match(word, false))
+		if result == nil {
+			result = scores
+			continue
+		}
+		for notePath := range result {
+			if score, ok := scores[notePath]; ok {
+				result[notePath] += score
+			} else {
+				delete(result, notePath)
+			}
+		}
+	}
+
+	for notePath := range result {
+		note, ok := e.notes[notePath]
+		if !ok {
+			var err error
+			if note, err = e.s.Get(notePath); err != nil {
+				delete(result, notePath)
+				continue
+			}
+			e.notes[notePath] = note
+		}
+
+		found := false
+		for _, field := range noteFields(note) {
+			if containsPhrase(search.Tokenize(field.Text), n.Words) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			delete(result, notePath)
+		}
+	}
+	return result
+}
+
+// containsPhrase reports whether words appear consecutively in tokens
+func containsPhrase(tokens []search.Token, words []string) bool {
+	for i := 0; i+len(words) <= len(tokens); i++ {
+		match := true
+		for j, word := range words {
+			if tokens[i+j].Word != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// matchField applies a tag:, title:, links: or has: filter to meta. For
+// links:, ids are the IDs of the notes with the title searched for.
+func matchField(meta NoteMeta, n search.FieldNode, ids map[string]bool) bool {
+	switch n.Field {
+	case "tag":
+		for _, tag := range meta.Tags {
+			if strings.EqualFold(tag, n.Value) {
+				return true
+			}
+		}
+	case "title":
+		return strings.Contains(strings.ToLower(meta.Title), n.Value)
+	case "links":
+		for _, link := range meta.Links {
+			if strings.EqualFold(strings.TrimSpace(link), n.Value) || ids[link] {
+				return true
+			}
+		}
+	case "has":
+		switch n.Value {
+		case "todo":
+			return meta.Todos > meta.DoneTodos
+		case "done":
+			return meta.DoneTodos > 0
+		case "tag":
+			return len(meta.Tags) > 0
+		case "link":
+			return len(meta.Links) > 0
+		}
+	}
+	return false
+}
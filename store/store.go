@@ -0,0 +1,81 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a note, folder or version doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// NoteStore is the persistence layer shared by the TUI and the CLI.
+// Paths are slash-separated and relative to the store root, e.g.
+// "note_1700000000.json" or "work/note_1700000000.json".
+type NoteStore interface {
+	// List returns the folders and notes directly inside folder ("" is the root)
+	List(folder string) ([]Entry, error)
+	// ListAll returns the folders and notes inside folder at any depth,
+	// each folder before its contents
+	ListAll(folder string) ([]Entry, error)
+	// Walk calls fn for every note in the store, in every folder
+	Walk(fn func(note *Note) error) error
+	// Get loads a single note, decrypting it if needed
+	Get(path string) (*Note, error)
+	// Put validates and writes note at note.Path
+	Put(note *Note) error
+	// Delete removes the note at path, or the folder at path with every
+	// note in it
+	Delete(path string) error
+	// Move renames a note or folder
+	Move(from, to string) error
+	// CreateFolder makes an empty folder at path
+	CreateFolder(path string) error
+	// Versions returns the saved versions of a note, newest first
+	Versions(path string) ([]Version, error)
+	// SaveVersion snapshots note into its version history
+	SaveVersion(note *Note) error
+	// Templates returns the available note templates
+	Templates() ([]Template, error)
+	// Metadata returns the indexed metadata of every note, without reading
+	// the notes themselves
+	Metadata() ([]NoteMeta, error)
+	// Search returns up to limit notes matching query, best first
+	// (0 means no limit)
+	Search(query string, limit int) ([]SearchHit, error)
+}
+
+// Entry is a single item returned by List
+type Entry struct {
+	Path      string
+	Title     string
+	IsFolder  bool
+	Tags      []string
+	Todos     int
+	DoneTodos int
+}
+
+type Note struct {
+	ID         string    `json:"id,omitempty"` // stable across renames and moves, for [[id|alias]] links
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Tags       []string  `json:"tags"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Path       string    `json:"path"`
+	Encrypted  bool      `json:"encrypted"`
+}
+
+type Version struct {
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Template struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Title       string   `json:"title"`
+	Content     string   `json:"content"`
+	Tags        []string `json:"tags"`
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// createDefaultTemplates seeds a new templates directory
+func createDefaultTemplates(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	
+	defaultTemplates := []Template{
+		{
+			Name:        "meeting",
+			Description: "Meeting notes template",
+			Title:       "Meeting Notes - {{date}}",
+			Content:     "# Meeting Notes\n\n**Date:** {{date}}\n**Attendees:** \n**Agenda:**\n\n## Notes\n\n## Action Items\n\n- [ ] \n",
+			Tags:        []string{"meeting"},
+		},
+		{
+			Name:        "journal",
+			Description: "Daily journal template",
+			Title:       "Journal - {{date}}",
+			Content:     "# Journal Entry\n\n**Date:** {{date}}\n\n## Today's Highlights\n\n\n## Thoughts\n\n\n## Tomorrow's Goals\n\n- [ ] \n",
+			Tags:        []string{"journal"},
+		},
+		{
+			Name:        "code",
+			Description: "Code snippet template",
+			Title:       "Code: {{title}}",
+			Content:     "# {{title}}\n\n**Language:** \n**Description:**\n\n```\n\n```\n",
+			Tags:        []string{"code"},
+		},
+		{
+			Name:        "todo",
+			Description: "To-do list template",
+			Title:       "Todo List - {{date}}",
+			Content:     "# Todo List\n\n**Date:** {{date}}\n\n## Tasks\n\n- [ ] \n- [ ] \n- [ ] \n",
+			Tags:        []string{"todo"},
+		},
+	}
+	
+	for _, tmpl := range defaultTemplates {
+		path := filepath.Join(dir, tmpl.Name+".json")
+		data, err := json.MarshalIndent(tmpl, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
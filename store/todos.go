@@ -0,0 +1,246 @@
+package store
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var todoRegex = regexp.MustCompile(`(?m)^\s*[-*]\s+\[([ xX])\]\s+(.+)$`)
+
+// dueRegex matches the due date of a todo, to move it on when the todo
+// recurs
+var dueRegex = regexp.MustCompile(`(^|\s)due:\S+`)
+
+// DateFormat is how due dates are written: due:2026-11-01
+const DateFormat = "2006-01-02"
+
+// Priorities a todo can be given with !high, !medium or !low
+const (
+	PriorityHigh   = "high"
+	PriorityMedium = "medium"
+	PriorityLow    = "low"
+)
+
+type TodoItem struct {
+	Text      string
+	Completed bool
+	LineNum   int
+	
+	// Metadata written among the text
+	Description string    // the text without it
+	Due         time.Time // due:2026-11-01, zero if none
+	Priority    string    // !high, !medium or !low
+	Every       string    // every:weekly, every:3d, ... for recurring todos
+	Assignees   []string  // @alice
+}
+
+// ExtractTodos extracts all todo items from note content
+func (n *Note) ExtractTodos() []TodoItem {
+	todos := []TodoItem{}
+	lines := strings.Split(n.Content, "\n")
+	
+	for i, line := range lines {
+		matches := todoRegex.FindStringSubmatch(line)
+		if len(matches) >= 3 {
+			completed := strings.ToLower(matches[1]) == "x"
+			todo := TodoItem{
+				Text:      strings.TrimSpace(matches[2]),
+				Completed: completed,
+				LineNum:   i,
+			}
+			todo.parseMetadata()
+			todos = append(todos, todo)
+		}
+	}
+	
+	return todos
+}
+
+// parseMetadata picks the due date, priority, recurrence and assignees out
+// of the text. Words that only look like metadata, such as a due: that
+// isn't a date, are left in the description.
+func (t *TodoItem) parseMetadata() {
+	var words []string
+	for _, word := range strings.Fields(t.Text) {
+		switch {
+		case strings.HasPrefix(word, "due:"):
+			due, err := time.ParseInLocation(DateFormat, strings.TrimPrefix(word, "due:"), time.Local)
+			if err != nil {
+				words = append(words, word)
+				continue
+			}
+			t.Due = due
+		case word == "!"+PriorityHigh || word == "!"+PriorityMedium || word == "!"+PriorityLow:
+			t.Priority = strings.TrimPrefix(word, "!")
+		case strings.HasPrefix(word, "every:") && validEvery(strings.TrimPrefix(word, "every:")):
+			t.Every = strings.TrimPrefix(word, "every:")
+		case len(word) > 1 && word[0] == '@':
+			t.Assignees = append(t.Assignees, word[1:])
+		default:
+			words = append(words, word)
+		}
+	}
+	t.Description = strings.Join(words, " ")
+}
+
+// validEvery reports whether every names a recurrence: daily, weekly,
+// monthly, yearly, or a number of days, weeks, months or years as in 3d
+func validEvery(every string) bool {
+	_, _, ok := parseEvery(every)
+	return ok
+}
+
+// parseEvery returns the recurrence as a count of days or months
+func parseEvery(every string) (days, months int, ok bool) {
+	switch every {
+	case "daily":
+		return 1, 0, true
+	case "weekly":
+		return 7, 0, true
+	case "monthly":
+		return 0, 1, true
+	case "yearly":
+		return 0, 12, true
+	}
+	
+	if len(every) < 2 {
+		return 0, 0, false
+	}
+	count, err := strconv.Atoi(every[:len(every)-1])
+	if err != nil || count < 1 {
+		return 0, 0, false
+	}
+	switch every[len(every)-1] {
+	case 'd':
+		return count, 0, true
+	case 'w':
+		return count * 7, 0, true
+	case 'm':
+		return 0, count, true
+	case 'y':
+		return 0, count * 12, true
+	}
+	return 0, 0, false
+}
+
+// NextDue returns when a recurring todo is next due after being done on
+// today: a step on from its due date, or from today if it has none, and
+// on past today if it was long overdue
+func (t TodoItem) NextDue(today time.Time) (time.Time, bool) {
+	days, months, ok := parseEvery(t.Every)
+	if !ok {
+		return time.Time{}, false
+	}
+	
+	from := t.Due
+	if from.IsZero() {
+		from = Today(today)
+	}
+	for step := 1; ; step++ {
+		next := addMonths(from, step*months).AddDate(0, 0, step*days)
+		if next.After(Today(today)) {
+			return next, true
+		}
+	}
+}
+
+// addMonths moves t on by a number of months, to the last day of the month
+// if it is shorter: a monthly todo due on the 31st is due on the 30th of
+// June, and on the 31st again in July
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	day := t.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// Today returns the start of the day t is in
+func Today(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Overdue reports whether a todo was due before today
+func (t TodoItem) Overdue(today time.Time) bool {
+	return !t.Due.IsZero() && t.Due.Before(Today(today))
+}
+
+// DueWithin reports whether a todo is due from today through the given
+// number of days after it
+func (t TodoItem) DueWithin(today time.Time, days int) bool {
+	start := Today(today)
+	return !t.Due.IsZero() && !t.Due.Before(start) && t.Due.Before(start.AddDate(0, 0, days+1))
+}
+
+// ToggleTodo toggles a todo item at the given line number. Ticking off a
+// recurring todo also adds its next occurrence on the line below, and
+// unticking it removes that line again.
+func (n *Note) ToggleTodo(lineNum int) bool {
+	lines := strings.Split(n.Content, "\n")
+	if lineNum < 0 || lineNum >= len(lines) {
+		return false
+	}
+	
+	line := lines[lineNum]
+	matches := todoRegex.FindStringSubmatch(line)
+	if len(matches) >= 2 {
+		// Toggle the checkbox
+		if matches[1] == " " {
+			// Mark as completed
+			lines[lineNum] = strings.Replace(line, "[ ]", "[x]", 1)
+			if next, ok := nextOccurrence(line, matches[2]); ok {
+				lines = append(lines[:lineNum+1], append([]string{next}, lines[lineNum+1:]...)...)
+			}
+		} else {
+			// Mark as incomplete
+			lines[lineNum] = strings.Replace(line, "[x]", "[ ]", 1)
+			lines[lineNum] = strings.Replace(lines[lineNum], "[X]", "[ ]", 1)
+			
+			// Take back the next occurrence ticking it off added, as long
+			// as it hasn't been touched since
+			if next, ok := nextOccurrence(lines[lineNum], matches[2]); ok &&
+				lineNum+1 < len(lines) && lines[lineNum+1] == next {
+				lines = append(lines[:lineNum+1], lines[lineNum+2:]...)
+			}
+		}
+		n.Content = strings.Join(lines, "\n")
+		return true
+	}
+	
+	return false
+}
+
+// nextOccurrence returns an open todo's line as it should read for its
+// next occurrence, if it recurs
+func nextOccurrence(line, text string) (string, bool) {
+	todo := TodoItem{Text: text}
+	todo.parseMetadata()
+	next, ok := todo.NextDue(time.Now())
+	if !ok {
+		return "", false
+	}
+	
+	due := "due:" + next.Format(DateFormat)
+	if todo.Due.IsZero() {
+		return line + " " + due, true
+	}
+	return dueRegex.ReplaceAllStringFunc(line, func(match string) string {
+		return strings.TrimSuffix(match, strings.TrimSpace(match)) + due
+	}), true
+}
+
+// CountTodos returns the count of completed and total todos
+func (n *Note) CountTodos() (completed, total int) {
+	todos := n.ExtractTodos()
+	total = len(todos)
+	for _, todo := range todos {
+		if todo.Completed {
+			completed++
+		}
+	}
+	return completed, total
+}
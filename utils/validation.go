@@ -111,6 +111,18 @@ func SanitizePath(path string) (string, error) {
 	return cleaned, nil
 }
 
+// ValidateFolderName checks the name of a folder of notes. Names starting
+// with a dot are kept for the store's own folders, such as .versions.
+func ValidateFolderName(name string) error {
+	if err := ValidateFilename(strings.TrimSpace(name)); err != nil {
+		return err
+	}
+	if strings.HasPrefix(name, ".") {
+		return fmt.Errorf("folder name cannot start with a dot")
+	}
+	return nil
+}
+
 func ValidateFilename(filename string) error {
 	if filename == "" {
 		return fmt.Errorf("filename cannot be empty")